@@ -16,8 +16,11 @@ import (
 	"zettelkasten/internal/api"
 	"zettelkasten/internal/config"
 	"zettelkasten/internal/database"
+	"zettelkasten/internal/llm"
 	"zettelkasten/internal/queue"
+	"zettelkasten/internal/rerank"
 	"zettelkasten/internal/services"
+	"zettelkasten/internal/tokenizer"
 	ws "zettelkasten/internal/websocket"
 )
 
@@ -58,10 +61,35 @@ func main() {
 
 	// Initialize services
 	embeddingService := services.NewEmbeddingService(cfg.OpenAIAPIKey)
+	if cfg.EmbeddingMode == "offline" {
+		log.Println("Using offline embeddings; search quality is only suitable for development")
+		embeddingService = services.NewOfflineEmbeddingService(1536)
+	}
+	llmProvider, err := llm.NewProvider(cfg.LLMProvider, cfg.LLMBaseURL, cfg.LLMAPIKey, cfg.LLMModel)
+	if err != nil {
+		log.Fatalf("Fatal: Failed to initialize LLM provider: %v", err)
+	}
+	if llmProvider == nil {
+		log.Println("No LLM provider configured; answers, chat and zettel mode are unavailable")
+	}
+	reranker, err := rerank.New(cfg.Reranker, cfg.RerankerURL, cfg.RerankerAPIKey, cfg.RerankerModel, llmProvider)
+	if err != nil {
+		log.Fatalf("Fatal: Failed to initialize reranker: %v", err)
+	}
 	authService := services.NewAuthService(mongodb, redis, cfg.JWTSecret)
 	eventService := services.NewEventService(wsHub)
-	documentService := services.NewDocumentService(mongodb, pineconeClient, embeddingService, eventService)
-	searchService := services.NewSearchService(pineconeClient, redis, embeddingService)
+	keywordIndex := services.NewKeywordIndex(mongodb)
+	cacheMetrics := services.NewCacheMetrics(redis)
+	searchCache := services.NewSearchCache(redis, cfg.SemanticCache, cacheMetrics)
+	queryEmbeddings := services.NewEmbeddingCache(redis, embeddingService, cacheMetrics)
+	tok := tokenizer.Load(cfg.TokenizerFile)
+	graphService := services.NewGraphService(mongodb, keywordIndex)
+	insightsService := services.NewInsightsService(mongodb, redis, graphService, eventService)
+	linkService := services.NewLinkService(mongodb, pineconeClient, redis, eventService, insightsService, cfg.LinkThreshold)
+	documentService := services.NewDocumentService(mongodb, pineconeClient, embeddingService, eventService, tok, llmProvider, keywordIndex, searchCache, linkService)
+	searchService := services.NewSearchService(pineconeClient, searchCache, queryEmbeddings, documentService, keywordIndex, reranker)
+	answerService := services.NewAnswerService(searchService, llmProvider, tok)
+	chatService := services.NewChatService(mongodb, answerService, llmProvider)
 	emailService := services.NewEmailService(cfg.EmailAPIKey, cfg.EmailFrom)
 
 	jobQueue := queue.NewJobQueue(redis, documentService, eventService)
@@ -72,6 +100,8 @@ func main() {
 		defer queueCancel()
 		jobQueue.StartWithContext(queueCtx)
 	}()
+	go linkService.StartWithContext(queueCtx)
+	go insightsService.StartWithContext(queueCtx)
 
 	// Initialize router
 	r := chi.NewRouter()
@@ -79,7 +109,7 @@ func main() {
 	// CORS middleware
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000", "http://127.0.0.1:3000"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
@@ -95,10 +125,15 @@ func main() {
 
 	// Initialize API handlers
 	api.NewAuthHandler(r, authService, emailService)
-	api.NewDocumentHandler(r, documentService, jobQueue, redis)
-	api.NewSearchHandler(r, searchService, embeddingService, redis)
+	api.NewDocumentHandler(r, documentService, graphService, jobQueue, redis)
+	api.NewSearchHandler(r, searchService, answerService, embeddingService, redis, wsHub)
 	api.NewUserHandler(r, authService)
-	api.NewAnalyticsHandler(r, mongodb)
+	api.NewTagHandler(r, documentService)
+	api.NewChunkHandler(r, documentService)
+	api.NewChatHandler(r, chatService, redis)
+	api.NewLinkHandler(r, linkService, graphService, redis)
+	api.NewGraphHandler(r, graphService, insightsService)
+	api.NewAnalyticsHandler(r, mongodb, cacheMetrics)
 	api.NewWebSocketHandler(r, wsHub)
 
 	// Health check
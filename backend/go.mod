@@ -13,6 +13,7 @@ require (
 	github.com/pinecone-io/go-pinecone/v3 v3.0.0
 	go.mongodb.org/mongo-driver v1.13.1
 	golang.org/x/crypto v0.23.0
+	golang.org/x/text v0.15.0
 	google.golang.org/protobuf v1.34.1
 )
 
@@ -34,7 +35,6 @@ require (
 	golang.org/x/net v0.25.0 // indirect
 	golang.org/x/sync v0.7.0 // indirect
 	golang.org/x/sys v0.20.0 // indirect
-	golang.org/x/text v0.15.0 // indirect
 	google.golang.org/genproto/googleapis/api v0.0.0-20240528184218-531527333157 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240528184218-531527333157 // indirect
 	google.golang.org/grpc v1.65.0 // indirect
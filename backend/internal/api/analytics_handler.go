@@ -5,17 +5,19 @@ import (
 	"os"
 
 	"zettelkasten/internal/middleware"
+	"zettelkasten/internal/services"
 
 	"github.com/go-chi/chi/v5"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type AnalyticsHandler struct {
-	db *mongo.Client
+	db           *mongo.Client
+	cacheMetrics *services.CacheMetrics
 }
 
-func NewAnalyticsHandler(r chi.Router, mongodb *mongo.Client) {
-	h := &AnalyticsHandler{db: mongodb}
+func NewAnalyticsHandler(r chi.Router, mongodb *mongo.Client, cacheMetrics *services.CacheMetrics) {
+	h := &AnalyticsHandler{db: mongodb, cacheMetrics: cacheMetrics}
 
 	r.Route("/v1/analytics", func(r chi.Router) {
 		r.Use(middleware.AuthMiddleware(os.Getenv("JWT_SECRET")))
@@ -25,9 +27,15 @@ func NewAnalyticsHandler(r chi.Router, mongodb *mongo.Client) {
 }
 
 func (h *AnalyticsHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
-	// userID := r.Context().Value("user_id").(string)
+	userID := r.Context().Value("user_id").(string)
 
-	// Mock analytics data
+	cacheStats, err := h.cacheMetrics.Stats(userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to load cache statistics")
+		return
+	}
+
+	// Mock analytics data, apart from the cache statistics
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"period": "month",
 		"stats": map[string]interface{}{
@@ -36,6 +44,7 @@ func (h *AnalyticsHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
 			"chunks_processed":   890,
 			"api_calls":          467,
 		},
+		"cache": cacheStats,
 		"daily_breakdown": []map[string]interface{}{
 			{
 				"date":      "2025-05-01",
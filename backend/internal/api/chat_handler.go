@@ -0,0 +1,188 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+
+	"zettelkasten/internal/database"
+	"zettelkasten/internal/middleware"
+	"zettelkasten/internal/models"
+	"zettelkasten/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ChatHandler struct {
+	chatService *services.ChatService
+}
+
+func NewChatHandler(r chi.Router, chatService *services.ChatService, redis *database.RedisClient) {
+	h := &ChatHandler{chatService: chatService}
+
+	r.Route("/v1/chats", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(os.Getenv("JWT_SECRET")))
+
+		r.Get("/", h.ListChats)
+		r.Post("/", h.CreateChat)
+		r.Get("/{chatID}", h.GetChat)
+		r.Patch("/{chatID}", h.UpdateChat)
+		r.Delete("/{chatID}", h.DeleteChat)
+		r.Get("/{chatID}/export", h.ExportChat)
+		r.With(middleware.RateLimitMiddleware(redis, 100, time.Minute)).Post("/{chatID}/messages", h.SendMessage)
+	})
+}
+
+func (h *ChatHandler) ListChats(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	chats, err := h.chatService.ListSessions(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list chats")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"chats": chats,
+	})
+}
+
+func (h *ChatHandler) CreateChat(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	var req struct {
+		Title string           `json:"title"`
+		Scope models.ChatScope `json:"scope"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	chat, err := h.chatService.CreateSession(r.Context(), userID, req.Title, req.Scope)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create chat")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, chat)
+}
+
+func (h *ChatHandler) GetChat(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	chat, err := h.chatService.GetSession(r.Context(), userID, chi.URLParam(r, "chatID"))
+	if err != nil {
+		respondWithChatError(w, err, "Failed to get chat")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chat)
+}
+
+func (h *ChatHandler) UpdateChat(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	var req struct {
+		Title *string           `json:"title"`
+		Scope *models.ChatScope `json:"scope"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	chat, err := h.chatService.UpdateSession(r.Context(), userID, chi.URLParam(r, "chatID"), req.Title, req.Scope)
+	if err != nil {
+		respondWithChatError(w, err, "Failed to update chat")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chat)
+}
+
+func (h *ChatHandler) DeleteChat(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	if err := h.chatService.DeleteSession(r.Context(), userID, chi.URLParam(r, "chatID")); err != nil {
+		respondWithChatError(w, err, "Failed to delete chat")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ChatHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	var req struct {
+		Content      string                 `json:"content"`
+		SearchParams services.SearchRequest `json:"search_params"`
+		LLMParams    services.LLMParams     `json:"llm_params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Content == "" {
+		respondWithError(w, http.StatusBadRequest, "Content is required")
+		return
+	}
+	if !services.IsValidSearchMode(req.SearchParams.Mode) {
+		respondWithError(w, http.StatusBadRequest, "Invalid search mode")
+		return
+	}
+	if !validSearchLimit(&req.SearchParams) {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Limit must be between 1 and %d", services.MaxSearchLimit))
+		return
+	}
+	if req.SearchParams.SimilarityThreshold == 0 {
+		req.SearchParams.SimilarityThreshold = 0.7
+	}
+
+	turn, err := h.chatService.SendMessage(r.Context(), userID, chi.URLParam(r, "chatID"), req.Content, req.SearchParams, req.LLMParams)
+	if err != nil {
+		if errors.Is(err, services.ErrNoLLMProvider) {
+			respondWithError(w, answerErrorStatus(err), answerErrorMessage(err))
+			return
+		}
+		respondWithChatError(w, err, "Failed to answer message")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, turn)
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func (h *ChatHandler) ExportChat(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+	chatID := chi.URLParam(r, "chatID")
+
+	markdown, err := h.chatService.ExportMarkdown(r.Context(), userID, chatID)
+	if err != nil {
+		respondWithChatError(w, err, "Failed to export chat")
+		return
+	}
+
+	filename := unsafeFilenameChars.ReplaceAllString("chat-"+chatID, "_") + ".md"
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(markdown))
+}
+
+func respondWithChatError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, services.ErrChatNotFound) {
+		respondWithError(w, http.StatusNotFound, "Chat not found")
+		return
+	}
+	respondWithError(w, http.StatusInternalServerError, message)
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+
+	"zettelkasten/internal/middleware"
+	"zettelkasten/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ChunkHandler struct {
+	documentService *services.DocumentService
+}
+
+func NewChunkHandler(r chi.Router, documentService *services.DocumentService) {
+	h := &ChunkHandler{documentService: documentService}
+
+	r.Route("/v1/chunks", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(os.Getenv("JWT_SECRET")))
+
+		r.Get("/{chunkID}/related", h.GetRelatedNotes)
+	})
+}
+
+func (h *ChunkHandler) GetRelatedNotes(w http.ResponseWriter, r *http.Request) {
+	chunkID := chi.URLParam(r, "chunkID")
+	userID := r.Context().Value("user_id").(string)
+
+	limit, minScore := relatedParams(r)
+	related, err := h.documentService.RelatedToChunk(r.Context(), userID, chunkID, limit, minScore)
+	if err != nil {
+		if errors.Is(err, services.ErrChunkNotFound) {
+			respondWithError(w, http.StatusNotFound, "Chunk not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to find related notes")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, related)
+}
+
+// relatedParams reads the number of related documents to return and the
+// minimum similarity of their chunks
+func relatedParams(r *http.Request) (int, float32) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 50 {
+		limit = services.DefaultRelatedLimit
+	}
+	minScore, _ := strconv.ParseFloat(r.URL.Query().Get("min_score"), 32)
+	return limit, float32(minScore)
+}
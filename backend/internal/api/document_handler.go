@@ -1,39 +1,58 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"path"
 	"strconv"
 	"time"
 
+	"zettelkasten/internal/archive"
 	"zettelkasten/internal/database"
 	"zettelkasten/internal/middleware"
+	"zettelkasten/internal/parsers"
 	"zettelkasten/internal/queue"
 	"zettelkasten/internal/services"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type DocumentHandler struct {
 	documentService *services.DocumentService
+	graphService    *services.GraphService
 	jobQueue        *queue.JobQueue
 	redis           *database.RedisClient
 }
 
-func NewDocumentHandler(r chi.Router, documentService *services.DocumentService, jobQueue *queue.JobQueue, redis *database.RedisClient) {
+func NewDocumentHandler(r chi.Router, documentService *services.DocumentService, graphService *services.GraphService, jobQueue *queue.JobQueue, redis *database.RedisClient) {
 	h := &DocumentHandler{
 		documentService: documentService,
+		graphService:    graphService,
 		jobQueue:        jobQueue,
 		redis:           redis,
 	}
 
 	r.Route("/v1/documents", func(r chi.Router) {
 		r.Use(middleware.AuthMiddleware(os.Getenv("JWT_SECRET")))
-		r.Use(middleware.RateLimitMiddleware(redis, 10, time.Hour)) // 10 uploads per hour
 
-		r.Post("/upload", h.Upload)
+		// Only the routes that parse and embed files are limited
+		processing := r.With(middleware.RateLimitMiddleware(redis, 10, time.Hour)) // 10 uploads per hour
+		processing.Post("/upload", h.Upload)
+		processing.Post("/{documentID}/rechunk", h.RechunkDocument)
+		processing.Post("/reindex", h.ReindexKeywords)
+
 		r.Get("/", h.ListDocuments)
 		r.Get("/{documentID}/chunks", h.GetDocumentChunks)
+		r.Get("/{documentID}/zettels", h.GetDocumentZettels)
+		r.Get("/{documentID}/related", h.GetRelatedNotes)
+		r.Get("/{documentID}/backlinks", h.GetBacklinks)
 		r.Delete("/{documentID}", h.DeleteDocument)
 	})
 }
@@ -54,6 +73,28 @@ func (h *DocumentHandler) Upload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	chunking, err := parseChunkingConfig(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid chunking options: %v", err))
+		return
+	}
+
+	chunkSize := r.FormValue("chunk_size_preference")
+	if chunkSize != "" && !services.IsValidChunkSizePreference(chunkSize) {
+		respondWithError(w, http.StatusBadRequest, "Invalid chunk size preference")
+		return
+	}
+
+	mode := r.FormValue("mode")
+	if !services.IsValidMode(mode) {
+		respondWithError(w, http.StatusBadRequest, "Invalid mode")
+		return
+	}
+	if mode == services.ModeZettel && !h.documentService.ZettelsAvailable() {
+		respondWithError(w, http.StatusServiceUnavailable, "No LLM provider is configured")
+		return
+	}
+
 	files := r.MultipartForm.File["files[]"]
 	if len(files) == 0 {
 		respondWithError(w, http.StatusBadRequest, "No files uploaded")
@@ -67,25 +108,151 @@ func (h *DocumentHandler) Upload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Queue files for processing
+	// Expand archives before queueing anything so that an archive that
+	// violates the limits rejects the whole upload. Each entry is read
+	// once, when its source type is detected.
+	var pending []pendingFile
 	for _, fileHeader := range files {
 		file, err := fileHeader.Open()
 		if err != nil {
 			continue
 		}
-		defer file.Close()
 
-		h.jobQueue.QueueFile(job.ID, userID, file, fileHeader.Filename, sourceType)
+		if !archive.IsArchive(fileHeader.Filename) {
+			data, err := io.ReadAll(file)
+			file.Close()
+			if err != nil {
+				continue
+			}
+			pending = append(pending, pendingFile{
+				filename:   fileHeader.Filename,
+				sourceType: sourceType,
+				data:       data,
+			})
+			continue
+		}
+
+		expanded, err := archive.Expand(file, fileHeader.Filename, archive.DefaultLimits)
+		file.Close()
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Failed to expand %s: %v", fileHeader.Filename, err))
+			return
+		}
+		defer expanded.Remove()
+		pending = append(pending, archiveEntries(expanded.Entries, sourceType)...)
+	}
+
+	if len(pending) == 0 {
+		respondWithError(w, http.StatusBadRequest, "No supported files found in upload")
+		return
+	}
+
+	// Options that are only invalid merged with the user's preferences
+	// would otherwise fail in the background
+	checked := make(map[string]bool)
+	for _, p := range pending {
+		key := p.sourceType + "/" + parsers.DefaultStrategy(p.sourceType, p.filename)
+		if checked[key] {
+			continue
+		}
+		checked[key] = true
+		opts := services.ProcessOptions{ChunkSizePreference: chunkSize, Chunking: chunking}
+		if err := h.documentService.CheckChunking(r.Context(), userID, p.sourceType, p.filename, opts); err != nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid chunking options: %v", err))
+			return
+		}
+	}
+
+	// Queue files for processing
+	queued := 0
+	for _, p := range pending {
+		opts := services.ProcessOptions{
+			OriginalPath:        p.originalPath,
+			ChunkSizePreference: chunkSize,
+			Chunking:            chunking,
+			Mode:                mode,
+		}
+		if err := h.jobQueue.QueueFile(job.ID, userID, p.data, p.filename, p.sourceType, opts); err == nil {
+			queued++
+		}
 	}
 
 	respondWithJSON(w, http.StatusAccepted, map[string]interface{}{
 		"job_id":                 job.ID,
 		"status":                 "processing",
 		"files_received":         len(files),
-		"estimated_time_seconds": len(files) * 30,
+		"files_queued":           queued,
+		"estimated_time_seconds": queued * 30,
 	})
 }
 
+// parseChunkingConfig reads the optional chunking form fields of an upload
+func parseChunkingConfig(r *http.Request) (parsers.ChunkingConfig, error) {
+	config := parsers.ChunkingConfig{Strategy: r.FormValue("chunking_strategy")}
+
+	fields := map[string]*int{
+		"min_tokens":     &config.MinTokens,
+		"max_tokens":     &config.MaxTokens,
+		"overlap_tokens": &config.Overlap,
+	}
+	for name, target := range fields {
+		value := r.FormValue(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("%s must be an integer", name)
+		}
+		*target = n
+	}
+
+	return config, config.Validate()
+}
+
+// pendingFile is an uploaded file, or a file extracted from an uploaded
+// archive, waiting to be queued
+type pendingFile struct {
+	filename     string
+	originalPath string
+	sourceType   string
+	data         []byte
+}
+
+// archiveEntries detects the source type of every archive entry, skipping
+// hidden, binary and unsupported files
+func archiveEntries(entries []archive.Entry, sourceType string) []pendingFile {
+	paths := make([]string, len(entries))
+	for i, entry := range entries {
+		paths[i] = entry.Path
+	}
+
+	// An Obsidian or Logseq vault uploaded as "standard" keeps its own type
+	fallback := sourceType
+	if vaultType := parsers.DetectVaultType(paths); vaultType != "" && sourceType == "standard" {
+		fallback = vaultType
+	}
+
+	var pending []pendingFile
+	for _, entry := range entries {
+		data, err := entry.ReadFile()
+		if err != nil {
+			continue
+		}
+		entryType := parsers.DetectSourceType(entry.Path, data, fallback)
+		if entryType == "" {
+			continue
+		}
+		pending = append(pending, pendingFile{
+			filename:     path.Base(entry.Path),
+			originalPath: entry.Path,
+			sourceType:   entryType,
+			data:         data,
+		})
+	}
+	return pending
+}
+
 func (h *DocumentHandler) ListDocuments(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("user_id").(string)
 
@@ -134,6 +301,139 @@ func (h *DocumentHandler) GetDocumentChunks(w http.ResponseWriter, r *http.Reque
 	})
 }
 
+func (h *DocumentHandler) GetDocumentZettels(w http.ResponseWriter, r *http.Request) {
+	documentID := chi.URLParam(r, "documentID")
+	userID := r.Context().Value("user_id").(string)
+
+	zettels, err := h.documentService.ListZettels(r.Context(), userID, documentID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get zettels")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"zettels": zettels,
+	})
+}
+
+func (h *DocumentHandler) GetRelatedNotes(w http.ResponseWriter, r *http.Request) {
+	documentID := chi.URLParam(r, "documentID")
+	userID := r.Context().Value("user_id").(string)
+
+	limit, minScore := relatedParams(r)
+	related, err := h.documentService.RelatedToDocument(r.Context(), userID, documentID, limit, minScore)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, primitive.ErrInvalidHex) {
+			respondWithError(w, http.StatusNotFound, "Document not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to find related notes")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, related)
+}
+
+func (h *DocumentHandler) GetBacklinks(w http.ResponseWriter, r *http.Request) {
+	documentID := chi.URLParam(r, "documentID")
+	userID := r.Context().Value("user_id").(string)
+
+	backlinks, err := h.graphService.Backlinks(r.Context(), userID, documentID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			respondWithError(w, http.StatusNotFound, "Document not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to get backlinks")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, backlinks)
+}
+
+func (h *DocumentHandler) RechunkDocument(w http.ResponseWriter, r *http.Request) {
+	documentID := chi.URLParam(r, "documentID")
+	userID := r.Context().Value("user_id").(string)
+
+	var req struct {
+		ChunkSizePreference string                 `json:"chunk_size_preference"`
+		Chunking            parsers.ChunkingConfig `json:"chunking"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	if req.ChunkSizePreference != "" && !services.IsValidChunkSizePreference(req.ChunkSizePreference) {
+		respondWithError(w, http.StatusBadRequest, "Invalid chunk size preference")
+		return
+	}
+	if err := req.Chunking.Validate(); err != nil {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid chunking options: %v", err))
+		return
+	}
+
+	doc, err := h.documentService.GetDocument(r.Context(), userID, documentID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Document not found")
+		return
+	}
+	if doc.SourceFileID == nil {
+		// Documents uploaded before original files were kept cannot be
+		// parsed again
+		respondWithError(w, http.StatusConflict, "Original file is not available for this document; upload it again to change its chunking")
+		return
+	}
+
+	opts := services.ProcessOptions{
+		ChunkSizePreference: req.ChunkSizePreference,
+		Chunking:            req.Chunking,
+	}
+	if err := h.documentService.CheckRechunking(r.Context(), userID, doc, opts); err != nil {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid chunking options: %v", err))
+		return
+	}
+
+	jobID := uuid.New().String()
+	if err := h.jobQueue.QueueRechunk(jobID, userID, documentID, opts); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to queue rechunk")
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+		"job_id":      jobID,
+		"document_id": documentID,
+		"status":      "processing",
+	})
+}
+
+// ReindexKeywords queues a job adding the user's documents, or the one given
+// as document_id, to the keyword index
+func (h *DocumentHandler) ReindexKeywords(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	documentID := r.URL.Query().Get("document_id")
+	if documentID != "" {
+		if _, err := h.documentService.GetDocument(r.Context(), userID, documentID); err != nil {
+			respondWithError(w, http.StatusNotFound, "Document not found")
+			return
+		}
+	}
+
+	jobID := uuid.New().String()
+	if err := h.jobQueue.QueueReindex(jobID, userID, documentID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to queue reindex")
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+		"job_id": jobID,
+		"status": "processing",
+	})
+}
+
 func (h *DocumentHandler) DeleteDocument(w http.ResponseWriter, r *http.Request) {
 	documentID := chi.URLParam(r, "documentID")
 	userID := r.Context().Value("user_id").(string)
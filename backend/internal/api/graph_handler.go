@@ -0,0 +1,188 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"zettelkasten/internal/graph"
+	"zettelkasten/internal/middleware"
+	"zettelkasten/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type GraphHandler struct {
+	graphService    *services.GraphService
+	insightsService *services.InsightsService
+}
+
+func NewGraphHandler(r chi.Router, graphService *services.GraphService, insightsService *services.InsightsService) {
+	h := &GraphHandler{
+		graphService:    graphService,
+		insightsService: insightsService,
+	}
+
+	r.Route("/v1/graph", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(os.Getenv("JWT_SECRET")))
+
+		r.Get("/", h.GetGraph)
+		r.Get("/export", h.ExportGraph)
+		r.Get("/insights", h.GetInsights)
+		r.Post("/insights/refresh", h.RefreshInsights)
+	})
+}
+
+func (h *GraphHandler) GetGraph(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	opts, err := graphOptions(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 2000 {
+		limit = 500
+	}
+
+	g, err := h.graphService.Graph(r.Context(), userID, opts)
+	if err != nil {
+		respondWithGraphError(w, err)
+		return
+	}
+
+	total := len(g.Nodes)
+	paged := g.Page(page, limit)
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"nodes": paged.Nodes,
+		"edges": paged.Edges,
+		"pagination": map[string]interface{}{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": (total + limit - 1) / limit,
+		},
+	})
+}
+
+// ExportGraph downloads the whole graph selected by the same options as
+// GetGraph for use in external graph tools
+func (h *GraphHandler) ExportGraph(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = graph.FormatGraphML
+	}
+	if !graph.IsValidFormat(format) {
+		respondWithError(w, http.StatusBadRequest, "Invalid format, use graphml, gexf or dot")
+		return
+	}
+
+	opts, err := graphOptions(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	g, err := h.graphService.Graph(r.Context(), userID, opts)
+	if err != nil {
+		respondWithGraphError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", graph.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="zettelkasten.%s"`, format))
+	w.WriteHeader(http.StatusOK)
+	graph.Write(w, g, format)
+}
+
+// GetInsights returns the latest analysis of the user's graph. Insights
+// that were never computed are queued.
+func (h *GraphHandler) GetInsights(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	insights, err := h.insightsService.Get(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			h.insightsService.Queue(userID)
+			respondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+				"status": "pending",
+			})
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to get graph insights")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, insights)
+}
+
+func (h *GraphHandler) RefreshInsights(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	h.insightsService.Queue(userID)
+	respondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+		"status": "pending",
+	})
+}
+
+// graphOptions reads the seed, depth, edge types and whether to include
+// chunks from the query string
+func graphOptions(r *http.Request) (services.GraphOptions, error) {
+	query := r.URL.Query()
+	opts := services.GraphOptions{Seed: query.Get("seed")}
+
+	if value := query.Get("depth"); value != "" {
+		depth, err := strconv.Atoi(value)
+		if err != nil || depth < 1 || depth > services.MaxGraphDepth {
+			return opts, fmt.Errorf("Depth must be between 1 and %d", services.MaxGraphDepth)
+		}
+		opts.Depth = depth
+	}
+
+	if value := query.Get("types"); value != "" {
+		for _, edgeType := range strings.Split(value, ",") {
+			edgeType = strings.TrimSpace(edgeType)
+			if !graph.IsValidEdgeType(edgeType) {
+				return opts, fmt.Errorf("Invalid edge type %q, use wikilink, tag or semantic", edgeType)
+			}
+			opts.EdgeTypes = append(opts.EdgeTypes, edgeType)
+		}
+	}
+
+	if value := query.Get("include_chunks"); value != "" {
+		include, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, errors.New("include_chunks must be true or false")
+		}
+		opts.IncludeChunks = include
+	}
+
+	if value := query.Get("include_suggested"); value != "" {
+		include, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, errors.New("include_suggested must be true or false")
+		}
+		opts.IncludeSuggested = include
+	}
+	return opts, nil
+}
+
+func respondWithGraphError(w http.ResponseWriter, err error) {
+	if errors.Is(err, graph.ErrNodeNotFound) {
+		respondWithError(w, http.StatusNotFound, "Seed node not found")
+		return
+	}
+	respondWithError(w, http.StatusInternalServerError, "Failed to build graph")
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"zettelkasten/internal/database"
+	"zettelkasten/internal/middleware"
+	"zettelkasten/internal/services"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type LinkHandler struct {
+	linkService  *services.LinkService
+	graphService *services.GraphService
+}
+
+func NewLinkHandler(r chi.Router, linkService *services.LinkService, graphService *services.GraphService, redis *database.RedisClient) {
+	h := &LinkHandler{
+		linkService:  linkService,
+		graphService: graphService,
+	}
+
+	r.Route("/v1/links", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(os.Getenv("JWT_SECRET")))
+
+		r.Get("/", h.ListLinks)
+		r.Get("/unresolved", h.ListUnresolvedLinks)
+		// Suggesting links queries the vector index for every chunk of
+		// every document queued
+		r.With(middleware.RateLimitMiddleware(redis, 10, time.Hour)).Post("/suggest", h.SuggestLinks)
+		r.Post("/{linkID}/accept", h.AcceptLink)
+		r.Post("/{linkID}/reject", h.RejectLink)
+	})
+}
+
+func (h *LinkHandler) ListLinks(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	status := r.URL.Query().Get("status")
+	if status != "" && !services.IsValidLinkStatus(status) {
+		respondWithError(w, http.StatusBadRequest, "Invalid link status")
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	links, total, err := h.linkService.ListLinks(r.Context(), userID, status, r.URL.Query().Get("document_id"), page, limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list links")
+		return
+	}
+
+	totalPages := (int(total) + limit - 1) / limit
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"links": links,
+		"pagination": map[string]interface{}{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": totalPages,
+		},
+	})
+}
+
+// ListUnresolvedLinks lists the wikilink targets that match none of the
+// user's documents, e.g. notes that were not imported
+func (h *LinkHandler) ListUnresolvedLinks(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	unresolved, err := h.graphService.UnresolvedLinks(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list unresolved links")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"unresolved": unresolved,
+		"total":      len(unresolved),
+	})
+}
+
+// SuggestLinks queues the user's documents, or the one given as
+// document_id, to have links suggested again
+func (h *LinkHandler) SuggestLinks(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	queued, err := h.linkService.QueueUserDocuments(r.Context(), userID, r.URL.Query().Get("document_id"))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			respondWithError(w, http.StatusNotFound, "Document not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to queue link suggestions")
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+		"queued_documents": queued,
+	})
+}
+
+func (h *LinkHandler) AcceptLink(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, services.LinkAccepted)
+}
+
+func (h *LinkHandler) RejectLink(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, services.LinkRejected)
+}
+
+func (h *LinkHandler) setStatus(w http.ResponseWriter, r *http.Request, status string) {
+	userID := r.Context().Value("user_id").(string)
+
+	link, err := h.linkService.SetStatus(r.Context(), userID, chi.URLParam(r, "linkID"), status)
+	if err != nil {
+		if errors.Is(err, services.ErrLinkNotFound) {
+			respondWithError(w, http.StatusNotFound, "Link not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to update link")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, link)
+}
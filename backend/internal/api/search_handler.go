@@ -1,29 +1,56 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"os"
+	"runtime/debug"
+	"strings"
 	"time"
 
 	"zettelkasten/internal/database"
 	"zettelkasten/internal/middleware"
 	"zettelkasten/internal/services"
+	ws "zettelkasten/internal/websocket"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 type SearchHandler struct {
 	searchService    *services.SearchService
+	answerService    *services.AnswerService
 	embeddingService *services.EmbeddingService
 	redis            *database.RedisClient
+	hub              *ws.Hub
 }
 
-func NewSearchHandler(r chi.Router, searchService *services.SearchService, embeddingService *services.EmbeddingService, redis *database.RedisClient) {
+// Answers streamed over the WebSocket run detached from the request and
+// stop after this long
+const answerStreamTimeout = 2 * time.Minute
+
+// tokenCoalesceInterval is how long tokens streamed over the WebSocket are
+// gathered into one message, so that a fast model does not fill the buffer
+// of a slow connection
+const tokenCoalesceInterval = 50 * time.Millisecond
+
+// streamWebSocket is the stream query parameter that delivers an answer
+// over the user's WebSocket connection
+const streamWebSocket = "websocket"
+
+var errStreamClosed = errors.New("stream closed by the client")
+
+func NewSearchHandler(r chi.Router, searchService *services.SearchService, answerService *services.AnswerService, embeddingService *services.EmbeddingService, redis *database.RedisClient, hub *ws.Hub) {
 	h := &SearchHandler{
 		searchService:    searchService,
+		answerService:    answerService,
 		embeddingService: embeddingService,
 		redis:            redis,
+		hub:              hub,
 	}
 
 	r.Route("/v1/search", func(r chi.Router) {
@@ -38,28 +65,30 @@ func NewSearchHandler(r chi.Router, searchService *services.SearchService, embed
 func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("user_id").(string)
 
-	var req struct {
-		Query               string                 `json:"query"`
-		Limit               int                    `json:"limit"`
-		Filters             services.SearchFilters `json:"filters"`
-		IncludeContext      bool                   `json:"include_context"`
-		SimilarityThreshold float32                `json:"similarity_threshold"`
-	}
-
+	var req services.SearchRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
+	if !services.IsValidSearchMode(req.Mode) {
+		respondWithError(w, http.StatusBadRequest, "Invalid search mode")
+		return
+	}
+	if !validSearchLimit(&req) {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Limit must be between 1 and %d", services.MaxSearchLimit))
+		return
+	}
+
 	// Set defaults
 	if req.Limit == 0 {
-		req.Limit = 10
+		req.Limit = services.DefaultSearchLimit
 	}
 	if req.SimilarityThreshold == 0 {
 		req.SimilarityThreshold = 0.7
 	}
 
-	results, err := h.searchService.Search(r.Context(), userID, req.Query, req.Limit, req.Filters, req.SimilarityThreshold)
+	results, err := h.searchService.Search(r.Context(), userID, req)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Search failed")
 		return
@@ -69,21 +98,193 @@ func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *SearchHandler) SearchWithLLM(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Query        string                 `json:"query"`
-		SearchParams map[string]interface{} `json:"search_params"`
-		LLMParams    map[string]interface{} `json:"llm_params"`
-	}
+	userID := r.Context().Value("user_id").(string)
 
+	var req services.AnswerRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	// This would integrate with an LLM service
-	respondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"llm_response": "LLM integration coming soon",
-		"sources_used": []interface{}{},
-		"tokens_used":  0,
+	if req.Query == "" {
+		respondWithError(w, http.StatusBadRequest, "Query is required")
+		return
+	}
+	if !services.IsValidSearchMode(req.SearchParams.Mode) {
+		respondWithError(w, http.StatusBadRequest, "Invalid search mode")
+		return
+	}
+	if !validSearchLimit(&req.SearchParams) {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Limit must be between 1 and %d", services.MaxSearchLimit))
+		return
+	}
+	if req.SearchParams.SimilarityThreshold == 0 {
+		req.SearchParams.SimilarityThreshold = 0.7
+	}
+
+	switch {
+	case r.URL.Query().Get("stream") == streamWebSocket:
+		h.streamAnswerToWebSocket(w, userID, req)
+		return
+	case strings.Contains(r.Header.Get("Accept"), "text/event-stream"):
+		h.streamAnswerEvents(w, r, userID, req)
+		return
+	}
+
+	resp, err := h.answerService.Answer(r.Context(), userID, req)
+	if err != nil {
+		respondWithError(w, answerErrorStatus(err), answerErrorMessage(err))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// streamAnswerEvents streams an answer as server-sent events: sources once
+// retrieved, then a token event per piece of the answer and finally done,
+// or error. The answer stops when the client disconnects.
+func (h *SearchHandler) streamAnswerEvents(w http.ResponseWriter, r *http.Request, userID string, req services.AnswerRequest) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondWithError(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string, payload map[string]interface{}) error {
+		if err := r.Context().Err(); err != nil {
+			return err
+		}
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	h.streamAnswer(r.Context(), userID, req, send)
+}
+
+// streamAnswerToWebSocket answers in the background, sending llm:sources,
+// llm:token and llm:done (or llm:error) messages tagged with the returned
+// stream_id over the user's WebSocket connections. The answer stops once
+// the user has no connection left.
+func (h *SearchHandler) streamAnswerToWebSocket(w http.ResponseWriter, userID string, req services.AnswerRequest) {
+	if !h.hub.HasClients(userID) {
+		respondWithError(w, http.StatusConflict, "No WebSocket connection is open")
+		return
+	}
+
+	streamID := uuid.New().String()
+	send := coalesceTokens(func(event string, payload map[string]interface{}) error {
+		if !h.hub.HasClients(userID) {
+			return errStreamClosed
+		}
+		payload["stream_id"] = streamID
+		h.hub.SendToUser(userID, "llm:"+event, payload)
+		return nil
+	}, tokenCoalesceInterval)
+
+	go func() {
+		// The router's Recoverer does not cover this goroutine
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				log.Printf("Answer stream %s for user %s panicked: %v\n%s", streamID, userID, recovered, debug.Stack())
+				h.hub.SendToUser(userID, "llm:error", map[string]interface{}{
+					"stream_id": streamID,
+					"message":   "Answer generation failed",
+				})
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), answerStreamTimeout)
+		defer cancel()
+		h.streamAnswer(ctx, userID, req, send)
+	}()
+
+	respondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+		"stream_id": streamID,
 	})
 }
+
+// coalesceTokens wraps send so that streamed tokens are joined and sent at
+// most once per interval. Any other event first sends the tokens gathered
+// so far.
+func coalesceTokens(send func(event string, payload map[string]interface{}) error, interval time.Duration) func(event string, payload map[string]interface{}) error {
+	var pending strings.Builder
+	var lastSent time.Time
+	flush := func() error {
+		if pending.Len() == 0 {
+			return nil
+		}
+		token := pending.String()
+		pending.Reset()
+		lastSent = time.Now()
+		return send("token", map[string]interface{}{"token": token})
+	}
+
+	return func(event string, payload map[string]interface{}) error {
+		if event == "token" {
+			token, _ := payload["token"].(string)
+			pending.WriteString(token)
+			if time.Since(lastSent) < interval {
+				return nil
+			}
+			return flush()
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		return send(event, payload)
+	}
+}
+
+// streamAnswer runs an answer, passing each stage to send
+func (h *SearchHandler) streamAnswer(ctx context.Context, userID string, req services.AnswerRequest, send func(event string, payload map[string]interface{}) error) {
+	resp, err := h.answerService.StreamAnswer(ctx, userID, req,
+		func(sources []services.SearchResult) error {
+			return send("sources", map[string]interface{}{"sources_used": sources})
+		},
+		func(token string) error {
+			return send("token", map[string]interface{}{"token": token})
+		},
+	)
+	if err != nil {
+		if ctx.Err() != nil || errors.Is(err, errStreamClosed) {
+			log.Printf("Answer stream for user %s stopped: %v", userID, err)
+			return
+		}
+		send("error", map[string]interface{}{"message": answerErrorMessage(err)})
+		return
+	}
+
+	send("done", map[string]interface{}{
+		"llm_response": resp.LLMResponse,
+		"citations":    resp.Citations,
+		"tokens_used":  resp.TokensUsed,
+		"usage":        resp.Usage,
+	})
+}
+
+func answerErrorStatus(err error) int {
+	if errors.Is(err, services.ErrNoLLMProvider) {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+func answerErrorMessage(err error) string {
+	if errors.Is(err, services.ErrNoLLMProvider) {
+		return "No LLM provider is configured"
+	}
+	return "Answer generation failed"
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+	"os"
+
+	"zettelkasten/internal/middleware"
+	"zettelkasten/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type TagHandler struct {
+	documentService *services.DocumentService
+}
+
+func NewTagHandler(r chi.Router, documentService *services.DocumentService) {
+	h := &TagHandler{documentService: documentService}
+
+	r.Route("/v1/tags", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware(os.Getenv("JWT_SECRET")))
+
+		r.Get("/", h.ListTags)
+	})
+}
+
+func (h *TagHandler) ListTags(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("user_id").(string)
+
+	tags, err := h.documentService.ListTags(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list tags")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"tags": tags,
+	})
+}
@@ -36,10 +36,11 @@ func (h *UserHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"id":         user.ID.Hex(),
-		"email":      user.Email,
-		"name":       user.Name,
-		"created_at": user.CreatedAt,
+		"id":          user.ID.Hex(),
+		"email":       user.Email,
+		"name":        user.Name,
+		"created_at":  user.CreatedAt,
+		"preferences": user.Preferences,
 		"stats": map[string]interface{}{
 			"total_documents": 0,
 			"total_chunks":    0,
@@ -66,6 +67,10 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		updates["name"] = req.Name
 	}
 	if req.Preferences != nil {
+		if err := services.ValidatePreferences(req.Preferences); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		updates["preferences"] = req.Preferences
 	}
 
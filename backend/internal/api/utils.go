@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+
+	"zettelkasten/internal/services"
 )
 
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
@@ -37,8 +39,18 @@ func validatePassword(password string) error {
 	return nil
 }
 
+// validSearchLimit rejects negative search limits and caps the others at
+// services.MaxSearchLimit. A zero limit is left for the default.
+func validSearchLimit(req *services.SearchRequest) bool {
+	if req.Limit < 0 {
+		return false
+	}
+	req.Limit = min(req.Limit, services.MaxSearchLimit)
+	return true
+}
+
 func isValidSourceType(sourceType string) bool {
-	validTypes := []string{"notion", "obsidian", "roam", "logseq", "standard"}
+	validTypes := []string{"notion", "obsidian", "roam", "logseq", "epub", "kindle", "readwise", "email", "standard"}
 	for _, valid := range validTypes {
 		if sourceType == valid {
 			return true
@@ -0,0 +1,309 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnsupportedArchive = errors.New("unsupported archive format")
+	ErrUnsafePath         = errors.New("archive entry has an unsafe path")
+	ErrTooManyEntries     = errors.New("archive contains too many entries")
+	ErrEntryTooLarge      = errors.New("archive entry exceeds the size limit")
+	ErrArchiveTooLarge    = errors.New("archive exceeds the total size limit")
+	ErrCompressionRatio   = errors.New("archive entry exceeds the compression ratio limit")
+)
+
+// Limits bounds how much work expanding a single upload may cause
+type Limits struct {
+	MaxEntries          int
+	MaxEntrySize        int64
+	MaxTotalSize        int64
+	MaxCompressionRatio int64
+}
+
+// DefaultLimits are applied to archive uploads, which are capped at 10 MB
+// by the frontend
+var DefaultLimits = Limits{
+	MaxEntries:          2000,
+	MaxEntrySize:        20 << 20,  // 20 MB per file
+	MaxTotalSize:        100 << 20, // 100 MB expanded
+	MaxCompressionRatio: 100,
+}
+
+// ratioCheckFloor is the output of a compressed stream below which its
+// compression ratio is not checked, as the padding of small tar files
+// compresses far better than any content
+const ratioCheckFloor = 1 << 20
+
+// Expanded is an archive extracted into a temporary directory, which the
+// caller removes once done with its entries
+type Expanded struct {
+	Dir     string
+	Entries []Entry
+}
+
+// Entry is a regular file extracted from an archive
+type Entry struct {
+	// Path is the validated path of the entry within the archive
+	Path string
+	// File is where its content was written
+	File string
+	Size int64
+}
+
+// ReadFile returns the content of the entry
+func (e Entry) ReadFile() ([]byte, error) {
+	return os.ReadFile(e.File)
+}
+
+// Remove deletes the extracted files
+func (x *Expanded) Remove() error {
+	return os.RemoveAll(x.Dir)
+}
+
+// IsArchive reports whether filename has a supported archive extension
+func IsArchive(filename string) bool {
+	return format(filename) != ""
+}
+
+func format(filename string) string {
+	lower := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip"
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar"
+	default:
+		return ""
+	}
+}
+
+// Expand extracts the regular files of a zip, tar or tar.gz archive into a
+// temporary directory, so that large archives are not held in memory. Entry
+// paths are validated against traversal and every size limit is enforced on
+// the bytes actually read, not on the sizes the archive headers claim.
+func Expand(r io.Reader, filename string, limits Limits) (*Expanded, error) {
+	archiveFormat := format(filename)
+	if archiveFormat == "" {
+		return nil, ErrUnsupportedArchive
+	}
+
+	dir, err := os.MkdirTemp("", "archive-")
+	if err != nil {
+		return nil, err
+	}
+	x := &extractor{limits: limits, expanded: &Expanded{Dir: dir}}
+
+	switch archiveFormat {
+	case "zip":
+		err = x.zip(r)
+	case "tar.gz":
+		compressed := &countingReader{r: r}
+		var gz *gzip.Reader
+		gz, err = gzip.NewReader(compressed)
+		if err != nil {
+			err = fmt.Errorf("invalid gzip stream: %w", err)
+			break
+		}
+		err = x.tar(&ratioReader{r: gz, compressed: compressed, ratio: limits.MaxCompressionRatio})
+		gz.Close()
+	case "tar":
+		err = x.tar(r)
+	}
+
+	if err != nil {
+		x.expanded.Remove()
+		return nil, err
+	}
+	return x.expanded, nil
+}
+
+// extractor writes the entries of an archive while enforcing the limits
+type extractor struct {
+	limits   Limits
+	expanded *Expanded
+	total    int64
+}
+
+func (x *extractor) zip(r io.Reader) error {
+	// Zip archives are read from their end, so the upload is spooled to disk
+	spool, err := os.CreateTemp(x.expanded.Dir, "upload-*.zip")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}()
+	size, err := io.Copy(spool, r)
+	if err != nil {
+		return err
+	}
+
+	reader, err := zip.NewReader(spool, size)
+	if err != nil {
+		return fmt.Errorf("invalid zip archive: %w", err)
+	}
+
+	for _, f := range reader.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+
+		name, err := safePath(f.Name)
+		if err != nil {
+			return err
+		}
+		if isJunk(name) {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		entry, err := x.add(name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+
+		compressed := int64(f.CompressedSize64)
+		if compressed == 0 {
+			compressed = 1
+		}
+		if x.limits.MaxCompressionRatio > 0 && entry.Size/compressed > x.limits.MaxCompressionRatio {
+			return fmt.Errorf("%s: %w", name, ErrCompressionRatio)
+		}
+	}
+	return nil
+}
+
+func (x *extractor) tar(r io.Reader) error {
+	reader := tar.NewReader(r)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid tar archive: %w", err)
+		}
+
+		// Symlinks, hard links and devices are never followed or extracted
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name, err := safePath(header.Name)
+		if err != nil {
+			return err
+		}
+		if isJunk(name) {
+			continue
+		}
+
+		if _, err := x.add(name, reader); err != nil {
+			return err
+		}
+	}
+}
+
+// add writes an entry to a file of its own, named by its position rather
+// than its path
+func (x *extractor) add(name string, r io.Reader) (Entry, error) {
+	if len(x.expanded.Entries) >= x.limits.MaxEntries {
+		return Entry{}, ErrTooManyEntries
+	}
+
+	f, err := os.Create(filepath.Join(x.expanded.Dir, strconv.Itoa(len(x.expanded.Entries))))
+	if err != nil {
+		return Entry{}, err
+	}
+	defer f.Close()
+
+	// Read at most one byte more than allowed to detect oversize entries
+	size, err := io.Copy(f, io.LimitReader(r, x.limits.MaxEntrySize+1))
+	if err != nil {
+		return Entry{}, fmt.Errorf("%s: %w", name, err)
+	}
+	if size > x.limits.MaxEntrySize {
+		return Entry{}, fmt.Errorf("%s: %w", name, ErrEntryTooLarge)
+	}
+
+	x.total += size
+	if x.total > x.limits.MaxTotalSize {
+		return Entry{}, ErrArchiveTooLarge
+	}
+
+	entry := Entry{Path: name, File: f.Name(), Size: size}
+	x.expanded.Entries = append(x.expanded.Entries, entry)
+	return entry, nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// ratioReader fails once the bytes decompressed through it exceed ratio
+// times the compressed bytes consumed
+type ratioReader struct {
+	r          io.Reader
+	compressed *countingReader
+	ratio      int64
+	n          int64
+}
+
+func (r *ratioReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	if r.ratio > 0 && r.n > ratioCheckFloor && r.n/max(r.compressed.n, 1) > r.ratio {
+		return n, ErrCompressionRatio
+	}
+	return n, err
+}
+
+// safePath normalises an entry name and rejects absolute paths and any
+// path that would escape the extraction root
+func safePath(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") || (len(name) > 1 && name[1] == ':') {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+		}
+	}
+
+	cleaned := path.Clean(name)
+	if cleaned == "." || cleaned == "" {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	return cleaned, nil
+}
+
+// isJunk reports metadata files added by archiving tools
+func isJunk(name string) bool {
+	return strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(path.Base(name), "._")
+}
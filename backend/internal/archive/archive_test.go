@@ -0,0 +1,162 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+		f.Write([]byte(content))
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func buildTarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := tar.NewWriter(gz)
+	for name, content := range files {
+		w.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		w.Write([]byte(content))
+	}
+	w.WriteHeader(&tar.Header{Name: "link.md", Linkname: "/etc/passwd", Typeflag: tar.TypeSymlink})
+	w.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestExpandZip(t *testing.T) {
+	data := buildZip(t, map[string]string{
+		"vault/note.md":          "# Note",
+		"vault/sub/other.md":     "# Other",
+		"__MACOSX/vault/._a.md":  "junk",
+		"vault/._note.md":        "junk",
+		"vault/.obsidian/app.md": "{}",
+	})
+
+	expanded, err := Expand(bytes.NewReader(data), "vault.zip", DefaultLimits)
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	defer expanded.Remove()
+	if len(expanded.Entries) != 3 {
+		t.Fatalf("Expand() = %d entries, want 3", len(expanded.Entries))
+	}
+	for _, entry := range expanded.Entries {
+		if entry.Path != "vault/note.md" {
+			continue
+		}
+		content, err := entry.ReadFile()
+		if err != nil || string(content) != "# Note" {
+			t.Errorf("ReadFile() = %q, %v, want %q", content, err, "# Note")
+		}
+	}
+}
+
+func TestExpandTarGz(t *testing.T) {
+	data := buildTarGz(t, map[string]string{
+		"notes/a.md": "A",
+		"notes/b.md": "B",
+	})
+
+	expanded, err := Expand(bytes.NewReader(data), "notes.tar.gz", DefaultLimits)
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	defer expanded.Remove()
+	if len(expanded.Entries) != 2 {
+		t.Fatalf("Expand() = %d entries, want 2 (symlinks skipped)", len(expanded.Entries))
+	}
+}
+
+func TestExpandRemovesFilesOnFailure(t *testing.T) {
+	before, _ := filepath.Glob(filepath.Join(os.TempDir(), "archive-*"))
+
+	data := buildZip(t, map[string]string{"a.md": "a", "../evil.md": "x"})
+	if _, err := Expand(bytes.NewReader(data), "upload.zip", DefaultLimits); err == nil {
+		t.Fatal("Expand() error = nil, want an error")
+	}
+
+	after, _ := filepath.Glob(filepath.Join(os.TempDir(), "archive-*"))
+	if len(after) > len(before) {
+		t.Errorf("Expand() left %d directories behind", len(after)-len(before))
+	}
+}
+
+func TestExpandRejectsTarGzBomb(t *testing.T) {
+	data := buildTarGz(t, map[string]string{"bomb.md": strings.Repeat("0", 8<<20)})
+
+	_, err := Expand(bytes.NewReader(data), "bomb.tar.gz", DefaultLimits)
+	if !errors.Is(err, ErrCompressionRatio) {
+		t.Errorf("Expand() error = %v, want %v", err, ErrCompressionRatio)
+	}
+}
+
+func TestExpandRejectsUnsafeArchives(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string
+		limits Limits
+		want   error
+	}{
+		{
+			name:   "path traversal",
+			files:  map[string]string{"../../etc/cron.d/evil": "x"},
+			limits: DefaultLimits,
+			want:   ErrUnsafePath,
+		},
+		{
+			name:   "absolute path",
+			files:  map[string]string{"/etc/passwd": "x"},
+			limits: DefaultLimits,
+			want:   ErrUnsafePath,
+		},
+		{
+			name:   "too many entries",
+			files:  map[string]string{"a.md": "a", "b.md": "b", "c.md": "c"},
+			limits: Limits{MaxEntries: 2, MaxEntrySize: 1 << 20, MaxTotalSize: 1 << 20},
+			want:   ErrTooManyEntries,
+		},
+		{
+			name:   "entry too large",
+			files:  map[string]string{"big.md": strings.Repeat("x", 2048)},
+			limits: Limits{MaxEntries: 10, MaxEntrySize: 1024, MaxTotalSize: 1 << 20},
+			want:   ErrEntryTooLarge,
+		},
+		{
+			name:   "compression bomb",
+			files:  map[string]string{"bomb.md": strings.Repeat("0", 1<<20)},
+			limits: DefaultLimits,
+			want:   ErrCompressionRatio,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := buildZip(t, tt.files)
+			_, err := Expand(bytes.NewReader(data), "upload.zip", tt.limits)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Expand() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
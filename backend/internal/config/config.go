@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strconv"
 )
 
 type Config struct {
@@ -15,6 +16,18 @@ type Config struct {
 	PineconeRegion string
 	PineconeModel  string // New: To specify the embedding model
 	OpenAIAPIKey   string
+	EmbeddingMode  string // "openai" or "offline"
+	TokenizerFile  string // tiktoken rank file used to count chunk tokens
+	LLMProvider    string // "openai", "stub" or empty for none
+	LLMBaseURL     string // OpenAI-compatible chat completions API
+	LLMModel       string
+	LLMAPIKey      string
+	Reranker       string // "lexical", "http" or "llm"
+	RerankerURL    string // Cohere-style rerank API for the http reranker
+	RerankerAPIKey string
+	RerankerModel  string
+	SemanticCache  float64 // cosine distance within which a query reuses another's cached results; 0 disables
+	LinkThreshold  float64 // chunk similarity above which documents are suggested as linked
 	JWTSecret      string
 	EmailAPIKey    string
 	EmailFrom      string
@@ -32,6 +45,18 @@ func Load() *Config {
 		PineconeRegion: getEnv("PINECONE_REGION", "us-east-1"),
 		PineconeModel:  getEnv("PINECONE_MODEL", "text-embedding-3-small"), // Default model
 		OpenAIAPIKey:   getEnv("OPENAI_API_KEY", ""),
+		EmbeddingMode:  getEnv("EMBEDDING_MODE", "openai"),
+		TokenizerFile:  getEnv("TOKENIZER_FILE", ""),
+		LLMProvider:    getEnv("LLM_PROVIDER", ""),
+		LLMBaseURL:     getEnv("LLM_BASE_URL", "https://api.openai.com/v1"),
+		LLMModel:       getEnv("LLM_MODEL", "gpt-4o-mini"),
+		LLMAPIKey:      getEnv("LLM_API_KEY", getEnv("OPENAI_API_KEY", "")),
+		Reranker:       getEnv("RERANKER", "lexical"),
+		RerankerURL:    getEnv("RERANKER_URL", ""),
+		RerankerAPIKey: getEnv("RERANKER_API_KEY", ""),
+		RerankerModel:  getEnv("RERANKER_MODEL", ""),
+		SemanticCache:  getEnvFloat("SEMANTIC_CACHE_TOLERANCE", 0),
+		LinkThreshold:  getEnvFloat("LINK_SUGGESTION_THRESHOLD", 0.8),
 		JWTSecret:      getEnv("JWT_SECRET", ""),
 		EmailAPIKey:    getEnv("EMAIL_API_KEY", ""),
 		EmailFrom:      getEnv("EMAIL_FROM", "noreply@zettelkasten.app"),
@@ -45,6 +70,15 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
 func getEnvInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		// Simple conversion - in production you might want better error handling
@@ -164,7 +164,7 @@ func (p *PineconeClient) Upsert(vectors []Vector) error {
 	// Convert legacy Vector format to pinecone.Vector format
 	var pineconeVectors []*pinecone.Vector
 	for _, v := range vectors {
-		meta, err := structpb.NewStruct(v.Metadata)
+		meta, err := structpb.NewStruct(normalizeMetadata(v.Metadata))
 		if err != nil {
 			return fmt.Errorf("failed to convert metadata: %w", err)
 		}
@@ -194,9 +194,14 @@ func (p *PineconeClient) Query(vector []float32, topK int, filter map[string]int
 		return nil, fmt.Errorf("failed to get index connection: %w", err)
 	}
 
+	// An unfiltered query would match other users' vectors, so a filter
+	// that cannot be encoded is an error
 	var metadataFilter *structpb.Struct
 	if filter != nil {
-		metadataFilter, _ = structpb.NewStruct(filter)
+		metadataFilter, err = structpb.NewStruct(normalizeMetadata(filter))
+		if err != nil {
+			return nil, fmt.Errorf("invalid metadata filter: %w", err)
+		}
 	}
 
 	response, err := idxConnection.QueryByVectorValues(ctx, &pinecone.QueryByVectorValuesRequest{
@@ -216,3 +221,85 @@ func (p *PineconeClient) Query(vector []float32, topK int, filter map[string]int
 		Usage:   response.Usage,
 	}, nil
 }
+
+// Delete removes vectors by ID
+func (p *PineconeClient) Delete(ids []string) error {
+	ctx := context.Background()
+
+	idx, err := p.client.DescribeIndex(ctx, p.indexName)
+	if err != nil {
+		return fmt.Errorf("failed to describe index: %w", err)
+	}
+
+	idxConnection, err := p.client.Index(pinecone.NewIndexConnParams{Host: idx.Host})
+	if err != nil {
+		return fmt.Errorf("failed to get index connection: %w", err)
+	}
+
+	return idxConnection.DeleteVectorsById(ctx, ids)
+}
+
+// normalizeMetadata converts string lists, which structpb does not accept,
+// into generic lists, including inside nested maps and lists such as filter
+// operators and clauses
+func normalizeMetadata(metadata map[string]interface{}) map[string]interface{} {
+	normalized := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		normalized[key] = normalizeValue(value)
+	}
+	return normalized
+}
+
+func normalizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []string:
+		values := make([]interface{}, len(v))
+		for i, item := range v {
+			values[i] = item
+		}
+		return values
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, item := range v {
+			values[i] = normalizeValue(item)
+		}
+		return values
+	case map[string]interface{}:
+		return normalizeMetadata(v)
+	}
+	return value
+}
+
+// Fetch returns the vectors with the given IDs, including their values.
+// IDs that do not exist are missing from the result.
+func (p *PineconeClient) Fetch(ids []string) (map[string]Vector, error) {
+	ctx := context.Background()
+
+	idx, err := p.client.DescribeIndex(ctx, p.indexName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe index: %w", err)
+	}
+
+	idxConnection, err := p.client.Index(pinecone.NewIndexConnParams{Host: idx.Host})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index connection: %w", err)
+	}
+
+	response, err := idxConnection.FetchVectors(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make(map[string]Vector, len(response.Vectors))
+	for id, v := range response.Vectors {
+		vector := Vector{ID: id}
+		if v.Values != nil {
+			vector.Values = *v.Values
+		}
+		if v.Metadata != nil {
+			vector.Metadata = v.Metadata.AsMap()
+		}
+		vectors[id] = vector
+	}
+	return vectors, nil
+}
@@ -37,6 +37,11 @@ func (r *RedisClient) Get(key string) (string, error) {
 	return r.client.Get(context.Background(), key).Result()
 }
 
+// SetNX sets key only if it does not exist and reports whether it did
+func (r *RedisClient) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	return r.client.SetNX(context.Background(), key, value, expiration).Result()
+}
+
 func (r *RedisClient) Incr(key string) (int64, error) {
 	return r.client.Incr(context.Background(), key).Result()
 }
@@ -60,3 +65,19 @@ func (r *RedisClient) Keys(pattern string) ([]string, error) {
 func (r *RedisClient) Del(keys ...string) error {
 	return r.client.Del(context.Background(), keys...).Err()
 }
+
+func (r *RedisClient) LRange(key string, start, stop int64) ([]string, error) {
+	return r.client.LRange(context.Background(), key, start, stop).Result()
+}
+
+func (r *RedisClient) LTrim(key string, start, stop int64) error {
+	return r.client.LTrim(context.Background(), key, start, stop).Err()
+}
+
+func (r *RedisClient) HIncrBy(key, field string, incr int64) error {
+	return r.client.HIncrBy(context.Background(), key, field, incr).Err()
+}
+
+func (r *RedisClient) HGetAll(key string) (map[string]string, error) {
+	return r.client.HGetAll(context.Background(), key).Result()
+}
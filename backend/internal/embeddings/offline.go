@@ -0,0 +1,86 @@
+package embeddings
+
+import (
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// OfflineEmbedder produces deterministic embeddings without calling an
+// external API by hashing word unigrams and bigrams into a fixed number of
+// dimensions. Texts sharing vocabulary end up close together, which is
+// enough for local development and tests but carries no real semantics.
+type OfflineEmbedder struct {
+	dimensions int
+}
+
+func NewOfflineEmbedder(dimensions int) *OfflineEmbedder {
+	if dimensions <= 0 {
+		dimensions = 1536
+	}
+	return &OfflineEmbedder{dimensions: dimensions}
+}
+
+func (e *OfflineEmbedder) Dimensions() int {
+	return e.dimensions
+}
+
+func (e *OfflineEmbedder) GenerateEmbedding(text string) ([]float32, error) {
+	vector := make([]float64, e.dimensions)
+
+	words := tokenize(text)
+	for i, word := range words {
+		e.add(vector, word, 1.0)
+		if i > 0 {
+			e.add(vector, words[i-1]+" "+word, 0.5)
+		}
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+
+	embedding := make([]float32, e.dimensions)
+	if norm == 0 {
+		return embedding, nil
+	}
+	for i, v := range vector {
+		embedding[i] = float32(v / norm)
+	}
+	return embedding, nil
+}
+
+func (e *OfflineEmbedder) GenerateEmbeddings(texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embedding, err := e.GenerateEmbedding(text)
+		if err != nil {
+			return nil, err
+		}
+		embeddings[i] = embedding
+	}
+	return embeddings, nil
+}
+
+// add hashes feature into a dimension, using a second hash bit for the sign
+// so that collisions tend to cancel out
+func (e *OfflineEmbedder) add(vector []float64, feature string, weight float64) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+
+	index := int(sum % uint64(e.dimensions))
+	if (sum>>63)&1 == 1 {
+		weight = -weight
+	}
+	vector[index] += weight
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,304 @@
+package graph
+
+import (
+	"math"
+	"sort"
+)
+
+// PageRank scores nodes by the weighted links pointing at them. Undirected
+// edges count in both directions and the rank of nodes without outgoing
+// edges is spread over all nodes. The scores sum to 1.
+func PageRank(g *Graph, damping float64, iterations int) map[string]float64 {
+	n := len(g.Nodes)
+	ranks := make(map[string]float64, n)
+	if n == 0 {
+		return ranks
+	}
+
+	index := make(map[string]int, n)
+	for i, node := range g.Nodes {
+		index[node.ID] = i
+	}
+
+	type arc struct {
+		from, to int
+		weight   float64
+	}
+	var arcs []arc
+	out := make([]float64, n)
+	add := func(from, to int, weight float64) {
+		arcs = append(arcs, arc{from, to, weight})
+		out[from] += weight
+	}
+	for _, edge := range g.Edges {
+		from, ok := index[edge.Source]
+		to, ok2 := index[edge.Target]
+		if !ok || !ok2 || from == to {
+			continue
+		}
+		weight := edge.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		add(from, to, weight)
+		if !edge.Directed {
+			add(to, from, weight)
+		}
+	}
+
+	rank := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+	for iteration := 0; iteration < iterations; iteration++ {
+		dangling := 0.0
+		for i, r := range rank {
+			if out[i] == 0 {
+				dangling += r
+			}
+		}
+
+		next := make([]float64, n)
+		base := (1-damping)/float64(n) + damping*dangling/float64(n)
+		for i := range next {
+			next[i] = base
+		}
+		for _, a := range arcs {
+			next[a.to] += damping * rank[a.from] * a.weight / out[a.from]
+		}
+
+		change := 0.0
+		for i := range rank {
+			change += math.Abs(next[i] - rank[i])
+		}
+		rank = next
+		if change < 1e-9 {
+			break
+		}
+	}
+
+	for i, node := range g.Nodes {
+		ranks[node.ID] = rank[i]
+	}
+	return ranks
+}
+
+// Communities groups nodes with the Louvain method, maximizing the
+// modularity of the weighted graph with edge directions ignored. Nodes are
+// visited in order, so the result is deterministic. Communities are
+// numbered from 0 by decreasing size; isolated nodes are communities of
+// their own.
+func Communities(g *Graph) map[string]int {
+	n := len(g.Nodes)
+	index := make(map[string]int, n)
+	for i, node := range g.Nodes {
+		index[node.ID] = i
+	}
+
+	// Both directions of an edge are stored, so that a community's edges
+	// to itself count twice as in the sum over all pairs of nodes
+	adjacent := make([]map[int]float64, n)
+	for i := range adjacent {
+		adjacent[i] = make(map[int]float64)
+	}
+	for _, edge := range g.Edges {
+		a, ok := index[edge.Source]
+		b, ok2 := index[edge.Target]
+		if !ok || !ok2 || a == b {
+			continue
+		}
+		weight := edge.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		adjacent[a][b] += weight
+		adjacent[b][a] += weight
+	}
+
+	membership := make([]int, n)
+	for i := range membership {
+		membership[i] = i
+	}
+	for {
+		communities, moved := moveNodes(adjacent)
+		if !moved {
+			break
+		}
+		for i := range membership {
+			membership[i] = communities[membership[i]]
+		}
+		adjacent = aggregate(adjacent, communities)
+	}
+
+	// Renumber by size, then by the first node of each community
+	sizes := make(map[int]int)
+	first := make(map[int]int)
+	var order []int
+	for i, community := range membership {
+		if _, seen := sizes[community]; !seen {
+			first[community] = i
+			order = append(order, community)
+		}
+		sizes[community]++
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if sizes[order[a]] != sizes[order[b]] {
+			return sizes[order[a]] > sizes[order[b]]
+		}
+		return first[order[a]] < first[order[b]]
+	})
+	number := make(map[int]int, len(order))
+	for i, community := range order {
+		number[community] = i
+	}
+
+	communities := make(map[string]int, n)
+	for i, node := range g.Nodes {
+		communities[node.ID] = number[membership[i]]
+	}
+	return communities
+}
+
+// moveNodes moves each node into the neighboring community that most
+// increases modularity until no move does. It returns the communities
+// numbered from 0 and whether any node moved.
+func moveNodes(adjacent []map[int]float64) ([]int, bool) {
+	n := len(adjacent)
+	degree := make([]float64, n)
+	total := 0.0
+	for i, neighbors := range adjacent {
+		for _, weight := range neighbors {
+			degree[i] += weight
+		}
+		total += degree[i]
+	}
+
+	community := make([]int, n)
+	communityDegree := make([]float64, n)
+	for i := range community {
+		community[i] = i
+		communityDegree[i] = degree[i]
+	}
+	if total == 0 {
+		return community, false
+	}
+
+	moved := false
+	for changed := true; changed; {
+		changed = false
+		for i := 0; i < n; i++ {
+			links := make(map[int]float64)
+			for j, weight := range adjacent[i] {
+				if j != i {
+					links[community[j]] += weight
+				}
+			}
+			candidates := make([]int, 0, len(links))
+			for c := range links {
+				candidates = append(candidates, c)
+			}
+			sort.Ints(candidates)
+
+			current := community[i]
+			communityDegree[current] -= degree[i]
+			best := current
+			bestGain := links[current] - communityDegree[current]*degree[i]/total
+			for _, c := range candidates {
+				if gain := links[c] - communityDegree[c]*degree[i]/total; gain > bestGain+1e-12 {
+					best, bestGain = c, gain
+				}
+			}
+			communityDegree[best] += degree[i]
+			if best != current {
+				community[i] = best
+				changed, moved = true, true
+			}
+		}
+	}
+
+	number := make(map[int]int)
+	for i, c := range community {
+		if _, ok := number[c]; !ok {
+			number[c] = len(number)
+		}
+		community[i] = number[c]
+	}
+	return community, moved
+}
+
+// aggregate merges each community into a single node, whose edges to
+// itself hold the weight within the community
+func aggregate(adjacent []map[int]float64, community []int) []map[int]float64 {
+	count := 0
+	for _, c := range community {
+		count = max(count, c+1)
+	}
+	merged := make([]map[int]float64, count)
+	for i := range merged {
+		merged[i] = make(map[int]float64)
+	}
+	for i, neighbors := range adjacent {
+		for j, weight := range neighbors {
+			merged[community[i]][community[j]] += weight
+		}
+	}
+	return merged
+}
+
+// Bridge is a node with edges into communities other than its own
+type Bridge struct {
+	ID string
+	// Communities are the other communities it is linked to, in order
+	Communities []int
+	// Weight is the total weight of its edges into them
+	Weight float64
+}
+
+// Bridges returns the nodes linking communities, those linked to the most
+// other communities first, then by the weight of those links
+func Bridges(g *Graph, communities map[string]int) []Bridge {
+	linked := make(map[string]map[int]bool)
+	weights := make(map[string]float64)
+	add := func(id string, community int, weight float64) {
+		if linked[id] == nil {
+			linked[id] = make(map[int]bool)
+		}
+		linked[id][community] = true
+		weights[id] += weight
+	}
+
+	for _, edge := range g.Edges {
+		a, ok := communities[edge.Source]
+		b, ok2 := communities[edge.Target]
+		if !ok || !ok2 || a == b {
+			continue
+		}
+		weight := edge.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		add(edge.Source, b, weight)
+		add(edge.Target, a, weight)
+	}
+
+	bridges := make([]Bridge, 0, len(linked))
+	for _, node := range g.Nodes {
+		others, ok := linked[node.ID]
+		if !ok {
+			continue
+		}
+		bridge := Bridge{ID: node.ID, Weight: weights[node.ID]}
+		for community := range others {
+			bridge.Communities = append(bridge.Communities, community)
+		}
+		sort.Ints(bridge.Communities)
+		bridges = append(bridges, bridge)
+	}
+	sort.SliceStable(bridges, func(i, j int) bool {
+		if len(bridges[i].Communities) != len(bridges[j].Communities) {
+			return len(bridges[i].Communities) > len(bridges[j].Communities)
+		}
+		return bridges[i].Weight > bridges[j].Weight
+	})
+	return bridges
+}
@@ -0,0 +1,85 @@
+package graph
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// twoTriangles is two triangles a-b-c and d-e-f joined by c - d, with an
+// isolated g
+func twoTriangles() *Graph {
+	g := &Graph{}
+	for _, id := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		g.Nodes = append(g.Nodes, Node{ID: id, Type: NodeDocument, Label: id})
+	}
+	for _, pair := range [][2]string{{"a", "b"}, {"b", "c"}, {"a", "c"}, {"d", "e"}, {"e", "f"}, {"d", "f"}, {"c", "d"}} {
+		g.Edges = append(g.Edges, Edge{Source: pair[0], Target: pair[1], Type: EdgeSemantic, Weight: 1})
+	}
+	return g
+}
+
+func TestPageRank(t *testing.T) {
+	g := twoTriangles()
+	ranks := PageRank(g, 0.85, 100)
+
+	sum := 0.0
+	for _, rank := range ranks {
+		sum += rank
+	}
+	if math.Abs(sum-1) > 1e-6 {
+		t.Errorf("ranks sum to %f, want 1", sum)
+	}
+	if ranks["c"] <= ranks["a"] || math.Abs(ranks["c"]-ranks["d"]) > 1e-9 {
+		t.Errorf("ranks = %v, want c = d above a", ranks)
+	}
+	if ranks["g"] >= ranks["a"] {
+		t.Errorf("isolated g ranks %f, above a at %f", ranks["g"], ranks["a"])
+	}
+
+	// A directed link passes rank to its target only
+	directed := &Graph{
+		Nodes: []Node{{ID: "x"}, {ID: "y"}},
+		Edges: []Edge{{Source: "x", Target: "y", Directed: true, Weight: 1}},
+	}
+	if ranks := PageRank(directed, 0.85, 100); ranks["y"] <= ranks["x"] {
+		t.Errorf("directed ranks = %v, want y above x", ranks)
+	}
+
+	if ranks := PageRank(&Graph{}, 0.85, 100); len(ranks) != 0 {
+		t.Errorf("empty graph ranks = %v", ranks)
+	}
+}
+
+func TestCommunities(t *testing.T) {
+	communities := Communities(twoTriangles())
+
+	if communities["a"] != communities["b"] || communities["b"] != communities["c"] {
+		t.Errorf("a, b and c are split: %v", communities)
+	}
+	if communities["d"] != communities["e"] || communities["e"] != communities["f"] {
+		t.Errorf("d, e and f are split: %v", communities)
+	}
+	if communities["a"] == communities["d"] {
+		t.Errorf("the triangles were merged: %v", communities)
+	}
+	if communities["g"] != 2 {
+		t.Errorf("isolated g is community %d, want the last, 2", communities["g"])
+	}
+}
+
+func TestBridges(t *testing.T) {
+	g := twoTriangles()
+	bridges := Bridges(g, Communities(g))
+
+	var ids []string
+	for _, bridge := range bridges {
+		ids = append(ids, bridge.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"c", "d"}) {
+		t.Errorf("bridges = %v, want c and d", ids)
+	}
+	if len(bridges) > 0 && (len(bridges[0].Communities) != 1 || bridges[0].Weight != 1) {
+		t.Errorf("bridge c = %+v, want one other community of weight 1", bridges[0])
+	}
+}
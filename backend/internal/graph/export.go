@@ -0,0 +1,254 @@
+package graph
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Export formats
+const (
+	FormatGraphML = "graphml"
+	FormatGEXF    = "gexf"
+	FormatDOT     = "dot"
+)
+
+func IsValidFormat(format string) bool {
+	return format == FormatGraphML || format == FormatGEXF || format == FormatDOT
+}
+
+// ContentType returns the media type of an export format
+func ContentType(format string) string {
+	if format == FormatDOT {
+		return "text/vnd.graphviz; charset=utf-8"
+	}
+	return "application/xml; charset=utf-8"
+}
+
+// Write encodes g in one of the export formats
+func Write(w io.Writer, g *Graph, format string) error {
+	switch format {
+	case FormatGraphML:
+		return WriteGraphML(w, g)
+	case FormatGEXF:
+		return WriteGEXF(w, g)
+	case FormatDOT:
+		return WriteDOT(w, g)
+	}
+	return fmt.Errorf("unknown graph format %q", format)
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID       string        `xml:"id,attr"`
+	Source   string        `xml:"source,attr"`
+	Target   string        `xml:"target,attr"`
+	Directed bool          `xml:"directed,attr"`
+	Data     []graphMLData `xml:"data"`
+}
+
+// WriteGraphML encodes g as GraphML, undirected by default with directed
+// edges marked as such
+func WriteGraphML(w io.Writer, g *Graph) error {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+			{ID: "type", For: "node", AttrName: "type", AttrType: "string"},
+			{ID: "source_type", For: "node", AttrName: "source_type", AttrType: "string"},
+			{ID: "tags", For: "node", AttrName: "tags", AttrType: "string"},
+			{ID: "edge_type", For: "edge", AttrName: "type", AttrType: "string"},
+			{ID: "weight", For: "edge", AttrName: "weight", AttrType: "double"},
+			{ID: "edge_label", For: "edge", AttrName: "label", AttrType: "string"},
+		},
+	}
+	doc.Graph.ID = "G"
+	doc.Graph.EdgeDefault = "undirected"
+
+	for _, node := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: node.ID,
+			Data: []graphMLData{
+				{Key: "label", Value: node.Label},
+				{Key: "type", Value: node.Type},
+				{Key: "source_type", Value: node.SourceType},
+				{Key: "tags", Value: strings.Join(node.Tags, ",")},
+			},
+		})
+	}
+	for i, edge := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			ID:       "e" + strconv.Itoa(i),
+			Source:   edge.Source,
+			Target:   edge.Target,
+			Directed: edge.Directed,
+			Data: []graphMLData{
+				{Key: "edge_type", Value: edge.Type},
+				{Key: "weight", Value: formatWeight(edge.Weight)},
+				{Key: "edge_label", Value: edge.Label},
+			},
+		})
+	}
+	return writeXML(w, doc)
+}
+
+type gexf struct {
+	XMLName xml.Name `xml:"gexf"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	Version string   `xml:"version,attr"`
+	Graph   struct {
+		DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+		Mode            string           `xml:"mode,attr"`
+		Attributes      []gexfAttributes `xml:"attributes"`
+		Nodes           []gexfNode       `xml:"nodes>node"`
+		Edges           []gexfEdge       `xml:"edges>edge"`
+	} `xml:"graph"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gexfNode struct {
+	ID        string      `xml:"id,attr"`
+	Label     string      `xml:"label,attr"`
+	AttValues []gexfValue `xml:"attvalues>attvalue"`
+}
+
+type gexfEdge struct {
+	ID        string      `xml:"id,attr"`
+	Source    string      `xml:"source,attr"`
+	Target    string      `xml:"target,attr"`
+	Type      string      `xml:"type,attr,omitempty"`
+	Weight    string      `xml:"weight,attr"`
+	Label     string      `xml:"label,attr,omitempty"`
+	AttValues []gexfValue `xml:"attvalues>attvalue"`
+}
+
+// WriteGEXF encodes g as GEXF 1.2
+func WriteGEXF(w io.Writer, g *Graph) error {
+	doc := gexf{XMLNS: "http://gexf.net/1.2", Version: "1.2"}
+	doc.Graph.DefaultEdgeType = "undirected"
+	doc.Graph.Mode = "static"
+	doc.Graph.Attributes = []gexfAttributes{
+		{Class: "node", Attributes: []gexfAttribute{
+			{ID: "type", Title: "type", Type: "string"},
+			{ID: "source_type", Title: "source_type", Type: "string"},
+			{ID: "tags", Title: "tags", Type: "string"},
+		}},
+		{Class: "edge", Attributes: []gexfAttribute{
+			{ID: "type", Title: "type", Type: "string"},
+		}},
+	}
+
+	for _, node := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gexfNode{
+			ID:    node.ID,
+			Label: node.Label,
+			AttValues: []gexfValue{
+				{For: "type", Value: node.Type},
+				{For: "source_type", Value: node.SourceType},
+				{For: "tags", Value: strings.Join(node.Tags, ",")},
+			},
+		})
+	}
+	for i, edge := range g.Edges {
+		direction := ""
+		if edge.Directed {
+			direction = "directed"
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, gexfEdge{
+			ID:        strconv.Itoa(i),
+			Source:    edge.Source,
+			Target:    edge.Target,
+			Type:      direction,
+			Weight:    formatWeight(edge.Weight),
+			Label:     edge.Label,
+			AttValues: []gexfValue{{For: "type", Value: edge.Type}},
+		})
+	}
+	return writeXML(w, doc)
+}
+
+// WriteDOT encodes g for Graphviz. DOT graphs are either directed or not,
+// so undirected edges are drawn without arrows.
+func WriteDOT(w io.Writer, g *Graph) error {
+	var b strings.Builder
+	b.WriteString("digraph zettelkasten {\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&b, "  %s [label=%s, type=%s];\n", strconv.Quote(node.ID), strconv.Quote(node.Label), strconv.Quote(node.Type))
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s [type=%s, weight=%s", strconv.Quote(edge.Source), strconv.Quote(edge.Target), strconv.Quote(edge.Type), formatWeight(edge.Weight))
+		if edge.Label != "" {
+			fmt.Fprintf(&b, ", label=%s", strconv.Quote(edge.Label))
+		}
+		if !edge.Directed {
+			b.WriteString(", dir=none")
+		}
+		b.WriteString("];\n")
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func formatWeight(weight float64) string {
+	return strconv.FormatFloat(weight, 'g', 4, 64)
+}
@@ -0,0 +1,150 @@
+// Package graph holds the knowledge graph of a user's notes and the
+// operations on it that do not depend on storage
+package graph
+
+import (
+	"errors"
+	"sort"
+)
+
+// Node types
+const (
+	NodeDocument = "document"
+	NodeChunk    = "chunk"
+)
+
+// Edge types. Wikilinks point from the linking note to the linked one and
+// chunks are contained in their document; the other edges are undirected.
+// Suggested edges are links that have not been accepted yet.
+const (
+	EdgeWikilink  = "wikilink"
+	EdgeTag       = "tag"
+	EdgeSemantic  = "semantic"
+	EdgeSuggested = "suggested"
+	EdgeContains  = "contains"
+)
+
+// ErrNodeNotFound is returned for a seed node that is not in the graph
+var ErrNodeNotFound = errors.New("node not found")
+
+// IsValidEdgeType reports whether edges of a type can be requested
+func IsValidEdgeType(edgeType string) bool {
+	return edgeType == EdgeWikilink || edgeType == EdgeTag || edgeType == EdgeSemantic
+}
+
+type Node struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Label string `json:"label"`
+	// DocumentID is set on chunks
+	DocumentID string   `json:"document_id,omitempty"`
+	SourceType string   `json:"source_type,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Degree     int      `json:"degree"`
+}
+
+type Edge struct {
+	Source   string  `json:"source"`
+	Target   string  `json:"target"`
+	Type     string  `json:"type"`
+	Directed bool    `json:"directed"`
+	Weight   float64 `json:"weight"`
+	// Label is the link text of wikilinks and the shared tags of tag edges
+	Label string `json:"label,omitempty"`
+}
+
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// SetDegrees counts the edges of every node
+func (g *Graph) SetDegrees() {
+	degrees := make(map[string]int, len(g.Nodes))
+	for _, edge := range g.Edges {
+		degrees[edge.Source]++
+		degrees[edge.Target]++
+	}
+	for i := range g.Nodes {
+		g.Nodes[i].Degree = degrees[g.Nodes[i].ID]
+	}
+}
+
+// SortByDegree orders nodes by degree, then by label
+func (g *Graph) SortByDegree() {
+	sort.SliceStable(g.Nodes, func(i, j int) bool {
+		if g.Nodes[i].Degree != g.Nodes[j].Degree {
+			return g.Nodes[i].Degree > g.Nodes[j].Degree
+		}
+		return g.Nodes[i].Label < g.Nodes[j].Label
+	})
+}
+
+// Neighborhood returns the nodes within depth edges of seed, in either
+// direction, ordered by distance, and the edges between them
+func (g *Graph) Neighborhood(seed string, depth int) (*Graph, error) {
+	nodes := make(map[string]Node, len(g.Nodes))
+	for _, node := range g.Nodes {
+		nodes[node.ID] = node
+	}
+	if _, ok := nodes[seed]; !ok {
+		return nil, ErrNodeNotFound
+	}
+
+	adjacent := make(map[string][]string)
+	for _, edge := range g.Edges {
+		adjacent[edge.Source] = append(adjacent[edge.Source], edge.Target)
+		adjacent[edge.Target] = append(adjacent[edge.Target], edge.Source)
+	}
+
+	visited := map[string]bool{seed: true}
+	order := []string{seed}
+	frontier := []string{seed}
+	for distance := 0; distance < depth && len(frontier) > 0; distance++ {
+		var next []string
+		for _, id := range frontier {
+			for _, neighbor := range adjacent[id] {
+				if !visited[neighbor] {
+					visited[neighbor] = true
+					order = append(order, neighbor)
+					next = append(next, neighbor)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	sub := &Graph{Nodes: make([]Node, 0, len(order)), Edges: []Edge{}}
+	for _, id := range order {
+		sub.Nodes = append(sub.Nodes, nodes[id])
+	}
+	for _, edge := range g.Edges {
+		if visited[edge.Source] && visited[edge.Target] {
+			sub.Edges = append(sub.Edges, edge)
+		}
+	}
+	return sub, nil
+}
+
+// Page returns a page of nodes in their current order. Each edge is
+// returned on the page of whichever of its nodes comes first, so that the
+// pages together hold every edge once; its other node may be on a later
+// page.
+func (g *Graph) Page(page, limit int) *Graph {
+	start := min((page-1)*limit, len(g.Nodes))
+	end := min(start+limit, len(g.Nodes))
+
+	position := make(map[string]int, len(g.Nodes))
+	for i, node := range g.Nodes {
+		position[node.ID] = i
+	}
+
+	paged := &Graph{Nodes: g.Nodes[start:end], Edges: []Edge{}}
+	for _, edge := range g.Edges {
+		first := min(position[edge.Source], position[edge.Target])
+		if first >= start && first < end {
+			paged.Edges = append(paged.Edges, edge)
+		}
+	}
+	return paged
+}
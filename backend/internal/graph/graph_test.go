@@ -0,0 +1,133 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testGraph is a path a - b - c - d with a chunk of a and an isolated e
+func testGraph() *Graph {
+	g := &Graph{
+		Nodes: []Node{
+			{ID: "a", Type: NodeDocument, Label: "Alpha"},
+			{ID: "b", Type: NodeDocument, Label: "Beta"},
+			{ID: "c", Type: NodeDocument, Label: "Gamma"},
+			{ID: "d", Type: NodeDocument, Label: "Delta"},
+			{ID: "e", Type: NodeDocument, Label: "Epsilon"},
+			{ID: "a_0", Type: NodeChunk, Label: "Alpha #1", DocumentID: "a"},
+		},
+		Edges: []Edge{
+			{Source: "a", Target: "b", Type: EdgeWikilink, Directed: true, Weight: 1, Label: "Beta"},
+			{Source: "c", Target: "b", Type: EdgeTag, Weight: 2, Label: "x, y"},
+			{Source: "c", Target: "d", Type: EdgeSemantic, Weight: 0.85},
+			{Source: "a", Target: "a_0", Type: EdgeContains, Directed: true, Weight: 1},
+		},
+	}
+	g.SetDegrees()
+	return g
+}
+
+func nodeIDs(g *Graph) string {
+	var ids []string
+	for _, node := range g.Nodes {
+		ids = append(ids, node.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestSetDegreesAndSort(t *testing.T) {
+	g := testGraph()
+	g.SortByDegree()
+
+	if ids := nodeIDs(g); ids != "a,b,c,a_0,d,e" {
+		t.Errorf("nodes by degree = %s, want a,b,c,a_0,d,e", ids)
+	}
+	if g.Nodes[0].Degree != 2 || g.Nodes[len(g.Nodes)-1].Degree != 0 {
+		t.Errorf("degrees = %d and %d, want 2 and 0", g.Nodes[0].Degree, g.Nodes[len(g.Nodes)-1].Degree)
+	}
+}
+
+func TestNeighborhood(t *testing.T) {
+	g := testGraph()
+
+	sub, err := g.Neighborhood("b", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := nodeIDs(sub); ids != "b,a,c" {
+		t.Errorf("depth 1 nodes = %s, want b,a,c", ids)
+	}
+	if len(sub.Edges) != 2 {
+		t.Errorf("depth 1 has %d edges, want 2", len(sub.Edges))
+	}
+
+	sub, _ = g.Neighborhood("b", 2)
+	if ids := nodeIDs(sub); ids != "b,a,c,a_0,d" {
+		t.Errorf("depth 2 nodes = %s, want b,a,c,a_0,d", ids)
+	}
+
+	if _, err := g.Neighborhood("missing", 1); err != ErrNodeNotFound {
+		t.Errorf("missing seed error = %v, want ErrNodeNotFound", err)
+	}
+}
+
+func TestPage(t *testing.T) {
+	g := testGraph()
+
+	first, second, third := g.Page(1, 2), g.Page(2, 2), g.Page(3, 2)
+	if nodeIDs(first) != "a,b" || nodeIDs(second) != "c,d" || nodeIDs(third) != "e,a_0" {
+		t.Fatalf("pages = %s, %s and %s", nodeIDs(first), nodeIDs(second), nodeIDs(third))
+	}
+	// c - b is returned with b and a - a_0 with a, on the first page
+	if len(first.Edges) != 3 || len(second.Edges) != 1 || len(third.Edges) != 0 {
+		t.Errorf("pages have %d, %d and %d edges, want 3, 1 and 0", len(first.Edges), len(second.Edges), len(third.Edges))
+	}
+
+	if beyond := g.Page(5, 3); len(beyond.Nodes) != 0 || len(beyond.Edges) != 0 {
+		t.Errorf("page beyond the end = %+v, want empty", beyond)
+	}
+}
+
+func TestExportFormats(t *testing.T) {
+	g := testGraph()
+	g.Nodes[1].Label = `Beta & "friends"`
+
+	for _, format := range []string{FormatGraphML, FormatGEXF} {
+		var buf bytes.Buffer
+		if err := Write(&buf, g, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		// The output must be well-formed XML
+		decoder := xml.NewDecoder(&buf)
+		for {
+			if _, err := decoder.Token(); err != nil {
+				if err != io.EOF {
+					t.Errorf("%s is not well-formed: %v", format, err)
+				}
+				break
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, g, FormatDOT); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+	for _, want := range []string{
+		`"b" [label="Beta & \"friends\"", type="document"];`,
+		`"a" -> "b" [type="wikilink", weight=1, label="Beta"];`,
+		`"c" -> "d" [type="semantic", weight=0.85, dir=none];`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output misses %s:\n%s", want, dot)
+		}
+	}
+
+	if err := Write(&buf, g, "svg"); err == nil {
+		t.Error("unknown format was accepted")
+	}
+}
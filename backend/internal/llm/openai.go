@@ -0,0 +1,178 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAIProvider calls an OpenAI-compatible chat completions API
+type OpenAIProvider struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+}
+
+func NewOpenAIProvider(baseURL, apiKey, model string) *OpenAIProvider {
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	if model == "" {
+		model = "gpt-4o-mini"
+	}
+	return &OpenAIProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{Timeout: 120 * time.Second},
+	}
+}
+
+func (p *OpenAIProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	resp, err := p.post(ctx, p.requestBody(req))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Choices []struct {
+			Message Message `json:"message"`
+		} `json:"choices"`
+		Usage Usage `json:"usage"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("chat completion failed: %s", result.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("chat completion failed with status %d", resp.StatusCode)
+	}
+	if len(result.Choices) == 0 {
+		return nil, fmt.Errorf("no completion returned")
+	}
+
+	return &Response{
+		Content: result.Choices[0].Message.Content,
+		Usage:   result.Usage,
+	}, nil
+}
+
+// Stream requests a streamed completion and passes on each content delta
+// as it arrives
+func (p *OpenAIProvider) Stream(ctx context.Context, req Request, onToken TokenFunc) (*Response, error) {
+	reqBody := p.requestBody(req)
+	reqBody["stream"] = true
+	reqBody["stream_options"] = map[string]bool{"include_usage": true}
+
+	resp, err := p.post(ctx, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var result struct {
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err == nil && result.Error != nil {
+			return nil, fmt.Errorf("chat completion failed: %s", result.Error.Message)
+		}
+		return nil, fmt.Errorf("chat completion failed with status %d", resp.StatusCode)
+	}
+
+	var content strings.Builder
+	var usage Usage
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *Usage `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("invalid stream event: %w", err)
+		}
+		if chunk.Usage != nil {
+			usage = *chunk.Usage
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		token := chunk.Choices[0].Delta.Content
+		content.WriteString(token)
+		if err := onToken(token); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return &Response{Content: content.String(), Usage: usage}, nil
+}
+
+func (p *OpenAIProvider) requestBody(req Request) map[string]interface{} {
+	reqBody := map[string]interface{}{
+		"model":    p.model,
+		"messages": req.Messages,
+	}
+	if req.MaxTokens > 0 {
+		reqBody["max_tokens"] = req.MaxTokens
+	}
+	if req.Temperature != nil {
+		reqBody["temperature"] = *req.Temperature
+	}
+	if req.JSON {
+		reqBody["response_format"] = map[string]string{"type": "json_object"}
+	}
+	return reqBody
+}
+
+func (p *OpenAIProvider) post(ctx context.Context, reqBody map[string]interface{}) (*http.Response, error) {
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	return p.client.Do(httpReq)
+}
@@ -0,0 +1,63 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAIStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Notes \"}}]}\n\n")
+		fmt.Fprint(w, ": keep-alive\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"link [1]\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":3,\"total_tokens\":15}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	var tokens []string
+	resp, err := NewOpenAIProvider(server.URL, "", "").Stream(context.Background(), Request{}, func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tokens) != 2 || resp.Content != "Notes link [1]" {
+		t.Errorf("tokens = %q, content = %q", tokens, resp.Content)
+	}
+	if resp.Usage.TotalTokens != 15 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestOpenAIRequestTemperature(t *testing.T) {
+	zero, warm := 0.0, 0.7
+	tests := []struct {
+		name        string
+		temperature *float64
+		want        interface{}
+		sent        bool
+	}{
+		{name: "Provider default", temperature: nil, sent: false},
+		{name: "Explicit zero", temperature: &zero, want: 0.0, sent: true},
+		{name: "Set", temperature: &warm, want: 0.7, sent: true},
+	}
+
+	provider := NewOpenAIProvider("", "", "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := provider.requestBody(Request{Temperature: tt.temperature})
+			got, ok := body["temperature"]
+			if ok != tt.sent || (ok && got != tt.want) {
+				t.Errorf("temperature = %v (sent %v), want %v (sent %v)", got, ok, tt.want, tt.sent)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+)
+
+// Message is a chat message sent to or received from a model
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Request is a chat completion request
+type Request struct {
+	Messages  []Message
+	MaxTokens int
+	// Temperature is left to the provider's default when nil
+	Temperature *float64
+	// JSON asks the model to reply with a single JSON object
+	JSON bool
+}
+
+// Usage reports the tokens consumed by a request
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Response is the model's reply
+type Response struct {
+	Content string
+	Usage   Usage
+}
+
+// Provider generates chat completions
+type Provider interface {
+	Complete(ctx context.Context, req Request) (*Response, error)
+}
+
+// NewProvider returns the provider named by kind: "openai" for any
+// OpenAI-compatible chat completions API at baseURL, or "stub". It returns
+// no provider when kind is empty.
+func NewProvider(kind, baseURL, apiKey, model string) (Provider, error) {
+	switch kind {
+	case "":
+		return nil, nil
+	case "openai":
+		return NewOpenAIProvider(baseURL, apiKey, model), nil
+	case "stub":
+		return NewStubProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q", kind)
+	}
+}
@@ -0,0 +1,30 @@
+package llm
+
+import "context"
+
+// TokenFunc receives each piece of a streamed completion. Returning an
+// error stops the stream.
+type TokenFunc func(token string) error
+
+// Streamer is implemented by providers that can stream completions. The
+// returned response holds the full content and usage.
+type Streamer interface {
+	Stream(ctx context.Context, req Request, onToken TokenFunc) (*Response, error)
+}
+
+// Stream streams a completion from p, or completes it in one piece when p
+// cannot stream
+func Stream(ctx context.Context, p Provider, req Request, onToken TokenFunc) (*Response, error) {
+	if streamer, ok := p.(Streamer); ok {
+		return streamer.Stream(ctx, req, onToken)
+	}
+
+	resp, err := p.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := onToken(resp.Content); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,247 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"zettelkasten/internal/ranking"
+)
+
+// StubProvider answers requests deterministically without a model, for
+// development and tests. It recognizes the application's prompts by the
+// reply they ask for and derives its responses from the numbered passages
+// and the question they carry.
+type StubProvider struct{}
+
+func NewStubProvider() *StubProvider {
+	return &StubProvider{}
+}
+
+// Markers of the question in the last user message of a prompt
+const (
+	stubQueryPrefix    = "Query: "
+	stubQuestionPrefix = "Question: "
+	stubFollowUpPrefix = "Follow-up: "
+	stubUserPrefix     = "User: "
+)
+
+func (p *StubProvider) Complete(ctx context.Context, req Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var system, user string
+	for _, message := range req.Messages {
+		switch message.Role {
+		case "system":
+			system = message.Content
+		case "user":
+			user = message.Content
+		}
+	}
+
+	var content string
+	switch {
+	case strings.Contains(system, `{"notes"`):
+		content = stubZettels(numberedPassages(user))
+	case strings.Contains(system, `{"scores"`):
+		query, _, _ := strings.Cut(strings.TrimPrefix(user, stubQueryPrefix), "\n")
+		content = stubRerank(query, numberedPassages(user))
+	case strings.Contains(user, stubFollowUpPrefix):
+		i := strings.LastIndex(user, stubFollowUpPrefix)
+		content = stubRewrite(strings.TrimSpace(user[i+len(stubFollowUpPrefix):]), userLines(user[:i]))
+	case strings.Contains(user, stubQuestionPrefix):
+		content = stubAnswer(numberedPassages(user[:strings.LastIndex(user, stubQuestionPrefix)]))
+	default:
+		if len(req.Messages) > 0 {
+			content = req.Messages[len(req.Messages)-1].Content
+		}
+	}
+
+	var prompt int
+	for _, message := range req.Messages {
+		prompt += countTokens(message.Content)
+	}
+	completion := countTokens(content)
+
+	return &Response{
+		Content: content,
+		Usage: Usage{
+			PromptTokens:     prompt,
+			CompletionTokens: completion,
+			TotalTokens:      prompt + completion,
+		},
+	}, nil
+}
+
+// Stream emits the stub's completion word by word
+func (p *StubProvider) Stream(ctx context.Context, req Request, onToken TokenFunc) (*Response, error) {
+	resp, err := p.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, token := range strings.SplitAfter(resp.Content, " ") {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if token == "" {
+			continue
+		}
+		if err := onToken(token); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// stubZettels turns every paragraph of the sources into a note titled by
+// the first words of its first sentence, which is also its summary. Each
+// note links to the note before it.
+func stubZettels(sources []string) string {
+	type note struct {
+		Title        string   `json:"title"`
+		Summary      string   `json:"summary"`
+		Content      string   `json:"content"`
+		Links        []string `json:"links"`
+		SourceChunks []int    `json:"source_chunks"`
+	}
+
+	notes := []note{}
+	for i, source := range sources {
+		for _, paragraph := range strings.Split(source, "\n\n") {
+			paragraph = strings.TrimSpace(paragraph)
+			if paragraph == "" {
+				continue
+			}
+			n := note{
+				Title:        firstWords(firstSentence(paragraph), 6),
+				Summary:      firstSentence(paragraph),
+				Content:      paragraph,
+				Links:        []string{},
+				SourceChunks: []int{i + 1},
+			}
+			if len(notes) > 0 {
+				n.Links = append(n.Links, notes[len(notes)-1].Title)
+			}
+			notes = append(notes, n)
+		}
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{"notes": notes})
+	return string(data)
+}
+
+// stubRerank scores each source from 0 to 10 by the share of query terms
+// it contains
+func stubRerank(query string, sources []string) string {
+	queryTerms := make(map[string]bool)
+	for _, term := range ranking.Terms(query) {
+		queryTerms[term] = true
+	}
+
+	scores := make([]float64, len(sources))
+	for i, source := range sources {
+		found := make(map[string]bool)
+		for _, term := range ranking.Terms(source) {
+			if queryTerms[term] {
+				found[term] = true
+			}
+		}
+		if len(queryTerms) > 0 {
+			scores[i] = math.Round(100*float64(len(found))/float64(len(queryTerms))) / 10
+		}
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{"scores": scores})
+	return string(data)
+}
+
+// stubAnswer quotes the first sentence of up to three sources, citing
+// each one
+func stubAnswer(sources []string) string {
+	if len(sources) == 0 {
+		return "I could not find anything about this in your notes."
+	}
+
+	var sentences []string
+	for i, source := range sources {
+		if i == 3 {
+			break
+		}
+		sentences = append(sentences, fmt.Sprintf("%s [%d]", firstSentence(source), i+1))
+	}
+	return "According to your notes: " + strings.Join(sentences, " ")
+}
+
+// stubRewrite prefixes a follow-up question with the previous question so
+// that its topic carries over
+func stubRewrite(query string, earlierQuestions []string) string {
+	if len(earlierQuestions) == 0 {
+		return query
+	}
+	return earlierQuestions[len(earlierQuestions)-1] + " " + query
+}
+
+// passageHeader starts a numbered passage, optionally followed by its title
+var passageHeader = regexp.MustCompile(`^\[(\d+)\]( .*)?$`)
+
+// numberedPassages reads the passages of a prompt, each introduced by a
+// line "[n]" or "[n] title" numbered from 1. Lines that look like a header
+// but are out of sequence belong to the passage they appear in.
+func numberedPassages(text string) []string {
+	var passages []string
+	var current []string
+	for _, line := range strings.Split(text, "\n") {
+		if match := passageHeader.FindStringSubmatch(line); match != nil && match[1] == strconv.Itoa(len(passages)+1) {
+			if len(passages) > 0 {
+				passages[len(passages)-1] = strings.TrimSpace(strings.Join(current, "\n"))
+			}
+			passages = append(passages, "")
+			current = nil
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(passages) > 0 {
+		passages[len(passages)-1] = strings.TrimSpace(strings.Join(current, "\n"))
+	}
+	return passages
+}
+
+// userLines returns the user's turns of a conversation transcript
+func userLines(conversation string) []string {
+	var lines []string
+	for _, line := range strings.Split(conversation, "\n") {
+		if text, ok := strings.CutPrefix(line, stubUserPrefix); ok {
+			lines = append(lines, text)
+		}
+	}
+	return lines
+}
+
+func firstWords(text string, n int) string {
+	words := strings.Fields(strings.TrimLeft(text, "#>-* "))
+	if len(words) > n {
+		words = words[:n]
+	}
+	return strings.TrimRight(strings.Join(words, " "), ".,;:!?")
+}
+
+func firstSentence(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if i := strings.IndexAny(text, ".!?"); i >= 0 {
+		return text[:i+1]
+	}
+	return text
+}
+
+// countTokens approximates the token count of text at four bytes a token
+func countTokens(text string) int {
+	return (len(text) + 3) / 4
+}
@@ -0,0 +1,131 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestStubZettels(t *testing.T) {
+	resp, err := NewStubProvider().Complete(context.Background(), Request{
+		Messages: []Message{
+			{Role: "system", Content: `Reply with a JSON object of the form {"notes":[...]}`},
+			{Role: "user", Content: "[1]\nSpaced repetition improves recall. Reviews are scheduled at growing intervals.\n\n" +
+				"[2]\n## Atomic notes hold one idea\n\nLinks connect them.\n\n"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reply struct {
+		Notes []struct {
+			Title        string   `json:"title"`
+			Summary      string   `json:"summary"`
+			Links        []string `json:"links"`
+			SourceChunks []int    `json:"source_chunks"`
+		} `json:"notes"`
+	}
+	if err := json.Unmarshal([]byte(resp.Content), &reply); err != nil {
+		t.Fatalf("invalid response %q: %v", resp.Content, err)
+	}
+
+	if len(reply.Notes) != 3 {
+		t.Fatalf("got %d notes, want 3", len(reply.Notes))
+	}
+	first := reply.Notes[0]
+	if first.Title != "Spaced repetition improves recall" {
+		t.Errorf("title = %q", first.Title)
+	}
+	if first.Summary != "Spaced repetition improves recall." {
+		t.Errorf("summary = %q", first.Summary)
+	}
+	if len(first.Links) != 0 {
+		t.Errorf("first note links = %q, want none", first.Links)
+	}
+
+	second := reply.Notes[1]
+	if second.Title != "Atomic notes hold one idea" {
+		t.Errorf("title = %q", second.Title)
+	}
+	if !reflect.DeepEqual(second.SourceChunks, []int{2}) {
+		t.Errorf("source chunks = %v, want [2]", second.SourceChunks)
+	}
+	if !reflect.DeepEqual(second.Links, []string{first.Title}) {
+		t.Errorf("links = %q, want %q", second.Links, first.Title)
+	}
+
+	if resp.Usage.TotalTokens != resp.Usage.PromptTokens+resp.Usage.CompletionTokens || resp.Usage.CompletionTokens == 0 {
+		t.Errorf("unexpected usage %+v", resp.Usage)
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	if _, err := NewProvider("stub", "", "", ""); err != nil {
+		t.Errorf("stub: %v", err)
+	}
+	if provider, err := NewProvider("", "", "", ""); provider != nil || err != nil {
+		t.Errorf("empty kind = %v, %v, want no provider", provider, err)
+	}
+	if _, err := NewProvider("unknown", "", "", ""); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
+
+func TestStubAnswer(t *testing.T) {
+	resp, err := NewStubProvider().Complete(context.Background(), Request{
+		Messages: []Message{
+			{Role: "system", Content: "You answer questions using only the user's notes."},
+			{Role: "user", Content: "Sources:\n\n[1] Atomicity\nNotes should be atomic. Each holds one idea.\n\n" +
+				"[2] Links\nLinks matter.\n\nQuestion: what makes a good note?"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "According to your notes: Notes should be atomic. [1] Links matter. [2]"
+	if resp.Content != expected {
+		t.Errorf("answer = %q, want %q", resp.Content, expected)
+	}
+}
+
+func TestStubStream(t *testing.T) {
+	var streamed string
+	resp, err := NewStubProvider().Stream(context.Background(), Request{
+		Messages: []Message{{Role: "user", Content: "[1] Links\nLinks matter.\n\nQuestion: do links matter?"}},
+	}, func(token string) error {
+		streamed += token
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if streamed != resp.Content {
+		t.Errorf("streamed %q, want %q", streamed, resp.Content)
+	}
+}
+
+func TestStubRewrite(t *testing.T) {
+	resp, err := NewStubProvider().Complete(context.Background(), Request{
+		Messages: []Message{
+			{Role: "system", Content: "You rewrite follow-up questions for a search engine."},
+			{Role: "user", Content: "Conversation:\nUser: what is spaced repetition?\nAssistant: Reviews at growing intervals [1].\n\n" +
+				"Follow-up: and how often?"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "what is spaced repetition? and how often?" {
+		t.Errorf("rewrite = %q", resp.Content)
+	}
+}
+
+func TestNumberedPassages(t *testing.T) {
+	text := "Query: q\n\n[1]\nFirst passage.\n[3] is not a header here.\n\n[2] Title\nSecond passage.\n\n"
+	expected := []string{"First passage.\n[3] is not a header here.", "Second passage."}
+	if passages := numberedPassages(text); !reflect.DeepEqual(passages, expected) {
+		t.Errorf("numberedPassages() = %q, want %q", passages, expected)
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ChatSession is a conversation answered from the user's notes
+type ChatSession struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Title  string             `bson:"title" json:"title"`
+	Scope  ChatScope          `bson:"scope" json:"scope"`
+	// Messages are omitted when sessions are listed
+	Messages  []ChatMessage `bson:"messages,omitempty" json:"messages,omitempty"`
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time     `bson:"updated_at" json:"updated_at"`
+}
+
+// ChatScope limits retrieval to some documents or tags. An empty scope
+// searches all of the user's notes.
+type ChatScope struct {
+	DocumentIDs []string `bson:"document_ids,omitempty" json:"document_ids,omitempty"`
+	Tags        []string `bson:"tags,omitempty" json:"tags,omitempty"`
+}
+
+type ChatMessage struct {
+	Role    string `bson:"role" json:"role"`
+	Content string `bson:"content" json:"content"`
+	// StandaloneQuery is the user's question rewritten without reference
+	// to earlier turns, as it was searched
+	StandaloneQuery string `bson:"standalone_query,omitempty" json:"standalone_query,omitempty"`
+	// Citations and TokensUsed are set on assistant messages
+	Citations  []Citation `bson:"citations,omitempty" json:"citations,omitempty"`
+	TokensUsed int        `bson:"tokens_used,omitempty" json:"tokens_used,omitempty"`
+	CreatedAt  time.Time  `bson:"created_at" json:"created_at"`
+}
+
+// Citation maps a citation number in an answer to its chunk
+type Citation struct {
+	Number     int    `bson:"number" json:"number"`
+	ChunkID    string `bson:"chunk_id" json:"chunk_id"`
+	DocumentID string `bson:"document_id" json:"document_id"`
+	Title      string `bson:"title" json:"title"`
+}
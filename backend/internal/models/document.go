@@ -15,4 +15,43 @@ type Document struct {
 	UploadedAt time.Time              `bson:"uploaded_at" json:"uploaded_at"`
 	Metadata   map[string]interface{} `bson:"metadata" json:"metadata"`
 	Status     string                 `bson:"status" json:"status"`
+	// Tags are normalized with parsers.NormalizeTags and copied to every
+	// chunk's vector metadata
+	Tags []string `bson:"tags,omitempty" json:"tags,omitempty"`
+	// Links are the targets of the document's [[wikilinks]], as written
+	Links    []string          `bson:"links,omitempty" json:"links,omitempty"`
+	Chunking *ChunkingSettings `bson:"chunking,omitempty" json:"chunking,omitempty"`
+	// SourceFileID points at the uploaded file in GridFS so the document
+	// can be rechunked; SourceIndex is its position among the documents
+	// parsed from that file
+	SourceFileID *primitive.ObjectID `bson:"source_file_id,omitempty" json:"-"`
+	SourceIndex  int                 `bson:"source_index" json:"-"`
+	// Zettel is set on atomic notes extracted from another document
+	Zettel *Zettel `bson:"zettel,omitempty" json:"zettel,omitempty"`
+}
+
+// Zettel holds what was extracted alongside an atomic note
+type Zettel struct {
+	Summary          string       `bson:"summary" json:"summary"`
+	SuggestedLinks   []ZettelLink `bson:"suggested_links" json:"suggested_links"`
+	SourceDocumentID string       `bson:"source_document_id" json:"source_document_id"`
+	// SourceChunkIDs are the vector IDs of the chunks the note came from
+	SourceChunkIDs []string `bson:"source_chunk_ids" json:"source_chunk_ids"`
+}
+
+// ZettelLink is a link suggested by the model. DocumentID is set once the
+// title has been matched to one of the user's notes.
+type ZettelLink struct {
+	Title      string `bson:"title" json:"title"`
+	DocumentID string `bson:"document_id,omitempty" json:"document_id,omitempty"`
+}
+
+// ChunkingSettings describe how a document is split into chunks. Sizes are
+// in tokens; zero values use the chunker defaults.
+type ChunkingSettings struct {
+	Preset    string `bson:"preset,omitempty" json:"preset,omitempty"`
+	Strategy  string `bson:"strategy,omitempty" json:"strategy,omitempty"`
+	MinTokens int    `bson:"min_tokens,omitempty" json:"min_tokens,omitempty"`
+	MaxTokens int    `bson:"max_tokens,omitempty" json:"max_tokens,omitempty"`
+	Overlap   int    `bson:"overlap_tokens,omitempty" json:"overlap_tokens,omitempty"`
 }
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Link connects two of a user's documents. Links are suggested from the
+// similarity of their chunks and become permanent once accepted; either
+// document may be given as the source, as links work in both directions.
+type Link struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID           primitive.ObjectID `bson:"user_id" json:"user_id"`
+	SourceDocumentID string             `bson:"source_document_id" json:"source_document_id"`
+	TargetDocumentID string             `bson:"target_document_id" json:"target_document_id"`
+	// SourceChunkID and TargetChunkID are the most similar pair of chunks
+	// found between the documents
+	SourceChunkID string  `bson:"source_chunk_id" json:"source_chunk_id"`
+	TargetChunkID string  `bson:"target_chunk_id" json:"target_chunk_id"`
+	Score         float32 `bson:"score" json:"score"`
+	Status        string  `bson:"status" json:"status"`
+	// Pair identifies the two documents regardless of direction
+	Pair      string    `bson:"pair" json:"-"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+	// SourceTitle and TargetTitle are filled in when links are listed
+	SourceTitle string `bson:"-" json:"source_title,omitempty"`
+	TargetTitle string `bson:"-" json:"target_title,omitempty"`
+}
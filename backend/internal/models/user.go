@@ -20,4 +20,6 @@ type User struct {
 type UserPreferences struct {
 	DefaultSimilarityThreshold float32 `bson:"default_similarity_threshold" json:"default_similarity_threshold"`
 	ChunkSizePreference        string  `bson:"chunk_size_preference" json:"chunk_size_preference"`
+	// CustomChunking is used when ChunkSizePreference is "custom"
+	CustomChunking *ChunkingSettings `bson:"custom_chunking,omitempty" json:"custom_chunking,omitempty"`
 }
@@ -0,0 +1,195 @@
+package parsers
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"zettelkasten/internal/tokenizer"
+)
+
+// Chunker splits the text extracted by a parser into chunks
+type Chunker interface {
+	Chunk(text string) ([]string, error)
+}
+
+// StructuredChunker is implemented by chunkers that attach metadata, such as
+// a heading breadcrumb, to the chunks they produce
+type StructuredChunker interface {
+	ChunkStructured(text string) ([]Chunk, error)
+}
+
+// ChunkerSetter is implemented by parsers whose chunking strategy can be
+// chosen per upload
+type ChunkerSetter interface {
+	SetChunker(chunker Chunker)
+}
+
+// Chunking strategies
+const (
+	StrategyParagraph = "paragraph"
+	StrategySentence  = "sentence"
+	StrategyFixed     = "fixed"
+	StrategyHeading   = "heading"
+	StrategyMarkdown  = "markdown"
+	StrategySemantic  = "semantic"
+)
+
+// Default chunk sizes, roughly the 100 words to 3000 characters produced by
+// ChunkByParagraphs
+const (
+	DefaultMinTokens = 128
+	DefaultMaxTokens = 768
+
+	// maxEmbeddingTokens is the input limit of text-embedding-3-small
+	maxEmbeddingTokens = 8191
+)
+
+// ChunkingConfig selects a chunking strategy and sizes its chunks in tokens
+type ChunkingConfig struct {
+	Strategy  string `json:"strategy,omitempty" bson:"strategy,omitempty"`
+	MinTokens int    `json:"min_tokens,omitempty" bson:"min_tokens,omitempty"`
+	MaxTokens int    `json:"max_tokens,omitempty" bson:"max_tokens,omitempty"`
+	// Overlap is the number of tokens repeated from the end of each chunk at
+	// the start of the next
+	Overlap int `json:"overlap_tokens,omitempty" bson:"overlap_tokens,omitempty"`
+}
+
+// DefaultStrategy returns the chunking strategy used for a file when none is
+// chosen: markdown for Obsidian and Logseq notes and Markdown files, whose
+// structure it follows, and paragraph otherwise
+func DefaultStrategy(sourceType, filename string) string {
+	switch sourceType {
+	case "obsidian", "logseq":
+		return StrategyMarkdown
+	case "standard":
+		switch strings.ToLower(path.Ext(filename)) {
+		case ".md", ".markdown":
+			return StrategyMarkdown
+		}
+	}
+	return StrategyParagraph
+}
+
+// WithDefaults fills in unset fields
+func (c ChunkingConfig) WithDefaults() ChunkingConfig {
+	if c.Strategy == "" {
+		c.Strategy = StrategyParagraph
+	}
+	if c.MaxTokens == 0 {
+		c.MaxTokens = DefaultMaxTokens
+	}
+	if c.MinTokens == 0 {
+		c.MinTokens = DefaultMinTokens
+		if c.MinTokens > c.MaxTokens {
+			c.MinTokens = c.MaxTokens
+		}
+	}
+	return c
+}
+
+// Validate checks the config after defaults have been applied
+func (c ChunkingConfig) Validate() error {
+	c = c.WithDefaults()
+
+	switch c.Strategy {
+	case StrategyParagraph, StrategySentence, StrategyFixed, StrategyHeading, StrategyMarkdown, StrategySemantic:
+	default:
+		return fmt.Errorf("unknown chunking strategy %q", c.Strategy)
+	}
+
+	if c.MinTokens < 0 || c.MaxTokens < 0 || c.Overlap < 0 {
+		return fmt.Errorf("chunk sizes must not be negative")
+	}
+	if c.MaxTokens > maxEmbeddingTokens {
+		return fmt.Errorf("max_tokens must be at most %d", maxEmbeddingTokens)
+	}
+	if c.MinTokens > c.MaxTokens {
+		return fmt.Errorf("min_tokens must not exceed max_tokens")
+	}
+	if c.Overlap >= c.MaxTokens {
+		return fmt.Errorf("overlap_tokens must be less than max_tokens")
+	}
+	return nil
+}
+
+// NewChunker builds the chunker described by config. The embedder is only
+// used by the semantic strategy.
+func NewChunker(config ChunkingConfig, tok tokenizer.Tokenizer, embedder Embedder) (Chunker, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	config = config.WithDefaults()
+	if tok == nil {
+		tok = tokenizer.NewEstimator()
+	}
+
+	sizer := tokenSizer{tokenizer: tok, config: config}
+	switch config.Strategy {
+	case StrategySentence:
+		return &SentenceChunker{tokenSizer: sizer}, nil
+	case StrategyFixed:
+		return &FixedTokenChunker{tokenSizer: sizer}, nil
+	case StrategyHeading:
+		return &HeadingChunker{tokenSizer: sizer}, nil
+	case StrategyMarkdown:
+		return &MarkdownChunker{tokenSizer: sizer}, nil
+	case StrategySemantic:
+		if embedder == nil {
+			return nil, fmt.Errorf("semantic chunking requires an embedder")
+		}
+		chunker := NewSemanticChunker(embedder, tok)
+		chunker.MaxTokens = config.MaxTokens
+		return chunker, nil
+	default:
+		return &ParagraphChunker{tokenSizer: sizer}, nil
+	}
+}
+
+// chunking is embedded by parsers to make their chunker configurable
+type chunking struct {
+	chunker Chunker
+}
+
+func (c *chunking) SetChunker(chunker Chunker) {
+	c.chunker = chunker
+}
+
+// chunk splits text with the configured chunker, defaulting to paragraph
+// chunking with a minimum of 100 words per chunk
+func (c *chunking) chunk(text string) ([]string, error) {
+	if c.chunker == nil {
+		return ChunkByParagraphs(text, 100), nil
+	}
+	return c.chunker.Chunk(text)
+}
+
+// chunkStructured splits text like chunk, keeping the metadata of chunkers
+// that provide it
+func (c *chunking) chunkStructured(text string) ([]Chunk, error) {
+	if structured, ok := c.chunker.(StructuredChunker); ok {
+		return structured.ChunkStructured(text)
+	}
+
+	texts, err := c.chunk(text)
+	if err != nil {
+		return nil, err
+	}
+	chunks := make([]Chunk, len(texts))
+	for i, text := range texts {
+		chunks[i] = Chunk{Content: text}
+	}
+	return chunks, nil
+}
+
+// withChunkMetadata adds the parser's metadata for a chunk to the metadata
+// set by the chunker
+func withChunkMetadata(metadata, extra map[string]interface{}) map[string]interface{} {
+	if metadata == nil {
+		return extra
+	}
+	for key, value := range extra {
+		metadata[key] = value
+	}
+	return metadata
+}
@@ -1,8 +1,12 @@
 package parsers
 
 import (
+	"fmt"
 	"strings"
 	"testing"
+
+	"zettelkasten/internal/embeddings"
+	"zettelkasten/internal/tokenizer"
 )
 
 func TestChunkByParagraphs(t *testing.T) {
@@ -102,3 +106,402 @@ func TestCountWords(t *testing.T) {
 		})
 	}
 }
+
+func TestSemanticChunker(t *testing.T) {
+	text := `Fresh pasta needs flour and eggs. Knead the pasta dough until the dough is smooth. Rest the pasta dough before rolling the pasta thin. Boil the pasta in salted water for two minutes.
+
+Telescopes gather light from distant stars. A larger telescope mirror collects more starlight. Astronomers point the telescope at faint stars and galaxies. Dark skies help the telescope reveal more stars.`
+
+	chunker := NewSemanticChunker(embeddings.NewOfflineEmbedder(256), nil)
+	chunks, err := chunker.Chunk(text)
+	if err != nil {
+		t.Fatalf("Chunk() error = %v", err)
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("Chunk() = %d chunks, want 2: %q", len(chunks), chunks)
+	}
+	if !strings.HasPrefix(chunks[0], "Fresh pasta") || !strings.HasSuffix(chunks[0], "two minutes.") {
+		t.Errorf("first chunk = %q, want the pasta paragraph", chunks[0])
+	}
+	if !strings.HasPrefix(chunks[1], "Telescopes") {
+		t.Errorf("second chunk = %q, want the telescope paragraph", chunks[1])
+	}
+}
+
+// countingEmbedder records the texts it is asked to embed
+type countingEmbedder struct {
+	*embeddings.OfflineEmbedder
+	texts []string
+}
+
+func (e *countingEmbedder) GenerateEmbeddings(texts []string) ([][]float32, error) {
+	e.texts = append(e.texts, texts...)
+	return e.OfflineEmbedder.GenerateEmbeddings(texts)
+}
+
+func TestSemanticChunkerEmbedsEachWindowOnce(t *testing.T) {
+	text := strings.Repeat("One sentence about pasta. Another about stars. ", 10)
+
+	embedder := &countingEmbedder{OfflineEmbedder: embeddings.NewOfflineEmbedder(64)}
+	chunker := NewSemanticChunker(embedder, nil)
+	if _, err := chunker.Chunk(text); err != nil {
+		t.Fatalf("Chunk() error = %v", err)
+	}
+
+	// 20 sentences have 19 gaps; the windows before and after them overlap
+	// in all but BufferSize+1 cases
+	if want := 20 + chunker.BufferSize; len(embedder.texts) != want {
+		t.Errorf("embedded %d texts, want %d", len(embedder.texts), want)
+	}
+}
+
+func TestSplitParagraphSentences(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected int
+	}{
+		{
+			name:     "Simple sentences",
+			text:     "One sentence. Another one! A question?",
+			expected: 3,
+		},
+		{
+			name:     "Abbreviations",
+			text:     "Dr. Smith met Mr. Jones, e.g. at noon. They talked.",
+			expected: 2,
+		},
+		{
+			name:     "Decimal numbers and quotes",
+			text:     `Pi is about 3.14 in value. "Really?" she asked.`,
+			expected: 3,
+		},
+		{
+			name:     "No terminal punctuation",
+			text:     "A fragment without an ending",
+			expected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sentences := splitParagraphSentences(tt.text)
+			if len(sentences) != tt.expected {
+				t.Errorf("splitParagraphSentences() = %d sentences %q, want %d", len(sentences), sentences, tt.expected)
+			}
+		})
+	}
+}
+
+func newTestChunker(t *testing.T, config ChunkingConfig) Chunker {
+	t.Helper()
+
+	chunker, err := NewChunker(config, tokenizer.NewEstimator(), nil)
+	if err != nil {
+		t.Fatalf("NewChunker() error = %v", err)
+	}
+	return chunker
+}
+
+func TestParagraphChunkerTokens(t *testing.T) {
+	tok := tokenizer.NewEstimator()
+	sentence := "The quick brown fox jumps over the lazy dog."
+	paragraph := strings.TrimSpace(strings.Repeat(sentence+" ", 2))
+	long := strings.TrimSpace(strings.Repeat(sentence+" ", 30))
+
+	chunker := newTestChunker(t, ChunkingConfig{Strategy: StrategyParagraph, MinTokens: 50, MaxTokens: 100})
+	chunks, err := chunker.Chunk(strings.Join([]string{paragraph, paragraph, long, paragraph}, "\n\n"))
+	if err != nil {
+		t.Fatalf("Chunk() error = %v", err)
+	}
+
+	// Two short paragraphs are grouped before the long paragraph is split
+	// on sentence boundaries
+	if chunks[0] != paragraph+"\n\n"+paragraph {
+		t.Errorf("first chunk = %q, want the first two paragraphs", chunks[0])
+	}
+	for _, chunk := range chunks {
+		if tok.Count(chunk) > 100 {
+			t.Errorf("chunk has %d tokens, want at most 100: %q", tok.Count(chunk), chunk)
+		}
+		if !strings.HasSuffix(chunk, ".") {
+			t.Errorf("chunk %q does not end on a sentence boundary", chunk)
+		}
+	}
+	if len(chunks) < 4 {
+		t.Errorf("Chunk() = %d chunks, want the long paragraph split", len(chunks))
+	}
+}
+
+func TestSentenceChunkerOverlap(t *testing.T) {
+	text := "First sentence is here. Second sentence is here. Third sentence is here. Fourth sentence is here."
+
+	chunker := newTestChunker(t, ChunkingConfig{Strategy: StrategySentence, MinTokens: 15, MaxTokens: 20, Overlap: 9})
+	chunks, err := chunker.Chunk(text)
+	if err != nil {
+		t.Fatalf("Chunk() error = %v", err)
+	}
+
+	expected := []string{
+		"First sentence is here. Second sentence is here.",
+		"Second sentence is here. Third sentence is here.",
+		"Third sentence is here. Fourth sentence is here.",
+	}
+	if len(chunks) != len(expected) {
+		t.Fatalf("Chunk() = %q, want %q", chunks, expected)
+	}
+	for i := range expected {
+		if chunks[i] != expected[i] {
+			t.Errorf("chunk %d = %q, want %q", i, chunks[i], expected[i])
+		}
+	}
+}
+
+func TestFixedTokenChunker(t *testing.T) {
+	tok := tokenizer.NewEstimator()
+	text := strings.TrimSpace(strings.Repeat("alpha beta gamma delta ", 50))
+
+	chunker := newTestChunker(t, ChunkingConfig{Strategy: StrategyFixed, MaxTokens: 40, Overlap: 10})
+	chunks, err := chunker.Chunk(text)
+	if err != nil {
+		t.Fatalf("Chunk() error = %v", err)
+	}
+
+	if len(chunks) < 2 {
+		t.Fatalf("Chunk() = %d chunks, want several windows", len(chunks))
+	}
+	for _, chunk := range chunks {
+		if tok.Count(chunk) > 40 {
+			t.Errorf("chunk has %d tokens, want at most 40", tok.Count(chunk))
+		}
+	}
+	if !strings.HasSuffix(chunks[len(chunks)-1], "delta") {
+		t.Errorf("last chunk = %q, want it to reach the end of the text", chunks[len(chunks)-1])
+	}
+
+	// Consecutive windows share the overlap
+	first := strings.Fields(chunks[0])
+	if !strings.Contains(chunks[1], strings.Join(first[len(first)-3:], " ")) {
+		t.Errorf("second chunk %q does not overlap the first %q", chunks[1], chunks[0])
+	}
+}
+
+func TestHeadingChunker(t *testing.T) {
+	text := "# Title\n\nIntro text.\n\n## Setup\n\nInstall it.\n\n```sh\n# not a heading\nmake\n```\n\n## Usage\n\nRun it."
+
+	chunker := newTestChunker(t, ChunkingConfig{Strategy: StrategyHeading})
+	chunks, err := chunker.Chunk(text)
+	if err != nil {
+		t.Fatalf("Chunk() error = %v", err)
+	}
+
+	if len(chunks) != 3 {
+		t.Fatalf("Chunk() = %d chunks, want 3: %q", len(chunks), chunks)
+	}
+	for i, prefix := range []string{"# Title", "## Setup", "## Usage"} {
+		if !strings.HasPrefix(chunks[i], prefix) {
+			t.Errorf("chunk %d = %q, want prefix %q", i, chunks[i], prefix)
+		}
+	}
+	if !strings.Contains(chunks[1], "# not a heading") {
+		t.Errorf("code block comment split the section: %q", chunks[1])
+	}
+}
+
+func TestChunkingConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  ChunkingConfig
+		wantErr bool
+	}{
+		{name: "Defaults", config: ChunkingConfig{}},
+		{name: "Sentence with overlap", config: ChunkingConfig{Strategy: StrategySentence, MaxTokens: 200, Overlap: 50}},
+		{name: "Unknown strategy", config: ChunkingConfig{Strategy: "words"}, wantErr: true},
+		{name: "Min above max", config: ChunkingConfig{MinTokens: 500, MaxTokens: 100}, wantErr: true},
+		{name: "Overlap not below max", config: ChunkingConfig{MaxTokens: 100, Overlap: 100}, wantErr: true},
+		{name: "Beyond model limit", config: ChunkingConfig{MaxTokens: 10000}, wantErr: true},
+		{name: "Negative", config: ChunkingConfig{Overlap: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMarkdownChunker(t *testing.T) {
+	text := "# Guide\n\nIntro paragraph.\n\n## Install\n\n```sh\nmake deps\n\nmake build\n```\n\n| Flag | Meaning |\n|------|---------|\n| -v | verbose |\n\n| -q | quiet |\n\n## Usage\n\n- First item\n\n  continued after a blank line\n- Second item\n  - nested item\n\nTrailing paragraph."
+
+	chunker := newTestChunker(t, ChunkingConfig{Strategy: StrategyMarkdown, MinTokens: 200, MaxTokens: 400})
+	chunks, err := chunker.(*MarkdownChunker).ChunkStructured(text)
+	if err != nil {
+		t.Fatalf("ChunkStructured() error = %v", err)
+	}
+
+	if len(chunks) != 3 {
+		t.Fatalf("ChunkStructured() = %d chunks, want 3: %q", len(chunks), chunkContents(chunks))
+	}
+
+	if !strings.Contains(chunks[1].Content, "```sh\nmake deps\n\nmake build\n```") {
+		t.Errorf("code fence was split: %q", chunks[1].Content)
+	}
+	if !strings.Contains(chunks[2].Content, "- First item\n\n  continued after a blank line\n- Second item\n  - nested item") {
+		t.Errorf("list items were split: %q", chunks[2].Content)
+	}
+
+	expectedPaths := [][]string{{"Guide"}, {"Guide", "Install"}, {"Guide", "Usage"}}
+	for i, expected := range expectedPaths {
+		path, _ := chunks[i].Metadata[HeadingPathKey].([]string)
+		if strings.Join(path, "/") != strings.Join(expected, "/") {
+			t.Errorf("chunk %d heading path = %v, want %v", i, path, expected)
+		}
+	}
+
+	input := EmbeddingInput(chunks[1])
+	if !strings.HasPrefix(input, "Guide > Install\n\n## Install") {
+		t.Errorf("EmbeddingInput() = %q, want the breadcrumb prefix", input)
+	}
+	if strings.Contains(chunks[1].Content, "Guide > Install") {
+		t.Errorf("chunk content %q contains the breadcrumb", chunks[1].Content)
+	}
+}
+
+func TestMarkdownChunkerKeepsFencesWhole(t *testing.T) {
+	tok := tokenizer.NewEstimator()
+
+	var code strings.Builder
+	code.WriteString("```go\n")
+	for i := 0; i < 60; i++ {
+		code.WriteString("fmt.Println(\"line of generated code\")\n")
+	}
+	code.WriteString("```")
+
+	chunker := newTestChunker(t, ChunkingConfig{Strategy: StrategyMarkdown, MinTokens: 50, MaxTokens: 100})
+	chunks, err := chunker.Chunk("## Code\n\n" + code.String() + "\n\nA closing paragraph.")
+	if err != nil {
+		t.Fatalf("Chunk() error = %v", err)
+	}
+
+	if len(chunks) != 2 {
+		t.Fatalf("Chunk() = %d chunks, want the fence and the paragraph", len(chunks))
+	}
+	if !strings.Contains(chunks[0], code.String()) || tok.Count(chunks[0]) <= 100 {
+		t.Errorf("chunk 0 = %q, want the whole fence beyond MaxTokens", chunks[0])
+	}
+	if chunks[1] != "A closing paragraph." {
+		t.Errorf("chunk 1 = %q, want the paragraph", chunks[1])
+	}
+}
+
+func TestMarkdownChunkerSplitsOversizedFences(t *testing.T) {
+	tok := tokenizer.NewEstimator()
+
+	var code strings.Builder
+	code.WriteString("```go\n")
+	for tok.Count(code.String()) <= 2*maxEmbeddingTokens {
+		code.WriteString("fmt.Println(\"line of generated code\")\n")
+	}
+	code.WriteString("```")
+
+	chunker := newTestChunker(t, ChunkingConfig{Strategy: StrategyMarkdown, MinTokens: 50, MaxTokens: 100})
+	chunks, err := chunker.Chunk("## Code\n\n" + code.String())
+	if err != nil {
+		t.Fatalf("Chunk() error = %v", err)
+	}
+
+	if len(chunks) < 3 {
+		t.Fatalf("Chunk() = %d chunks, want the fence split", len(chunks))
+	}
+	for _, chunk := range chunks {
+		if tok.Count(chunk) > maxEmbeddingTokens {
+			t.Errorf("chunk has %d tokens, want at most %d", tok.Count(chunk), maxEmbeddingTokens)
+		}
+		if strings.Count(chunk, "```") != 2 {
+			t.Errorf("chunk %q does not contain a complete fence", chunk[:40])
+		}
+	}
+}
+
+func TestMarkdownChunkerStructure(t *testing.T) {
+	text := "---\ntitle: Guide\ntags: [setup]\n---\n\nGuide\n=====\n\nIntro paragraph.\n\nInstall\n-------\n\nRun the installer.\n\n***\n\nAn aside after a break.\n\n* * *\n\nClosing words."
+
+	chunker := newTestChunker(t, ChunkingConfig{Strategy: StrategyMarkdown, MinTokens: 200, MaxTokens: 400})
+	chunks, err := chunker.(*MarkdownChunker).ChunkStructured(text)
+	if err != nil {
+		t.Fatalf("ChunkStructured() error = %v", err)
+	}
+
+	expected := []struct {
+		content string
+		path    []string
+	}{
+		{"Guide\n=====\n\nIntro paragraph.", []string{"Guide"}},
+		{"Install\n-------\n\nRun the installer.", []string{"Guide", "Install"}},
+		{"An aside after a break.", []string{"Guide", "Install"}},
+		{"Closing words.", []string{"Guide", "Install"}},
+	}
+	if len(chunks) != len(expected) {
+		t.Fatalf("ChunkStructured() = %q, want %d chunks", chunkContents(chunks), len(expected))
+	}
+	for i, want := range expected {
+		if chunks[i].Content != want.content {
+			t.Errorf("chunk %d = %q, want %q", i, chunks[i].Content, want.content)
+		}
+		path, _ := chunks[i].Metadata[HeadingPathKey].([]string)
+		if strings.Join(path, "/") != strings.Join(want.path, "/") {
+			t.Errorf("chunk %d heading path = %v, want %v", i, path, want.path)
+		}
+	}
+}
+
+func TestParseMarkdownBlocks(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		kinds []markdownBlockKind
+	}{
+		{"setext heading spans the paragraph", "First line\nsecond line\n===", []markdownBlockKind{markdownHeading}},
+		{"dashes after a paragraph underline it", "Title\n---\nBody", []markdownBlockKind{markdownHeading, markdownParagraph}},
+		{"dashes after a blank line are a break", "Body\n\n---\n\nMore", []markdownBlockKind{markdownParagraph, markdownBreak, markdownParagraph}},
+		{"spaced asterisks are a break, not a list", "Body\n* * *\nMore", []markdownBlockKind{markdownParagraph, markdownBreak, markdownParagraph}},
+		{"front matter is dropped", "---\nkey: value\n...\nBody", []markdownBlockKind{markdownParagraph}},
+		{"unclosed front matter is a break", "---\nBody", []markdownBlockKind{markdownBreak, markdownParagraph}},
+		{"list item is kept", "- item", []markdownBlockKind{markdownListItem}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := parseMarkdownBlocks(tt.text)
+			kinds := make([]markdownBlockKind, len(blocks))
+			for i, block := range blocks {
+				kinds[i] = block.kind
+			}
+			if fmt.Sprint(kinds) != fmt.Sprint(tt.kinds) {
+				t.Errorf("parseMarkdownBlocks(%q) kinds = %v, want %v", tt.text, kinds, tt.kinds)
+			}
+		})
+	}
+}
+
+func TestDefaultStrategy(t *testing.T) {
+	tests := []struct {
+		sourceType, filename, want string
+	}{
+		{"obsidian", "note.md", StrategyMarkdown},
+		{"logseq", "pages/page.md", StrategyMarkdown},
+		{"standard", "README.MD", StrategyMarkdown},
+		{"standard", "notes.markdown", StrategyMarkdown},
+		{"standard", "notes.txt", StrategyParagraph},
+		{"notion", "export.md", StrategyParagraph},
+	}
+
+	for _, tt := range tests {
+		if got := DefaultStrategy(tt.sourceType, tt.filename); got != tt.want {
+			t.Errorf("DefaultStrategy(%q, %q) = %q, want %q", tt.sourceType, tt.filename, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package parsers
+
+import (
+	"bytes"
+	"path"
+	"strings"
+)
+
+// textExtensions are plain text formats handled by the Markdown-style parsers
+var textExtensions = map[string]bool{
+	".md": true, ".markdown": true, ".txt": true, ".org": true,
+	".rst": true, ".adoc": true, ".asciidoc": true, ".tex": true,
+}
+
+// markdownSourceTypes can parse plain text and Markdown entries
+var markdownSourceTypes = map[string]bool{
+	"standard": true, "obsidian": true, "logseq": true, "readwise": true,
+}
+
+// DetectVaultType inspects the paths of an uploaded folder or archive for
+// the configuration directories written by Obsidian and Logseq
+func DetectVaultType(paths []string) string {
+	for _, p := range paths {
+		for _, part := range strings.Split(p, "/") {
+			if part == ".obsidian" {
+				return "obsidian"
+			}
+		}
+		if strings.HasSuffix(p, "logseq/config.edn") {
+			return "logseq"
+		}
+	}
+	return ""
+}
+
+// DetectSourceType chooses the parser for a single file extracted from an
+// archive. Formats with an unambiguous signature are detected from the file
+// itself; other text files use fallback. An empty result means the entry
+// should be skipped (hidden, binary or unsupported files).
+func DetectSourceType(entryPath string, data []byte, fallback string) string {
+	for _, part := range strings.Split(entryPath, "/") {
+		if strings.HasPrefix(part, ".") {
+			return ""
+		}
+	}
+
+	ext := strings.ToLower(path.Ext(entryPath))
+	base := strings.ToLower(path.Base(entryPath))
+
+	switch ext {
+	case ".epub":
+		return "epub"
+	case ".mbox", ".eml":
+		return "email"
+	case ".json":
+		trimmed := bytes.TrimSpace(data)
+		switch {
+		case bytes.HasPrefix(trimmed, []byte("[")):
+			return "roam"
+		case bytes.HasPrefix(trimmed, []byte("{")):
+			return "notion"
+		default:
+			return ""
+		}
+	case ".csv":
+		header := data
+		if i := bytes.IndexByte(header, '\n'); i >= 0 {
+			header = header[:i]
+		}
+		if bytes.Contains(header, []byte("Highlight")) && bytes.Contains(header, []byte("Book Title")) {
+			return "readwise"
+		}
+		return "standard"
+	}
+
+	if !textExtensions[ext] || isBinary(data) {
+		return ""
+	}
+
+	if base == "my clippings.txt" || (ext == ".txt" && bytes.Contains(data, []byte("- Your Highlight"))) {
+		return "kindle"
+	}
+
+	if markdownSourceTypes[fallback] {
+		return fallback
+	}
+	return "standard"
+}
+
+// isBinary reports whether data looks like binary content
+func isBinary(data []byte) bool {
+	sample := data
+	if len(sample) > 8000 {
+		sample = sample[:8000]
+	}
+	return bytes.IndexByte(sample, 0) >= 0
+}
@@ -0,0 +1,44 @@
+package parsers
+
+import "testing"
+
+func TestDetectSourceType(t *testing.T) {
+	tests := []struct {
+		path     string
+		data     string
+		fallback string
+		expected string
+	}{
+		{"vault/note.md", "# Note", "obsidian", "obsidian"},
+		{"export/page.md", "# Page", "notion", "standard"},
+		{"books/smart-notes.epub", "PK", "standard", "epub"},
+		{"mail/inbox.mbox", "From a@b", "standard", "email"},
+		{"roam/graph.json", `[{"title": "Page"}]`, "standard", "roam"},
+		{"notion/page.json", `{"title": "Page"}`, "standard", "notion"},
+		{"kindle/My Clippings.txt", "Book (Author)", "standard", "kindle"},
+		{"readwise.csv", "Highlight,Book Title,Book Author\n", "standard", "readwise"},
+		{"vault/.obsidian/workspace.json", "{}", "obsidian", ""},
+		{"images/photo.png", "\x89PNG", "standard", ""},
+		{"notes/binary.txt", "abc\x00def", "standard", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := DetectSourceType(tt.path, []byte(tt.data), tt.fallback); got != tt.expected {
+				t.Errorf("DetectSourceType(%q) = %q, want %q", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDetectVaultType(t *testing.T) {
+	if got := DetectVaultType([]string{"vault/note.md", "vault/.obsidian/app.json"}); got != "obsidian" {
+		t.Errorf("DetectVaultType() = %q, want obsidian", got)
+	}
+	if got := DetectVaultType([]string{"graph/pages/a.md", "graph/logseq/config.edn"}); got != "logseq" {
+		t.Errorf("DetectVaultType() = %q, want logseq", got)
+	}
+	if got := DetectVaultType([]string{"notes/a.md"}); got != "" {
+		t.Errorf("DetectVaultType() = %q, want empty", got)
+	}
+}
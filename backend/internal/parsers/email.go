@@ -0,0 +1,449 @@
+package parsers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// EmailParser reads mbox archives and single .eml messages, grouping
+// messages into one document per conversation thread
+type EmailParser struct {
+	chunking
+	wordDecoder      *mime.WordDecoder
+	attributionRegex *regexp.Regexp
+	subjectRegex     *regexp.Regexp
+	messageIDRegex   *regexp.Regexp
+}
+
+func NewEmailParser() *EmailParser {
+	return &EmailParser{
+		wordDecoder:      &mime.WordDecoder{CharsetReader: charsetReader},
+		attributionRegex: regexp.MustCompile(`(?i)^(on\s.+wrote:|.+\s(schrieb|a écrit|escribió)\s?.*:)$`),
+		subjectRegex:     regexp.MustCompile(`(?i)^((re|fw|fwd|aw|wg)(\[\d+\])?:\s*)+`),
+		messageIDRegex:   regexp.MustCompile(`<[^<>\s]+>`),
+	}
+}
+
+// emailMessage is a decoded message with the headers we keep as metadata
+type emailMessage struct {
+	MessageID  string
+	InReplyTo  []string
+	References []string
+	From       string
+	FromName   string
+	To         []string
+	Subject    string
+	Date       time.Time
+	Body       string
+	order      int
+}
+
+func (p *EmailParser) Parse(file io.Reader, filename string) ([]string, map[string]interface{}, error) {
+	documents, err := p.ParseDocuments(file, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	chunks, metadata := flattenDocuments(documents, filename)
+	return chunks, metadata, nil
+}
+
+func (p *EmailParser) ParseDocuments(file io.Reader, filename string) ([]ParsedDocument, error) {
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var rawMessages [][]byte
+	if bytes.HasPrefix(content, []byte("From ")) {
+		rawMessages = splitMbox(content)
+	} else {
+		rawMessages = [][]byte{content}
+	}
+
+	var messages []*emailMessage
+	for i, raw := range rawMessages {
+		msg, err := p.parseMessage(raw)
+		if err != nil {
+			// Skip malformed messages in an archive rather than failing it
+			if len(rawMessages) == 1 {
+				return nil, fmt.Errorf("failed to parse email: %w", err)
+			}
+			continue
+		}
+		msg.order = i
+		messages = append(messages, msg)
+	}
+
+	var documents []ParsedDocument
+	for _, thread := range groupThreads(messages) {
+		document, err := p.threadDocument(thread, filename)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+	return documents, nil
+}
+
+// splitMbox splits an mbox archive on its "From " separator lines and
+// undoes the ">From " quoting applied to message bodies
+func splitMbox(content []byte) [][]byte {
+	var messages [][]byte
+	var current bytes.Buffer
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if bytes.HasPrefix(line, []byte("From ")) {
+			if current.Len() > 0 {
+				messages = append(messages, append([]byte(nil), current.Bytes()...))
+				current.Reset()
+			}
+			continue
+		}
+		if bytes.HasPrefix(bytes.TrimLeft(line, ">"), []byte("From ")) && line[0] == '>' {
+			line = line[1:]
+		}
+		current.Write(line)
+		current.WriteByte('\n')
+	}
+	if current.Len() > 0 {
+		messages = append(messages, current.Bytes())
+	}
+	return messages
+}
+
+func (p *EmailParser) parseMessage(raw []byte) (*emailMessage, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &emailMessage{
+		MessageID:  p.firstMessageID(m.Header.Get("Message-Id")),
+		InReplyTo:  p.messageIDRegex.FindAllString(m.Header.Get("In-Reply-To"), -1),
+		References: p.messageIDRegex.FindAllString(m.Header.Get("References"), -1),
+		Subject:    p.decodeHeader(m.Header.Get("Subject")),
+	}
+
+	if date, err := m.Header.Date(); err == nil {
+		msg.Date = date
+	}
+
+	if from, err := p.parseAddressList(m.Header.Get("From")); err == nil && len(from) > 0 {
+		msg.From = strings.ToLower(from[0].Address)
+		msg.FromName = from[0].Name
+	}
+	for _, header := range []string{"To", "Cc"} {
+		if addresses, err := p.parseAddressList(m.Header.Get(header)); err == nil {
+			for _, address := range addresses {
+				msg.To = append(msg.To, strings.ToLower(address.Address))
+			}
+		}
+	}
+
+	body, err := p.readBody(m.Header.Get("Content-Type"), m.Header.Get("Content-Transfer-Encoding"), m.Body)
+	if err != nil {
+		return nil, err
+	}
+	msg.Body = p.stripQuotedReply(body)
+
+	if msg.MessageID == "" {
+		msg.MessageID = fmt.Sprintf("<%s.%d@local>", msg.From, msg.Date.Unix())
+	}
+
+	return msg, nil
+}
+
+func (p *EmailParser) firstMessageID(value string) string {
+	if id := p.messageIDRegex.FindString(value); id != "" {
+		return id
+	}
+	return strings.TrimSpace(value)
+}
+
+func (p *EmailParser) decodeHeader(value string) string {
+	decoded, err := p.wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return strings.TrimSpace(value)
+	}
+	return strings.TrimSpace(decoded)
+}
+
+func (p *EmailParser) parseAddressList(value string) ([]*mail.Address, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	parser := mail.AddressParser{WordDecoder: p.wordDecoder}
+	return parser.ParseList(value)
+}
+
+// readBody returns the plain text of a MIME entity, preferring text/plain
+// alternatives and skipping attachments
+func (p *EmailParser) readBody(contentType, transferEncoding string, body io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		var plain, html string
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+			if disposition, _, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition")); disposition == "attachment" {
+				continue
+			}
+
+			partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			text, err := p.readBody(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if err != nil || strings.TrimSpace(text) == "" {
+				continue
+			}
+
+			switch {
+			case partType == "text/html" && html == "":
+				html = text
+			case plain == "" && partType != "text/html":
+				plain = text
+			}
+			if mediaType != "multipart/alternative" && plain != "" {
+				break
+			}
+		}
+		if plain != "" {
+			return plain, nil
+		}
+		return html, nil
+	}
+
+	if mediaType != "" && !strings.HasPrefix(mediaType, "text/") {
+		return "", nil
+	}
+
+	decoded, err := io.ReadAll(decodeTransferEncoding(transferEncoding, body))
+	if err != nil {
+		return "", err
+	}
+
+	text, err := decodeCharset(params["charset"], decoded)
+	if err != nil {
+		text = string(decoded)
+	}
+
+	if mediaType == "text/html" {
+		text, _ = extractXHTMLText([]byte(text))
+	}
+	return strings.ReplaceAll(text, "\r\n", "\n"), nil
+}
+
+func decodeTransferEncoding(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	default:
+		return body
+	}
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, err
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+func decodeCharset(charset string, data []byte) (string, error) {
+	charset = strings.ToLower(strings.TrimSpace(charset))
+	if charset == "" || charset == "utf-8" || charset == "us-ascii" {
+		return string(data), nil
+	}
+	reader, err := charsetReader(charset, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	decoded, err := io.ReadAll(reader)
+	return string(decoded), err
+}
+
+// stripQuotedReply removes quoted text from earlier messages in the thread
+// so that each chunk only holds what the sender actually wrote
+func (p *EmailParser) stripQuotedReply(body string) string {
+	lines := strings.Split(body, "\n")
+	var kept []string
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "-----Original Message-----" || strings.HasPrefix(trimmed, "________________") {
+			break
+		}
+		if p.attributionRegex.MatchString(trimmed) {
+			break
+		}
+		// Attribution lines are often wrapped across two lines
+		if i+1 < len(lines) && strings.HasPrefix(strings.ToLower(trimmed), "on ") &&
+			p.attributionRegex.MatchString(trimmed+" "+strings.TrimSpace(lines[i+1])) {
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		kept = append(kept, strings.TrimRight(line, " \t"))
+	}
+
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
+
+// groupThreads links messages through Message-ID, In-Reply-To and
+// References and returns each connected thread sorted by date
+func groupThreads(messages []*emailMessage) [][]*emailMessage {
+	parent := make(map[string]string)
+	var find func(id string) string
+	find = func(id string) string {
+		if parent[id] == "" || parent[id] == id {
+			parent[id] = id
+			return id
+		}
+		root := find(parent[id])
+		parent[id] = root
+		return root
+	}
+	union := func(a, b string) {
+		ra, rb := find(a), find(b)
+		if ra != rb {
+			parent[rb] = ra
+		}
+	}
+
+	for _, msg := range messages {
+		find(msg.MessageID)
+		for _, ref := range append(append([]string{}, msg.References...), msg.InReplyTo...) {
+			union(ref, msg.MessageID)
+		}
+	}
+
+	threads := make(map[string][]*emailMessage)
+	var roots []string
+	for _, msg := range messages {
+		root := find(msg.MessageID)
+		if _, ok := threads[root]; !ok {
+			roots = append(roots, root)
+		}
+		threads[root] = append(threads[root], msg)
+	}
+
+	result := make([][]*emailMessage, 0, len(roots))
+	for _, root := range roots {
+		thread := threads[root]
+		sort.SliceStable(thread, func(i, j int) bool {
+			if thread[i].Date.Equal(thread[j].Date) {
+				return thread[i].order < thread[j].order
+			}
+			return thread[i].Date.Before(thread[j].Date)
+		})
+		result = append(result, thread)
+	}
+	return result
+}
+
+func (p *EmailParser) threadDocument(thread []*emailMessage, filename string) (ParsedDocument, error) {
+	first := thread[0]
+	threadID := first.MessageID
+	if len(first.References) > 0 {
+		threadID = first.References[0]
+	}
+
+	title := p.subjectRegex.ReplaceAllString(first.Subject, "")
+	if title == "" {
+		title = "(no subject)"
+	}
+
+	participantSet := make(map[string]bool)
+	var participants []string
+	addParticipant := func(address string) {
+		if address != "" && !participantSet[address] {
+			participantSet[address] = true
+			participants = append(participants, address)
+		}
+	}
+
+	var chunks []Chunk
+	for _, msg := range thread {
+		addParticipant(msg.From)
+		for _, to := range msg.To {
+			addParticipant(to)
+		}
+
+		messageParticipants := append([]string{}, msg.To...)
+		if msg.From != "" {
+			messageParticipants = append(messageParticipants, msg.From)
+		}
+
+		messageChunks, err := p.chunkStructured(msg.Body)
+		if err != nil {
+			return ParsedDocument{}, err
+		}
+		for _, chunk := range messageChunks {
+			chunkMetadata := map[string]interface{}{
+				"message_id": msg.MessageID,
+				"thread_id":  threadID,
+				"subject":    msg.Subject,
+			}
+			if msg.From != "" {
+				chunkMetadata["from"] = msg.From
+			}
+			if msg.FromName != "" {
+				chunkMetadata["from_name"] = msg.FromName
+			}
+			if len(msg.To) > 0 {
+				chunkMetadata["to"] = msg.To
+			}
+			if len(messageParticipants) > 0 {
+				chunkMetadata["participants"] = messageParticipants
+			}
+			if !msg.Date.IsZero() {
+				chunkMetadata["sent_at"] = msg.Date.Unix()
+			}
+			chunk.Metadata = withChunkMetadata(chunk.Metadata, chunkMetadata)
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	metadata := map[string]interface{}{
+		"title":         title,
+		"original_path": filename,
+		"thread_id":     threadID,
+		"message_count": len(thread),
+		"participants":  participants,
+	}
+	if !first.Date.IsZero() {
+		metadata["started_at"] = first.Date
+		metadata["last_message_at"] = thread[len(thread)-1].Date
+	}
+
+	return ParsedDocument{Chunks: chunks, Metadata: metadata}, nil
+}
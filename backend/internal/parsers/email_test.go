@@ -0,0 +1,117 @@
+package parsers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEmailParserMbox(t *testing.T) {
+	mbox := strings.Join([]string{
+		"From alice@example.com Mon Mar  3 10:00:00 2025",
+		"Message-ID: <1@example.com>",
+		"From: Alice <Alice@example.com>",
+		"To: bob@example.com",
+		"Subject: =?UTF-8?Q?Caf=C3=A9_notes?=",
+		"Date: Mon, 3 Mar 2025 10:00:00 +0000",
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		"Let's meet at the café.",
+		">From the archive, nothing else.",
+		"",
+		"From bob@example.com Mon Mar  3 11:00:00 2025",
+		"Message-ID: <2@example.com>",
+		"In-Reply-To: <1@example.com>",
+		"References: <1@example.com>",
+		"From: bob@example.com",
+		"To: alice@example.com",
+		"Subject: Re: =?UTF-8?Q?Caf=C3=A9_notes?=",
+		"Date: Mon, 3 Mar 2025 11:00:00 +0000",
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=\"b1\"",
+		"",
+		"--b1",
+		"Content-Type: text/plain; charset=iso-8859-1",
+		"Content-Transfer-Encoding: quoted-printable",
+		"",
+		"Sounds good, see you th=E9re.",
+		"",
+		"On Mon, Mar 3, 2025 at 10:00 AM Alice <alice@example.com> wrote:",
+		"> Let's meet at the caf=C3=A9.",
+		"--b1",
+		"Content-Type: text/html; charset=utf-8",
+		"",
+		"<p>Sounds good</p>",
+		"--b1--",
+		"",
+		"From carol@example.com Tue Mar  4 09:00:00 2025",
+		"Message-ID: <3@example.com>",
+		"From: carol@example.com",
+		"To: alice@example.com",
+		"Subject: Unrelated",
+		"Date: Tue, 4 Mar 2025 09:00:00 +0000",
+		"",
+		"A separate thread.",
+		"",
+	}, "\n")
+
+	documents, err := NewEmailParser().ParseDocuments(strings.NewReader(mbox), "inbox.mbox")
+	if err != nil {
+		t.Fatalf("ParseDocuments() error = %v", err)
+	}
+	if len(documents) != 2 {
+		t.Fatalf("ParseDocuments() = %d threads, want 2", len(documents))
+	}
+
+	thread := documents[0]
+	if thread.Metadata["title"] != "Café notes" {
+		t.Errorf("thread title = %v", thread.Metadata["title"])
+	}
+	if thread.Metadata["message_count"] != 2 {
+		t.Errorf("thread message_count = %v, want 2", thread.Metadata["message_count"])
+	}
+	if len(thread.Chunks) != 2 {
+		t.Fatalf("thread has %d chunks, want 2", len(thread.Chunks))
+	}
+
+	first := thread.Chunks[0]
+	if first.Content != "Let's meet at the café.\nFrom the archive, nothing else." {
+		t.Errorf("first message content = %q", first.Content)
+	}
+	if first.Metadata["from"] != "alice@example.com" {
+		t.Errorf("first message from = %v", first.Metadata["from"])
+	}
+
+	reply := thread.Chunks[1]
+	if reply.Content != "Sounds good, see you thére." {
+		t.Errorf("reply content = %q", reply.Content)
+	}
+	if reply.Metadata["thread_id"] != "<1@example.com>" {
+		t.Errorf("reply thread_id = %v", reply.Metadata["thread_id"])
+	}
+	if reply.Metadata["sent_at"] != int64(1740999600) {
+		t.Errorf("reply sent_at = %v", reply.Metadata["sent_at"])
+	}
+}
+
+func TestEmailParserEML(t *testing.T) {
+	eml := "From: dave@example.com\r\n" +
+		"To: erin@example.com\r\n" +
+		"Subject: Reading list\r\n" +
+		"Date: Wed, 5 Mar 2025 08:30:00 +0100\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		"U3RhcnQgd2l0aCBMdWhtYW5uLgoKLS0tLS1PcmlnaW5h\r\n" +
+		"bCBNZXNzYWdlLS0tLS0KT2xkIHN0dWZmLg==\r\n"
+
+	documents, err := NewEmailParser().ParseDocuments(strings.NewReader(eml), "reading.eml")
+	if err != nil {
+		t.Fatalf("ParseDocuments() error = %v", err)
+	}
+	if len(documents) != 1 || len(documents[0].Chunks) != 1 {
+		t.Fatalf("ParseDocuments() = %v", documents)
+	}
+	if got := documents[0].Chunks[0].Content; got != "Start with Luhmann." {
+		t.Errorf("content = %q", got)
+	}
+}
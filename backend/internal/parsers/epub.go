@@ -0,0 +1,500 @@
+package parsers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"zettelkasten/internal/archive"
+)
+
+type EPUBParser struct {
+	chunking
+}
+
+func NewEPUBParser() *EPUBParser {
+	return &EPUBParser{}
+}
+
+// epubContainer is META-INF/container.xml, which points at the OPF package file
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// epubPackage is the OPF package document
+type epubPackage struct {
+	Metadata struct {
+		Titles      []string `xml:"title"`
+		Creators    []string `xml:"creator"`
+		Languages   []string `xml:"language"`
+		Identifiers []struct {
+			Scheme string `xml:"scheme,attr"`
+			Value  string `xml:",chardata"`
+		} `xml:"identifier"`
+	} `xml:"metadata"`
+	Manifest []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine struct {
+		Toc      string `xml:"toc,attr"`
+		ItemRefs []struct {
+			IDRef  string `xml:"idref,attr"`
+			Linear string `xml:"linear,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+// ncxNavPoint is an entry of an EPUB 2 NCX table of contents
+type ncxNavPoint struct {
+	Label   string `xml:"navLabel>text"`
+	Content struct {
+		Src string `xml:"src,attr"`
+	} `xml:"content"`
+	Children []ncxNavPoint `xml:"navPoint"`
+}
+
+type epubChapter struct {
+	title string
+	text  strings.Builder
+}
+
+func (p *EPUBParser) Parse(file io.Reader, filename string) ([]string, map[string]interface{}, error) {
+	chunks, metadata, err := p.ParseChunks(file, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	return chunkContents(chunks), metadata, nil
+}
+
+func (p *EPUBParser) ParseChunks(file io.Reader, filename string) ([]Chunk, map[string]interface{}, error) {
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid epub archive: %w", err)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	// Locate the OPF package through the container file
+	var container epubContainer
+	if err := decodeZipXML(files, "META-INF/container.xml", &container); err != nil {
+		return nil, nil, err
+	}
+	if len(container.Rootfiles) == 0 {
+		return nil, nil, fmt.Errorf("epub container has no rootfile")
+	}
+	opfPath := container.Rootfiles[0].FullPath
+
+	var pkg epubPackage
+	if err := decodeZipXML(files, opfPath, &pkg); err != nil {
+		return nil, nil, err
+	}
+
+	metadata := p.extractMetadata(&pkg, filename)
+
+	// Resolve manifest items relative to the OPF location
+	manifest := make(map[string]string)
+	var ncxPath, navPath string
+	for _, item := range pkg.Manifest {
+		href := resolveEPUBPath(opfPath, item.Href)
+		manifest[item.ID] = href
+		if item.MediaType == "application/x-dtbncx+xml" || item.ID == pkg.Spine.Toc {
+			ncxPath = href
+		}
+		if hasProperty(item.Properties, "nav") {
+			navPath = href
+		}
+	}
+
+	// Build href -> chapter title from the table of contents, preferring
+	// the EPUB 3 nav document and falling back to the NCX
+	tocTitles := make(map[string]string)
+	if navPath != "" {
+		p.readNavTitles(files, navPath, tocTitles)
+	}
+	if len(tocTitles) == 0 && ncxPath != "" {
+		p.readNCXTitles(files, ncxPath, tocTitles)
+	}
+
+	// Walk the spine in reading order. Items listed in the table of contents
+	// start a new chapter; other items continue the current one.
+	var chapters []*epubChapter
+	for _, ref := range pkg.Spine.ItemRefs {
+		if ref.Linear == "no" {
+			continue
+		}
+		href, ok := manifest[ref.IDRef]
+		if !ok || href == navPath {
+			continue
+		}
+
+		data, err := readZipFile(files, href)
+		if errors.Is(err, archive.ErrEntryTooLarge) {
+			return nil, nil, err
+		}
+		if err != nil {
+			continue
+		}
+		text, heading := extractXHTMLText(data)
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		title, inToc := tocTitles[href]
+		if inToc || len(chapters) == 0 {
+			if title == "" {
+				title = heading
+			}
+			chapters = append(chapters, &epubChapter{title: title})
+		}
+
+		current := chapters[len(chapters)-1]
+		if current.text.Len() > 0 {
+			current.text.WriteString("\n\n")
+		}
+		current.text.WriteString(text)
+	}
+
+	var chunks []Chunk
+	chapterTitles := make([]string, 0, len(chapters))
+	for i, chapter := range chapters {
+		chapterTitles = append(chapterTitles, chapter.title)
+		chapterChunks, err := p.chunkStructured(chapter.text.String())
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, chunk := range chapterChunks {
+			chunk.Metadata = withChunkMetadata(chunk.Metadata, map[string]interface{}{
+				"chapter_title": chapter.title,
+				"chapter_index": i,
+			})
+			chunks = append(chunks, chunk)
+		}
+	}
+	metadata["chapters"] = chapterTitles
+
+	return chunks, metadata, nil
+}
+
+// extractMetadata lifts Dublin Core fields from the OPF into document metadata
+func (p *EPUBParser) extractMetadata(pkg *epubPackage, filename string) map[string]interface{} {
+	metadata := make(map[string]interface{})
+	metadata["original_path"] = filename
+
+	title := strings.TrimSuffix(filename, filepath.Ext(filename))
+	if len(pkg.Metadata.Titles) > 0 && strings.TrimSpace(pkg.Metadata.Titles[0]) != "" {
+		title = strings.TrimSpace(pkg.Metadata.Titles[0])
+	}
+	metadata["title"] = title
+
+	var creators []string
+	for _, creator := range pkg.Metadata.Creators {
+		if creator = strings.TrimSpace(creator); creator != "" {
+			creators = append(creators, creator)
+		}
+	}
+	if len(creators) > 0 {
+		metadata["creator"] = strings.Join(creators, ", ")
+	}
+
+	if len(pkg.Metadata.Languages) > 0 {
+		metadata["language"] = strings.TrimSpace(pkg.Metadata.Languages[0])
+	}
+
+	for _, identifier := range pkg.Metadata.Identifiers {
+		if isbn, ok := parseISBN(identifier.Scheme, identifier.Value); ok {
+			metadata["isbn"] = isbn
+			break
+		}
+	}
+
+	return metadata
+}
+
+func (p *EPUBParser) readNCXTitles(files map[string]*zip.File, ncxPath string, titles map[string]string) {
+	var ncx struct {
+		NavPoints []ncxNavPoint `xml:"navMap>navPoint"`
+	}
+	if err := decodeZipXML(files, ncxPath, &ncx); err != nil {
+		return
+	}
+
+	var walk func(points []ncxNavPoint)
+	walk = func(points []ncxNavPoint) {
+		for _, point := range points {
+			href := resolveEPUBPath(ncxPath, point.Content.Src)
+			if _, exists := titles[href]; !exists {
+				titles[href] = strings.TrimSpace(point.Label)
+			}
+			walk(point.Children)
+		}
+	}
+	walk(ncx.NavPoints)
+}
+
+func (p *EPUBParser) readNavTitles(files map[string]*zip.File, navPath string, titles map[string]string) {
+	data, err := readZipFile(files, navPath)
+	if err != nil {
+		return
+	}
+
+	decoder := newXHTMLDecoder(data)
+	navDepth := 0
+	var href string
+	var label strings.Builder
+	inLink := false
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "nav" {
+				if navDepth > 0 || xmlAttr(t, "type") == "toc" {
+					navDepth++
+				}
+			} else if navDepth > 0 && t.Name.Local == "a" {
+				inLink = true
+				href = xmlAttr(t, "href")
+				label.Reset()
+			}
+		case xml.EndElement:
+			if t.Name.Local == "nav" && navDepth > 0 {
+				navDepth--
+			} else if inLink && t.Name.Local == "a" {
+				inLink = false
+				resolved := resolveEPUBPath(navPath, href)
+				if _, exists := titles[resolved]; !exists {
+					titles[resolved] = strings.Join(strings.Fields(label.String()), " ")
+				}
+			}
+		case xml.CharData:
+			if inLink {
+				label.Write(t)
+			}
+		}
+	}
+}
+
+// blockElements end a paragraph when extracting text from XHTML
+var blockElements = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "blockquote": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"li": true, "ul": true, "ol": true, "pre": true, "table": true, "tr": true,
+	"dt": true, "dd": true, "figure": true, "figcaption": true, "hr": true,
+}
+
+// extractXHTMLText returns the paragraph text of an XHTML content document
+// together with its first heading (or <title>) as a fallback chapter title
+func extractXHTMLText(data []byte) (string, string) {
+	decoder := newXHTMLDecoder(data)
+
+	var paragraphs []string
+	var current, heading, pageTitle strings.Builder
+	skipDepth := 0
+	inHead := false
+	headingDepth := 0
+	headingDone := false
+	inTitle := false
+
+	flush := func() {
+		if text := strings.Join(strings.Fields(current.String()), " "); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+		current.Reset()
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			switch {
+			case name == "script" || name == "style":
+				skipDepth++
+			case name == "title":
+				inTitle = true
+			case name == "head":
+				inHead = true
+			case name == "br":
+				current.WriteString(" ")
+			case blockElements[name]:
+				flush()
+			}
+			if !headingDone && len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6' {
+				headingDepth++
+			}
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			switch {
+			case name == "script" || name == "style":
+				if skipDepth > 0 {
+					skipDepth--
+				}
+			case name == "title":
+				inTitle = false
+			case name == "head":
+				inHead = false
+			case blockElements[name]:
+				flush()
+			}
+			if headingDepth > 0 && len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6' {
+				headingDepth--
+				if headingDepth == 0 && strings.TrimSpace(heading.String()) != "" {
+					headingDone = true
+				}
+			}
+		case xml.CharData:
+			if skipDepth > 0 {
+				continue
+			}
+			if inTitle {
+				pageTitle.Write(t)
+			}
+			if inHead {
+				continue
+			}
+			current.Write(t)
+			current.WriteString(" ")
+			if headingDepth > 0 {
+				heading.Write(t)
+				heading.WriteString(" ")
+			}
+		}
+	}
+	flush()
+
+	title := strings.Join(strings.Fields(heading.String()), " ")
+	if title == "" {
+		title = strings.Join(strings.Fields(pageTitle.String()), " ")
+	}
+
+	return strings.Join(paragraphs, "\n\n"), title
+}
+
+func newXHTMLDecoder(data []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+	return decoder
+}
+
+func decodeZipXML(files map[string]*zip.File, name string, v interface{}) error {
+	data, err := readZipFile(files, name)
+	if err != nil {
+		return err
+	}
+	decoder := newXHTMLDecoder(data)
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+func readZipFile(files map[string]*zip.File, name string) ([]byte, error) {
+	f, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("epub is missing %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	// Entries come from untrusted uploads, so cap them like archive entries
+	maxSize := archive.DefaultLimits.MaxEntrySize
+	data, err := io.ReadAll(io.LimitReader(rc, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("%s: %w", name, archive.ErrEntryTooLarge)
+	}
+	return data, nil
+}
+
+// resolveEPUBPath resolves href relative to the file at base, dropping any
+// fragment so that TOC entries match spine items
+func resolveEPUBPath(base, href string) string {
+	if i := strings.Index(href, "#"); i >= 0 {
+		href = href[:i]
+	}
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	return path.Clean(path.Join(path.Dir(base), href))
+}
+
+func xmlAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func hasProperty(properties, property string) bool {
+	for _, p := range strings.Fields(properties) {
+		if p == property {
+			return true
+		}
+	}
+	return false
+}
+
+// parseISBN recognises identifiers marked with an ISBN scheme, urn:isbn:
+// values, or bare 10/13 digit ISBNs
+func parseISBN(scheme, value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	lower := strings.ToLower(value)
+	if strings.HasPrefix(lower, "urn:isbn:") {
+		return value[len("urn:isbn:"):], true
+	}
+	if strings.EqualFold(scheme, "isbn") {
+		return value, value != ""
+	}
+
+	digits := strings.NewReplacer("-", "", " ", "").Replace(value)
+	if len(digits) != 10 && len(digits) != 13 {
+		return "", false
+	}
+	for i, r := range digits {
+		if r >= '0' && r <= '9' {
+			continue
+		}
+		if i == len(digits)-1 && (r == 'X' || r == 'x') && len(digits) == 10 {
+			continue
+		}
+		return "", false
+	}
+	return value, true
+}
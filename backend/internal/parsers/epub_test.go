@@ -0,0 +1,150 @@
+package parsers
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"zettelkasten/internal/archive"
+)
+
+func buildEPUB(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close epub: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestEPUBParser(t *testing.T) {
+	data := buildEPUB(t, map[string]string{
+		"mimetype": "application/epub+zip",
+		"META-INF/container.xml": `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`,
+		"OEBPS/content.opf": `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf" version="2.0">
+  <metadata>
+    <dc:title>How to Take Smart Notes</dc:title>
+    <dc:creator>Sönke Ahrens</dc:creator>
+    <dc:language>en</dc:language>
+    <dc:identifier opf:scheme="ISBN">978-1542866507</dc:identifier>
+  </metadata>
+  <manifest>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+    <item id="ch1" href="text/ch1.xhtml" media-type="application/xhtml+xml"/>
+    <item id="ch1b" href="text/ch1b.xhtml" media-type="application/xhtml+xml"/>
+    <item id="ch2" href="text/ch2.xhtml" media-type="application/xhtml+xml"/>
+  </manifest>
+  <spine toc="ncx">
+    <itemref idref="ch1"/>
+    <itemref idref="ch1b"/>
+    <itemref idref="ch2"/>
+  </spine>
+</package>`,
+		"OEBPS/toc.ncx": `<?xml version="1.0"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/">
+  <navMap>
+    <navPoint id="n1"><navLabel><text>Introduction</text></navLabel><content src="text/ch1.xhtml"/></navPoint>
+    <navPoint id="n2"><navLabel><text>Writing Is All That Matters</text></navLabel><content src="text/ch2.xhtml#start"/></navPoint>
+  </navMap>
+</ncx>`,
+		"OEBPS/text/ch1.xhtml":  `<html xmlns="http://www.w3.org/1999/xhtml"><head><title>Ch 1</title></head><body><h1>Introduction</h1><p>Everyone writes.</p></body></html>`,
+		"OEBPS/text/ch1b.xhtml": `<html xmlns="http://www.w3.org/1999/xhtml"><body><p>Continued &amp; extended.</p></body></html>`,
+		"OEBPS/text/ch2.xhtml":  `<html xmlns="http://www.w3.org/1999/xhtml"><body><h1>Chapter 2</h1><p>The slip-box is a tool.</p><script>ignored()</script></body></html>`,
+	})
+
+	chunks, metadata, err := NewEPUBParser().ParseChunks(bytes.NewReader(data), "smart-notes.epub")
+	if err != nil {
+		t.Fatalf("ParseChunks() error = %v", err)
+	}
+
+	expectedMetadata := map[string]string{
+		"title":    "How to Take Smart Notes",
+		"creator":  "Sönke Ahrens",
+		"language": "en",
+		"isbn":     "978-1542866507",
+	}
+	for key, want := range expectedMetadata {
+		if got := metadata[key]; got != want {
+			t.Errorf("metadata[%q] = %v, want %q", key, got, want)
+		}
+	}
+
+	if len(chunks) != 2 {
+		t.Fatalf("ParseChunks() = %d chunks, want 2", len(chunks))
+	}
+
+	if got := chunks[0].Metadata["chapter_title"]; got != "Introduction" {
+		t.Errorf("chunk 0 chapter_title = %v, want Introduction", got)
+	}
+	if got := chunks[0].Content; got != "Introduction\n\nEveryone writes.\n\nContinued & extended." {
+		t.Errorf("chunk 0 content = %q", got)
+	}
+	if got := chunks[1].Metadata["chapter_title"]; got != "Writing Is All That Matters" {
+		t.Errorf("chunk 1 chapter_title = %v, want Writing Is All That Matters", got)
+	}
+	if got := chunks[1].Content; got != "Chapter 2\n\nThe slip-box is a tool." {
+		t.Errorf("chunk 1 content = %q", got)
+	}
+}
+
+func TestReadZipFileRejectsOversizedEntries(t *testing.T) {
+	maxSize := archive.DefaultLimits.MaxEntrySize
+	data := buildEPUB(t, map[string]string{
+		"small.xhtml": "<p>fits</p>",
+		"large.xhtml": strings.Repeat("a", int(maxSize)+1),
+	})
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("failed to open epub: %v", err)
+	}
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	if _, err := readZipFile(files, "small.xhtml"); err != nil {
+		t.Errorf("readZipFile(small) error = %v", err)
+	}
+	if _, err := readZipFile(files, "large.xhtml"); !errors.Is(err, archive.ErrEntryTooLarge) {
+		t.Errorf("readZipFile(large) error = %v, want %v", err, archive.ErrEntryTooLarge)
+	}
+}
+
+func TestParseISBN(t *testing.T) {
+	tests := []struct {
+		scheme string
+		value  string
+		want   string
+		ok     bool
+	}{
+		{"ISBN", "978-1542866507", "978-1542866507", true},
+		{"", "urn:isbn:9781542866507", "9781542866507", true},
+		{"", "0-306-40615-2", "0-306-40615-2", true},
+		{"", "urn:uuid:1234-5678", "", false},
+		{"", "12345", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseISBN(tt.scheme, tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseISBN(%q, %q) = %q, %v, want %q, %v", tt.scheme, tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package parsers
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// highlight is a single passage highlighted in a book, with optional note
+type highlight struct {
+	Text          string
+	Note          string
+	Location      string
+	Page          string
+	HighlightedAt time.Time
+	Tags          []string
+}
+
+// highlightBook groups highlights belonging to the same book
+type highlightBook struct {
+	Title      string
+	Author     string
+	Highlights []highlight
+}
+
+// highlightLibrary collects books in order of first appearance
+type highlightLibrary struct {
+	books []*highlightBook
+	index map[string]*highlightBook
+}
+
+func newHighlightLibrary() *highlightLibrary {
+	return &highlightLibrary{index: make(map[string]*highlightBook)}
+}
+
+func (l *highlightLibrary) book(title, author string) *highlightBook {
+	key := strings.ToLower(title) + "\x00" + strings.ToLower(author)
+	if b, ok := l.index[key]; ok {
+		return b
+	}
+	b := &highlightBook{Title: title, Author: author}
+	l.index[key] = b
+	l.books = append(l.books, b)
+	return b
+}
+
+// documents converts every book with at least one highlight into a
+// document whose chunks are the individual highlights
+func (l *highlightLibrary) documents(filename string) []ParsedDocument {
+	var documents []ParsedDocument
+	for _, b := range l.books {
+		if len(b.Highlights) == 0 {
+			continue
+		}
+		documents = append(documents, b.document(filename))
+	}
+	return documents
+}
+
+func (b *highlightBook) document(filename string) ParsedDocument {
+	metadata := map[string]interface{}{
+		"title":           b.Title,
+		"original_path":   filename,
+		"highlight_count": len(b.Highlights),
+	}
+	if b.Author != "" {
+		metadata["author"] = b.Author
+	}
+
+	tagSet := make(map[string]bool)
+	var tags []string
+	chunks := make([]Chunk, 0, len(b.Highlights))
+	for _, h := range b.Highlights {
+		content := h.Text
+		chunkMetadata := map[string]interface{}{
+			"book_title": b.Title,
+		}
+		if b.Author != "" {
+			chunkMetadata["author"] = b.Author
+		}
+		if h.Location != "" {
+			chunkMetadata["location"] = h.Location
+		}
+		if h.Page != "" {
+			chunkMetadata["page"] = h.Page
+		}
+		if h.Note != "" {
+			chunkMetadata["note"] = h.Note
+			content += "\n\nNote: " + h.Note
+		}
+		if !h.HighlightedAt.IsZero() {
+			chunkMetadata["highlighted_at"] = h.HighlightedAt.Unix()
+		}
+		if len(h.Tags) > 0 {
+			chunkMetadata["tags"] = h.Tags
+			for _, tag := range h.Tags {
+				if !tagSet[tag] {
+					tagSet[tag] = true
+					tags = append(tags, tag)
+				}
+			}
+		}
+
+		chunks = append(chunks, Chunk{Content: content, Metadata: chunkMetadata})
+	}
+	if len(tags) > 0 {
+		metadata["tags"] = tags
+	}
+
+	return ParsedDocument{Chunks: chunks, Metadata: metadata}
+}
+
+// flattenDocuments merges parsed documents for callers of the plain Parser
+// interface, which only supports a single document per file
+func flattenDocuments(documents []ParsedDocument, filename string) ([]string, map[string]interface{}) {
+	var chunks []string
+	var titles []string
+	for _, doc := range documents {
+		chunks = append(chunks, chunkContents(doc.Chunks)...)
+		if title, ok := doc.Metadata["title"].(string); ok {
+			titles = append(titles, title)
+		}
+	}
+
+	metadata := map[string]interface{}{
+		"title":         strings.TrimSuffix(filename, filepath.Ext(filename)),
+		"original_path": filename,
+		"books":         titles,
+	}
+	return chunks, metadata
+}
+
+// parseHighlightTime tries the timestamp layouts used by Kindle and Readwise
+func parseHighlightTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	layouts := []string{
+		time.RFC3339,
+		"2006-01-02 15:04:05-07:00",
+		"2006-01-02 15:04:05Z07:00",
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+		"Monday, January 2, 2006 3:04:05 PM",
+		"Monday, January 2, 2006 15:04:05",
+		"Monday, 2 January 2006 15:04:05",
+		"Monday, 2 January 2006 3:04:05 PM",
+		"January 2, 2006 3:04 PM",
+		"January 2, 2006",
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,132 @@
+package parsers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKindleParser(t *testing.T) {
+	clippings := "\ufeffHow to Take Smart Notes (Sönke Ahrens)\r\n" +
+		"- Your Highlight on page 12 | Location 180-182 | Added on Monday, March 3, 2025 10:15:32 AM\r\n" +
+		"\r\n" +
+		"The slip-box is the shared work of you and your notes.\r\n" +
+		"==========\r\n" +
+		"How to Take Smart Notes (Sönke Ahrens)\r\n" +
+		"- Your Note on page 12 | Location 181 | Added on Monday, March 3, 2025 10:16:00 AM\r\n" +
+		"\r\n" +
+		"Compare with Luhmann's interview.\r\n" +
+		"==========\r\n" +
+		"How to Take Smart Notes (Sönke Ahrens)\r\n" +
+		"- Your Bookmark on page 40 | Location 600 | Added on Monday, March 3, 2025 11:00:00 AM\r\n" +
+		"\r\n" +
+		"\r\n" +
+		"==========\r\n" +
+		"Thinking, Fast and Slow (Kahneman, Daniel)\r\n" +
+		"- Your Highlight on Location 1402-1403 | Added on Tuesday, March 4, 2025 9:00:00 PM\r\n" +
+		"\r\n" +
+		"Nothing in life is as important as you think it is.\r\n" +
+		"==========\r\n"
+
+	documents, err := NewKindleParser().ParseDocuments(strings.NewReader(clippings), "My Clippings.txt")
+	if err != nil {
+		t.Fatalf("ParseDocuments() error = %v", err)
+	}
+	if len(documents) != 2 {
+		t.Fatalf("ParseDocuments() = %d documents, want 2", len(documents))
+	}
+
+	first := documents[0]
+	if first.Metadata["title"] != "How to Take Smart Notes" || first.Metadata["author"] != "Sönke Ahrens" {
+		t.Errorf("first document = %v by %v", first.Metadata["title"], first.Metadata["author"])
+	}
+	if len(first.Chunks) != 1 {
+		t.Fatalf("first document has %d chunks, want 1", len(first.Chunks))
+	}
+
+	chunk := first.Chunks[0]
+	if chunk.Metadata["page"] != "12" || chunk.Metadata["location"] != "180-182" {
+		t.Errorf("chunk location = page %v, location %v", chunk.Metadata["page"], chunk.Metadata["location"])
+	}
+	if chunk.Metadata["note"] != "Compare with Luhmann's interview." {
+		t.Errorf("chunk note = %v", chunk.Metadata["note"])
+	}
+	if _, ok := chunk.Metadata["highlighted_at"].(int64); !ok {
+		t.Errorf("chunk highlighted_at = %v, want unix timestamp", chunk.Metadata["highlighted_at"])
+	}
+
+	if documents[1].Metadata["author"] != "Kahneman, Daniel" {
+		t.Errorf("second document author = %v", documents[1].Metadata["author"])
+	}
+}
+
+func TestReadwiseParserCSV(t *testing.T) {
+	export := `Highlight,Book Title,Book Author,Amazon Book ID,Note,Color,Tags,Location Type,Location,Highlighted at,Document tags
+"Write every note as if for someone else.",Smart Notes,Sönke Ahrens,B06WVYW33Y,,yellow,"writing, zettelkasten",location,180,2025-03-03 10:15:32+00:00,
+"Elaborate on ideas, don't collect them.",Smart Notes,Sönke Ahrens,B06WVYW33Y,Key point,yellow,,page,42,2025-03-03 10:20:00+00:00,
+"Deliberate practice needs feedback.",Peak,Anders Ericsson,B01A4B0N4A,,blue,,location,900,,
+`
+
+	documents, err := NewReadwiseParser().ParseDocuments(strings.NewReader(export), "readwise-data.csv")
+	if err != nil {
+		t.Fatalf("ParseDocuments() error = %v", err)
+	}
+	if len(documents) != 2 {
+		t.Fatalf("ParseDocuments() = %d documents, want 2", len(documents))
+	}
+
+	chunks := documents[0].Chunks
+	if len(chunks) != 2 {
+		t.Fatalf("first document has %d chunks, want 2", len(chunks))
+	}
+	if tags, _ := chunks[0].Metadata["tags"].([]string); len(tags) != 2 || tags[1] != "zettelkasten" {
+		t.Errorf("chunk 0 tags = %v", chunks[0].Metadata["tags"])
+	}
+	if chunks[1].Metadata["page"] != "42" || chunks[1].Metadata["note"] != "Key point" {
+		t.Errorf("chunk 1 metadata = %v", chunks[1].Metadata)
+	}
+}
+
+func TestReadwiseParserMarkdown(t *testing.T) {
+	export := `# Smart Notes
+
+![](https://images.example.com/cover.jpg)
+
+### Metadata
+- Author: [[Sönke Ahrens]]
+- Full Title: How to Take Smart Notes
+- Category: #books
+
+### Highlights
+- Write every note as if for someone else. ([Location 180](https://readwise.io/to_kindle?action=open&asin=B06WVYW33Y&location=180))
+    - Tags: [[writing]]
+    - Note: Audience matters.
+- Elaborate on ideas. (Page 42)
+`
+
+	documents, err := NewReadwiseParser().ParseDocuments(strings.NewReader(export), "Smart Notes.md")
+	if err != nil {
+		t.Fatalf("ParseDocuments() error = %v", err)
+	}
+	if len(documents) != 1 {
+		t.Fatalf("ParseDocuments() = %d documents, want 1", len(documents))
+	}
+
+	doc := documents[0]
+	if doc.Metadata["title"] != "How to Take Smart Notes" || doc.Metadata["author"] != "Sönke Ahrens" {
+		t.Errorf("document = %v by %v", doc.Metadata["title"], doc.Metadata["author"])
+	}
+	if len(doc.Chunks) != 2 {
+		t.Fatalf("document has %d chunks, want 2", len(doc.Chunks))
+	}
+
+	first := doc.Chunks[0]
+	if first.Content != "Write every note as if for someone else.\n\nNote: Audience matters." {
+		t.Errorf("chunk 0 content = %q", first.Content)
+	}
+	if first.Metadata["location"] != "180" {
+		t.Errorf("chunk 0 location = %v", first.Metadata["location"])
+	}
+	if doc.Chunks[1].Metadata["page"] != "42" {
+		t.Errorf("chunk 1 page = %v", doc.Chunks[1].Metadata["page"])
+	}
+}
@@ -5,3 +5,94 @@ import "io"
 type Parser interface {
 	Parse(file io.Reader, filename string) ([]string, map[string]interface{}, error)
 }
+
+// Chunk is a piece of parsed content together with metadata that applies
+// only to that piece, such as the chapter it was taken from.
+type Chunk struct {
+	Content  string
+	Metadata map[string]interface{}
+}
+
+// StructuredParser is implemented by parsers that can attach per-chunk
+// metadata. Callers should prefer ParseChunks when it is available.
+type StructuredParser interface {
+	ParseChunks(file io.Reader, filename string) ([]Chunk, map[string]interface{}, error)
+}
+
+// chunkContents returns only the text of each chunk.
+func chunkContents(chunks []Chunk) []string {
+	contents := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		contents[i] = chunk.Content
+	}
+	return contents
+}
+
+// ParseChunks parses file with parser, using per-chunk metadata when the
+// parser supports it and plain chunks otherwise.
+func ParseChunks(parser Parser, file io.Reader, filename string) ([]Chunk, map[string]interface{}, error) {
+	if structured, ok := parser.(StructuredParser); ok {
+		return structured.ParseChunks(file, filename)
+	}
+
+	contents, metadata, err := parser.Parse(file, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chunks := make([]Chunk, len(contents))
+	for i, content := range contents {
+		chunks[i] = Chunk{Content: content}
+	}
+	return chunks, metadata, nil
+}
+
+// ParsedDocument is one document produced from an uploaded file.
+type ParsedDocument struct {
+	Chunks   []Chunk
+	Metadata map[string]interface{}
+}
+
+// MultiDocumentParser is implemented by parsers whose input files bundle
+// several documents, such as highlight exports covering many books.
+type MultiDocumentParser interface {
+	ParseDocuments(file io.Reader, filename string) ([]ParsedDocument, error)
+}
+
+// ParseDocuments parses file with parser into one or more documents.
+func ParseDocuments(parser Parser, file io.Reader, filename string) ([]ParsedDocument, error) {
+	if multi, ok := parser.(MultiDocumentParser); ok {
+		return multi.ParseDocuments(file, filename)
+	}
+
+	chunks, metadata, err := ParseChunks(parser, file, filename)
+	if err != nil {
+		return nil, err
+	}
+	return []ParsedDocument{{Chunks: chunks, Metadata: metadata}}, nil
+}
+
+// NewParser returns the parser for a source type, falling back to the
+// standard text parser
+func NewParser(sourceType string) Parser {
+	switch sourceType {
+	case "notion":
+		return NewNotionParser()
+	case "obsidian":
+		return NewObsidianParser()
+	case "roam":
+		return NewRoamParser()
+	case "logseq":
+		return NewLogseqParser()
+	case "epub":
+		return NewEPUBParser()
+	case "kindle":
+		return NewKindleParser()
+	case "readwise":
+		return NewReadwiseParser()
+	case "email":
+		return NewEmailParser()
+	default:
+		return NewStandardParser()
+	}
+}
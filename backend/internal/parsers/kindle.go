@@ -0,0 +1,164 @@
+package parsers
+
+import (
+	"io"
+	"regexp"
+	"strings"
+)
+
+// KindleParser reads the "My Clippings.txt" file written by Kindle devices
+type KindleParser struct {
+	authorRegex   *regexp.Regexp
+	pageRegex     *regexp.Regexp
+	locationRegex *regexp.Regexp
+	addedRegex    *regexp.Regexp
+}
+
+func NewKindleParser() *KindleParser {
+	return &KindleParser{
+		authorRegex:   regexp.MustCompile(`^(.*?)\s*\(([^()]*)\)\s*$`),
+		pageRegex:     regexp.MustCompile(`(?i)\bpage\s+([\w-]+)`),
+		locationRegex: regexp.MustCompile(`(?i)\b(?:location|loc\.)\s+([\d-]+)`),
+		addedRegex:    regexp.MustCompile(`(?i)added on\s+(.+)$`),
+	}
+}
+
+const kindleSeparator = "=========="
+
+func (p *KindleParser) Parse(file io.Reader, filename string) ([]string, map[string]interface{}, error) {
+	documents, err := p.ParseDocuments(file, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	chunks, metadata := flattenDocuments(documents, filename)
+	return chunks, metadata, nil
+}
+
+func (p *KindleParser) ParseDocuments(file io.Reader, filename string) ([]ParsedDocument, error) {
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\ufeff", "")
+
+	library := newHighlightLibrary()
+	for _, entry := range strings.Split(text, kindleSeparator) {
+		lines := strings.Split(strings.TrimSpace(entry), "\n")
+		if len(lines) < 2 {
+			continue
+		}
+
+		title, author := p.parseTitleLine(strings.TrimSpace(lines[0]))
+		meta := strings.TrimSpace(lines[1])
+		body := strings.TrimSpace(strings.Join(lines[2:], "\n"))
+		kind := p.clippingKind(meta)
+
+		b := library.book(title, author)
+		switch kind {
+		case "highlight":
+			if body == "" {
+				continue
+			}
+			h := highlight{Text: body}
+			if m := p.pageRegex.FindStringSubmatch(meta); m != nil {
+				h.Page = m[1]
+			}
+			if m := p.locationRegex.FindStringSubmatch(meta); m != nil {
+				h.Location = m[1]
+			}
+			if m := p.addedRegex.FindStringSubmatch(meta); m != nil {
+				h.HighlightedAt = parseHighlightTime(m[1])
+			}
+			b.Highlights = append(b.Highlights, h)
+		case "note":
+			if body == "" {
+				continue
+			}
+			var location string
+			if m := p.locationRegex.FindStringSubmatch(meta); m != nil {
+				location = m[1]
+			}
+			p.attachNote(b, location, body)
+		}
+	}
+
+	return library.documents(filename), nil
+}
+
+// parseTitleLine splits "Book Title (Author Name)" into title and author
+func (p *KindleParser) parseTitleLine(line string) (string, string) {
+	if m := p.authorRegex.FindStringSubmatch(line); m != nil && strings.TrimSpace(m[1]) != "" {
+		return strings.TrimSpace(m[1]), strings.TrimSpace(m[2])
+	}
+	return line, ""
+}
+
+// clippingKind classifies the metadata line as highlight, note or bookmark
+func (p *KindleParser) clippingKind(meta string) string {
+	lower := strings.ToLower(meta)
+	switch {
+	case strings.Contains(lower, "note"):
+		return "note"
+	case strings.Contains(lower, "bookmark"):
+		return "bookmark"
+	default:
+		return "highlight"
+	}
+}
+
+// attachNote adds a note to the highlight whose location range contains
+// the note's location, falling back to the most recent highlight. Notes
+// without any highlight become highlights of their own.
+func (p *KindleParser) attachNote(b *highlightBook, location, note string) {
+	for i := len(b.Highlights) - 1; i >= 0; i-- {
+		if location != "" && locationContains(b.Highlights[i].Location, location) {
+			b.Highlights[i].Note = joinNotes(b.Highlights[i].Note, note)
+			return
+		}
+	}
+	if len(b.Highlights) > 0 && location == "" {
+		last := &b.Highlights[len(b.Highlights)-1]
+		last.Note = joinNotes(last.Note, note)
+		return
+	}
+	b.Highlights = append(b.Highlights, highlight{Text: note, Location: location})
+}
+
+// locationContains reports whether location falls within a Kindle location
+// range such as "1234-1240" (or the shorthand "1234-40")
+func locationContains(rangeValue, location string) bool {
+	start, end := rangeValue, rangeValue
+	if i := strings.Index(rangeValue, "-"); i >= 0 {
+		start, end = rangeValue[:i], rangeValue[i+1:]
+		if len(end) < len(start) {
+			end = start[:len(start)-len(end)] + end
+		}
+	}
+	loc := location
+	if i := strings.Index(location, "-"); i >= 0 {
+		loc = location[:i]
+	}
+	return compareNumeric(start, loc) <= 0 && compareNumeric(loc, end) <= 0
+}
+
+// compareNumeric compares two non-negative decimal strings
+func compareNumeric(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func joinNotes(existing, note string) string {
+	if existing == "" {
+		return note
+	}
+	return existing + "\n" + note
+}
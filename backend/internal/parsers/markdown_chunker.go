@@ -0,0 +1,489 @@
+package parsers
+
+import (
+	"regexp"
+	"strings"
+)
+
+// HeadingPathKey is the chunk metadata key holding the headings a chunk is
+// nested under, outermost first
+const HeadingPathKey = "heading_path"
+
+// MarkdownChunker splits Markdown along its block structure. Chunks never
+// end inside a fenced code block, table or list item, a new chunk starts at
+// every heading and thematic break, and blocks are grouped until a chunk
+// reaches MinTokens. Front matter is left out of the chunks.
+// Blocks longer than MaxTokens on their own are split: paragraphs on
+// sentence boundaries, other blocks between lines. Code fences and tables
+// are kept whole up to the embedding input limit and only split beyond it,
+// with fences closed and reopened and table headers repeated. Overlap is
+// not used since chunks follow the document structure.
+type MarkdownChunker struct {
+	tokenSizer
+}
+
+type markdownBlockKind int
+
+const (
+	markdownParagraph markdownBlockKind = iota
+	markdownHeading
+	markdownFence
+	markdownTable
+	markdownListItem
+	markdownQuote
+	markdownBreak
+)
+
+// markdownBlock is a top-level block of a Markdown document
+type markdownBlock struct {
+	kind  markdownBlockKind
+	lines []string
+	// level and title are the level and text of a heading
+	level int
+	title string
+	// list numbers the list a list item belongs to, so that items of the
+	// same list are kept on consecutive lines
+	list int
+}
+
+func (b markdownBlock) text() string {
+	return strings.Join(b.lines, "\n")
+}
+
+func (c *MarkdownChunker) Chunk(text string) ([]string, error) {
+	chunks, err := c.ChunkStructured(text)
+	if err != nil {
+		return nil, err
+	}
+	return chunkContents(chunks), nil
+}
+
+func (c *MarkdownChunker) ChunkStructured(text string) ([]Chunk, error) {
+	blocks := parseMarkdownBlocks(text)
+
+	var chunks []Chunk
+	var current []markdownBlock
+	var currentPath []string
+	tokens := 0
+	headingsOnly := true
+
+	var headings []markdownBlock
+	path := func() []string {
+		titles := make([]string, len(headings))
+		for i, h := range headings {
+			titles[i] = h.title
+		}
+		return titles
+	}
+
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		chunk := Chunk{Content: joinMarkdownBlocks(current)}
+		if len(currentPath) > 0 {
+			chunk.Metadata = map[string]interface{}{HeadingPathKey: currentPath}
+		}
+		chunks = append(chunks, chunk)
+		current, currentPath, tokens, headingsOnly = nil, nil, 0, true
+	}
+
+	add := func(b markdownBlock) {
+		if len(current) == 0 || headingsOnly {
+			currentPath = path()
+		}
+		current = append(current, b)
+		tokens += c.tokenizer.Count(b.text()) + 1
+	}
+
+	for i, block := range blocks {
+		if block.kind == markdownBreak {
+			if !headingsOnly {
+				flush()
+			}
+			continue
+		}
+		if block.kind == markdownHeading {
+			// Consecutive headings stay together with the first content
+			// below them
+			if !headingsOnly {
+				flush()
+			}
+			for len(headings) > 0 && headings[len(headings)-1].level >= block.level {
+				headings = headings[:len(headings)-1]
+			}
+			headings = append(headings, block)
+			add(block)
+			continue
+		}
+
+		for _, piece := range c.splitBlock(block) {
+			if !headingsOnly && tokens+c.tokenizer.Count(piece.text())+1 > c.config.MaxTokens {
+				flush()
+			}
+			add(piece)
+			headingsOnly = false
+		}
+
+		// Keep filling the chunk while the next block continues the same list
+		nextContinuesList := i+1 < len(blocks) && block.kind == markdownListItem &&
+			blocks[i+1].kind == markdownListItem && blocks[i+1].list == block.list
+		if tokens >= c.config.MinTokens && !nextContinuesList {
+			flush()
+		}
+	}
+	flush()
+
+	return chunks, nil
+}
+
+// splitBlock returns block unchanged if it fits into MaxTokens, and
+// otherwise pieces of it that do. Code fences and tables may grow up to the
+// embedding input limit before they are split, since a cut through them
+// loses more context than an oversized chunk.
+func (c *MarkdownChunker) splitBlock(block markdownBlock) []markdownBlock {
+	limit := c.config.MaxTokens
+	if block.kind == markdownFence || block.kind == markdownTable {
+		limit = max(limit, maxEmbeddingTokens)
+	}
+	if c.tokenizer.Count(block.text()) <= limit {
+		return []markdownBlock{block}
+	}
+
+	var prefix, suffix []string
+	body := block.lines
+	switch block.kind {
+	case markdownParagraph:
+		// Group sentences into pieces of up to MaxTokens
+		sizer := tokenSizer{tokenizer: c.tokenizer, config: c.config}
+		sizer.config.MinTokens = c.config.MaxTokens
+		sizer.config.Overlap = 0
+		var pieces []markdownBlock
+		for _, text := range sizer.pack(sizer.sentenceUnits(block.text())) {
+			pieces = append(pieces, markdownBlock{kind: block.kind, lines: []string{text}})
+		}
+		return pieces
+	case markdownFence:
+		prefix = body[:1]
+		body = body[1:]
+		if len(body) > 0 && isFenceClose(body[len(body)-1], prefix[0]) {
+			suffix = body[len(body)-1:]
+			body = body[:len(body)-1]
+		} else {
+			suffix = []string{fenceMarker(prefix[0])}
+		}
+	case markdownTable:
+		prefix = body[:2]
+		body = body[2:]
+	}
+
+	budget := limit - c.tokenizer.Count(strings.Join(append(append([]string{}, prefix...), suffix...), "\n")) - 1
+	if budget < 1 {
+		budget = 1
+	}
+
+	var pieces []markdownBlock
+	for _, lines := range c.groupLines(body, budget) {
+		piece := block
+		piece.lines = append(append(append([]string{}, prefix...), lines...), suffix...)
+		pieces = append(pieces, piece)
+	}
+	return pieces
+}
+
+// groupLines groups consecutive lines into runs of at most budget tokens,
+// cutting single lines that exceed the budget
+func (c *MarkdownChunker) groupLines(lines []string, budget int) [][]string {
+	var groups [][]string
+	var current []string
+	tokens := 0
+
+	for _, line := range lines {
+		lineTokens := c.tokenizer.Count(line) + 1
+		if lineTokens > budget {
+			if len(current) > 0 {
+				groups = append(groups, current)
+				current, tokens = nil, 0
+			}
+			for _, piece := range c.splitTokens(line, budget, 0) {
+				groups = append(groups, []string{piece})
+			}
+			continue
+		}
+
+		if len(current) > 0 && tokens+lineTokens > budget {
+			groups = append(groups, current)
+			current, tokens = nil, 0
+		}
+		current = append(current, line)
+		tokens += lineTokens
+	}
+	if len(current) > 0 {
+		groups = append(groups, current)
+	}
+
+	return groups
+}
+
+// joinMarkdownBlocks separates blocks with blank lines, keeping items of the
+// same list on consecutive lines
+func joinMarkdownBlocks(blocks []markdownBlock) string {
+	var b strings.Builder
+	for i, block := range blocks {
+		if i > 0 {
+			prev := blocks[i-1]
+			if prev.kind == markdownListItem && block.kind == markdownListItem && prev.list == block.list {
+				b.WriteString("\n")
+			} else {
+				b.WriteString("\n\n")
+			}
+		}
+		b.WriteString(block.text())
+	}
+	return strings.TrimSpace(b.String())
+}
+
+var (
+	listItemRegex       = regexp.MustCompile(`^(\s*)([-*+]|\d{1,9}[.)])(\s+|$)`)
+	thematicBreakRegex  = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	tableDelimiterRegex = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+)
+
+// parseMarkdownBlocks splits a Markdown document into its top-level blocks:
+// ATX and setext headings, fenced code blocks, tables, list items, block
+// quotes, thematic breaks and paragraphs. Blank lines between blocks and
+// YAML front matter are dropped.
+func parseMarkdownBlocks(text string) []markdownBlock {
+	lines := strings.Split(normalizeNewlines(text), "\n")
+
+	var blocks []markdownBlock
+	list := 0
+	inList := false
+
+	for i := frontMatterEnd(lines); i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		indent := leadingSpaces(line)
+
+		switch {
+		case trimmed == "":
+			i++
+			continue
+
+		case indent < 4 && fenceMarker(line) != "":
+			end := i + 1
+			for end < len(lines) && !isFenceClose(lines[end], line) {
+				end++
+			}
+			if end < len(lines) {
+				end++
+			}
+			blocks = append(blocks, markdownBlock{kind: markdownFence, lines: lines[i:end]})
+			i = end
+
+		case indent < 4 && isATXHeading(trimmed):
+			level := strings.IndexFunc(trimmed, func(r rune) bool { return r != '#' })
+			if level < 0 {
+				level = len(trimmed)
+			}
+			blocks = append(blocks, markdownBlock{kind: markdownHeading, lines: []string{trimmed}, level: level, title: headingTitle(trimmed)})
+			i++
+
+		case thematicBreakRegex.MatchString(line):
+			blocks = append(blocks, markdownBlock{kind: markdownBreak, lines: []string{trimmed}})
+			i++
+
+		case strings.Contains(line, "|") && i+1 < len(lines) && strings.Contains(lines[i+1], "-") && tableDelimiterRegex.MatchString(lines[i+1]):
+			end := i + 2
+			for end < len(lines) && strings.TrimSpace(lines[end]) != "" && strings.Contains(lines[end], "|") {
+				end++
+			}
+			blocks = append(blocks, markdownBlock{kind: markdownTable, lines: lines[i:end]})
+			i = end
+
+		case listItemRegex.MatchString(line):
+			if !inList {
+				list++
+			}
+			end := listItemEnd(lines, i)
+			blocks = append(blocks, markdownBlock{kind: markdownListItem, lines: trimTrailingBlank(lines[i:end]), list: list})
+			i = end
+			inList = true
+			continue
+
+		case strings.HasPrefix(trimmed, ">"):
+			end := i + 1
+			for end < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[end]), ">") {
+				end++
+			}
+			blocks = append(blocks, markdownBlock{kind: markdownQuote, lines: lines[i:end]})
+			i = end
+
+		default:
+			end := i + 1
+			level := 0
+			for end < len(lines) {
+				// An underline turns the paragraph above it into a heading
+				if level = setextLevel(lines[end]); level > 0 || startsMarkdownBlock(lines, end) {
+					break
+				}
+				end++
+			}
+			if level > 0 {
+				title := strings.Join(strings.Fields(strings.Join(lines[i:end], " ")), " ")
+				blocks = append(blocks, markdownBlock{kind: markdownHeading, lines: lines[i : end+1], level: level, title: title})
+				i = end + 1
+			} else {
+				blocks = append(blocks, markdownBlock{kind: markdownParagraph, lines: lines[i:end]})
+				i = end
+			}
+		}
+
+		inList = false
+	}
+
+	return blocks
+}
+
+// listItemEnd returns the index of the first line after the list item
+// starting at lines[start]. Nested lists, indented continuation lines and
+// lazy continuation lines belong to the item.
+func listItemEnd(lines []string, start int) int {
+	indent := leadingSpaces(lines[start])
+
+	end := start + 1
+	for end < len(lines) {
+		line := lines[end]
+		if strings.TrimSpace(line) == "" {
+			// A blank line continues the item only if indented content
+			// follows it
+			next := end + 1
+			for next < len(lines) && strings.TrimSpace(lines[next]) == "" {
+				next++
+			}
+			if next < len(lines) && leadingSpaces(lines[next]) > indent {
+				end = next
+				continue
+			}
+			return end
+		}
+
+		lineIndent := leadingSpaces(line)
+		if lineIndent <= indent && (listItemRegex.MatchString(line) || startsMarkdownBlock(lines, end)) {
+			return end
+		}
+		end++
+	}
+	return end
+}
+
+// startsMarkdownBlock reports whether lines[i] interrupts a paragraph
+func startsMarkdownBlock(lines []string, i int) bool {
+	line := lines[i]
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, ">") || listItemRegex.MatchString(line) {
+		return true
+	}
+	if leadingSpaces(line) < 4 && (fenceMarker(line) != "" || isATXHeading(trimmed)) {
+		return true
+	}
+	if thematicBreakRegex.MatchString(line) {
+		return true
+	}
+	return strings.Contains(line, "|") && i+1 < len(lines) && strings.Contains(lines[i+1], "-") && tableDelimiterRegex.MatchString(lines[i+1])
+}
+
+// setextLevel returns 1 or 2 if line underlines a setext heading with "="
+// or "-", and 0 otherwise
+func setextLevel(line string) int {
+	trimmed := strings.TrimSpace(line)
+	if leadingSpaces(line) >= 4 || trimmed == "" {
+		return 0
+	}
+	switch {
+	case strings.Trim(trimmed, "=") == "":
+		return 1
+	case strings.Trim(trimmed, "-") == "":
+		return 2
+	}
+	return 0
+}
+
+// frontMatterEnd returns the index of the first line after the YAML front
+// matter opening the document, or 0 if there is none
+func frontMatterEnd(lines []string) int {
+	if len(lines) == 0 || strings.TrimRight(lines[0], " \t") != "---" {
+		return 0
+	}
+	for i := 1; i < len(lines); i++ {
+		switch strings.TrimRight(lines[i], " \t") {
+		case "---", "...":
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// fenceMarker returns the backticks or tildes opening a code fence, or ""
+func fenceMarker(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	for _, c := range []string{"`", "~"} {
+		n := 0
+		for n < len(trimmed) && trimmed[n] == c[0] {
+			n++
+		}
+		if n >= 3 {
+			return trimmed[:n]
+		}
+	}
+	return ""
+}
+
+// isFenceClose reports whether line closes the fence opened by open
+func isFenceClose(line, open string) bool {
+	marker := fenceMarker(open)
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, marker) && strings.Trim(trimmed, marker[:1]) == ""
+}
+
+// headingTitle returns the text of an ATX heading without its markers
+func headingTitle(line string) string {
+	title := strings.TrimSpace(strings.TrimLeft(line, "#"))
+	if stripped := strings.TrimRight(title, "#"); strings.HasSuffix(stripped, " ") || stripped == "" {
+		title = strings.TrimSpace(stripped)
+	}
+	return title
+}
+
+func leadingSpaces(line string) int {
+	n := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			n++
+		case '\t':
+			n += 4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+func trimTrailingBlank(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// EmbeddingInput returns the text to embed for a chunk: its content
+// prefixed with the heading breadcrumb when the chunker recorded one, so that
+// the stored content stays free of the breadcrumb
+func EmbeddingInput(chunk Chunk) string {
+	headings, _ := chunk.Metadata[HeadingPathKey].([]string)
+	if len(headings) == 0 {
+		return chunk.Content
+	}
+	return strings.Join(headings, " > ") + "\n\n" + chunk.Content
+}
@@ -5,19 +5,29 @@ import (
 	"io"
 )
 
-type NotionParser struct{}
+type NotionParser struct {
+	chunking
+}
 
 func NewNotionParser() *NotionParser {
 	return &NotionParser{}
 }
 
 func (p *NotionParser) Parse(file io.Reader, filename string) ([]string, map[string]interface{}, error) {
+	chunks, metadata, err := p.ParseChunks(file, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	return chunkContents(chunks), metadata, nil
+}
+
+func (p *NotionParser) ParseChunks(file io.Reader, filename string) ([]Chunk, map[string]interface{}, error) {
 	var notionData map[string]interface{}
 	if err := json.NewDecoder(file).Decode(&notionData); err != nil {
 		return nil, nil, err
 	}
 
-	var chunks []string
+	var chunks []Chunk
 	metadata := make(map[string]interface{})
 
 	// Extract title
@@ -30,7 +40,10 @@ func (p *NotionParser) Parse(file io.Reader, filename string) ([]string, map[str
 
 	// Extract content
 	if content, ok := notionData["content"].(string); ok {
-		chunks = ChunkByParagraphs(content, 100)
+		var err error
+		if chunks, err = p.chunkStructured(content); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	// Extract tags if available
@@ -7,6 +7,7 @@ import (
 )
 
 type ObsidianParser struct {
+	chunking
 	headerRegex *regexp.Regexp
 	tagRegex    *regexp.Regexp
 }
@@ -19,6 +20,14 @@ func NewObsidianParser() *ObsidianParser {
 }
 
 func (p *ObsidianParser) Parse(file io.Reader, filename string) ([]string, map[string]interface{}, error) {
+	chunks, metadata, err := p.ParseChunks(file, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	return chunkContents(chunks), metadata, nil
+}
+
+func (p *ObsidianParser) ParseChunks(file io.Reader, filename string) ([]Chunk, map[string]interface{}, error) {
 	// Read entire file content
 	content, err := io.ReadAll(file)
 	if err != nil {
@@ -51,8 +60,10 @@ func (p *ObsidianParser) Parse(file io.Reader, filename string) ([]string, map[s
 	}
 	metadata["tags"] = uniqueTags
 
-	// Use paragraph-based chunking with minimum 100 words per chunk
-	chunks := ChunkByParagraphs(text, 100)
+	chunks, err := p.chunkStructured(text)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	return chunks, metadata, nil
 }
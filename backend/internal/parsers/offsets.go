@@ -0,0 +1,142 @@
+package parsers
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Chunk metadata keys locating a chunk in the original file. Offsets are in
+// bytes with the end exclusive; lines are numbered from 1.
+const (
+	StartOffsetKey = "start_offset"
+	EndOffsetKey   = "end_offset"
+	StartLineKey   = "start_line"
+	EndLineKey     = "end_line"
+)
+
+// minAnchorLength is the shortest line, ignoring whitespace, used to locate
+// a chunk line by line. Shorter lines such as code fence markers are too
+// ambiguous to place.
+const minAnchorLength = 4
+
+// LocateChunks records where each chunk's content appears in source. Chunks
+// are matched ignoring whitespace, since chunkers normalize line breaks and
+// spacing, and in order, so that repeated text is attributed to the right
+// chunk. Chunks that cannot be found, such as text extracted from binary
+// formats, are left without a location.
+func LocateChunks(source []byte, chunks []Chunk) {
+	if !utf8.Valid(source) {
+		return
+	}
+
+	index := newSourceIndex(string(source))
+	from := 0
+	for i := range chunks {
+		start, end, ok := index.locate(chunks[i].Content, from)
+		if !ok {
+			continue
+		}
+		// Later chunks may overlap this one but never start before it
+		from = index.compactPosition(start)
+
+		if chunks[i].Metadata == nil {
+			chunks[i].Metadata = make(map[string]interface{})
+		}
+		chunks[i].Metadata[StartOffsetKey] = start
+		chunks[i].Metadata[EndOffsetKey] = end
+		chunks[i].Metadata[StartLineKey] = index.line(start)
+		chunks[i].Metadata[EndLineKey] = index.line(end - 1)
+	}
+}
+
+// sourceIndex maps the non-whitespace bytes of a source to their offsets
+type sourceIndex struct {
+	compact    string
+	offsets    []int
+	lineStarts []int
+}
+
+func newSourceIndex(source string) *sourceIndex {
+	var compact strings.Builder
+	offsets := make([]int, 0, len(source))
+	lineStarts := []int{0}
+
+	for i := 0; i < len(source); i++ {
+		c := source[i]
+		if c == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+		if isASCIISpace(c) {
+			continue
+		}
+		compact.WriteByte(c)
+		offsets = append(offsets, i)
+	}
+
+	return &sourceIndex{compact: compact.String(), offsets: offsets, lineStarts: lineStarts}
+}
+
+// locate finds text at or after the compact position from and returns its
+// byte range in the source
+func (s *sourceIndex) locate(text string, from int) (int, int, bool) {
+	needle := compactText(text)
+	if needle == "" || from > len(s.compact) {
+		return 0, 0, false
+	}
+
+	if i := strings.Index(s.compact[from:], needle); i >= 0 {
+		start := from + i
+		return s.offsets[start], s.offsets[start+len(needle)-1] + 1, true
+	}
+
+	// Chunkers may rewrite parts of a chunk, e.g. repeat a table header or
+	// reopen a code fence, so fall back to placing its lines in order and
+	// accept the match if most of the chunk was found
+	first, last, matched := -1, -1, 0
+	pos := from
+	for _, line := range strings.Split(text, "\n") {
+		line = compactText(line)
+		if len(line) < minAnchorLength {
+			continue
+		}
+		i := strings.Index(s.compact[pos:], line)
+		if i < 0 {
+			continue
+		}
+		if first < 0 {
+			first = pos + i
+		}
+		pos += i + len(line)
+		last = pos
+		matched += len(line)
+	}
+	if first < 0 || 2*matched < len(needle) {
+		return 0, 0, false
+	}
+	return s.offsets[first], s.offsets[last-1] + 1, true
+}
+
+// compactPosition returns the compact position of a source offset
+func (s *sourceIndex) compactPosition(offset int) int {
+	return sort.SearchInts(s.offsets, offset)
+}
+
+// line returns the 1-based line number of a source offset
+func (s *sourceIndex) line(offset int) int {
+	return sort.Search(len(s.lineStarts), func(i int) bool { return s.lineStarts[i] > offset })
+}
+
+func compactText(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if !isASCIISpace(text[i]) {
+			b.WriteByte(text[i])
+		}
+	}
+	return b.String()
+}
+
+func isASCIISpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
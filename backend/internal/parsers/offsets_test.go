@@ -0,0 +1,68 @@
+package parsers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLocateChunks(t *testing.T) {
+	source := "# Notes\r\n\r\nFirst paragraph\r\nwraps here.\r\n\r\nSecond paragraph.\r\n\r\nFirst paragraph\r\nwraps here.\r\n"
+
+	chunks := []Chunk{
+		{Content: "# Notes"},
+		{Content: "First paragraph wraps here."},
+		{Content: "Second paragraph."},
+		{Content: "First paragraph wraps here."},
+		{Content: "Not in the source."},
+	}
+	LocateChunks([]byte(source), chunks)
+
+	tests := []struct {
+		text      string
+		startLine int
+		endLine   int
+		nth       int
+	}{
+		{"# Notes", 1, 1, 0},
+		{"First paragraph\r\nwraps here.", 3, 4, 0},
+		{"Second paragraph.", 6, 6, 0},
+		{"First paragraph\r\nwraps here.", 8, 9, 1},
+	}
+	for i, tt := range tests {
+		start := strings.Index(source, tt.text)
+		if tt.nth == 1 {
+			start = strings.LastIndex(source, tt.text)
+		}
+		metadata := chunks[i].Metadata
+		if metadata[StartOffsetKey] != start || metadata[EndOffsetKey] != start+len(tt.text) {
+			t.Errorf("chunk %d offsets = %v-%v, want %d-%d", i, metadata[StartOffsetKey], metadata[EndOffsetKey], start, start+len(tt.text))
+		}
+		if metadata[StartLineKey] != tt.startLine || metadata[EndLineKey] != tt.endLine {
+			t.Errorf("chunk %d lines = %v-%v, want %d-%d", i, metadata[StartLineKey], metadata[EndLineKey], tt.startLine, tt.endLine)
+		}
+	}
+
+	if _, ok := chunks[4].Metadata[StartOffsetKey]; ok {
+		t.Errorf("chunk missing from the source has a location: %v", chunks[4].Metadata)
+	}
+}
+
+func TestLocateChunksRewrittenBlocks(t *testing.T) {
+	var table strings.Builder
+	table.WriteString("| Name | Value |\n|------|-------|\n")
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&table, "| a fairly long row name %d | and its fairly long value %d |\n", i, i)
+	}
+	source := "Intro.\n\n" + table.String()
+
+	// A split table repeats its header in the second piece
+	rows := strings.Split(strings.TrimSpace(table.String()), "\n")
+	second := strings.Join(append(rows[:2:2], rows[12:]...), "\n")
+	chunks := []Chunk{{Content: strings.Join(rows[:12], "\n")}, {Content: second}}
+	LocateChunks([]byte(source), chunks)
+
+	if chunks[1].Metadata[EndOffsetKey] != len(strings.TrimRight(source, "\n")) {
+		t.Errorf("second chunk end = %v, want the end of the table", chunks[1].Metadata[EndOffsetKey])
+	}
+}
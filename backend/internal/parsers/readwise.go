@@ -0,0 +1,215 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ReadwiseParser reads Readwise exports, either the CSV export covering the
+// whole library or the per-book Markdown export
+type ReadwiseParser struct {
+	headingRegex  *regexp.Regexp
+	authorRegex   *regexp.Regexp
+	titleRegex    *regexp.Regexp
+	locationRegex *regexp.Regexp
+	wikilinkRegex *regexp.Regexp
+}
+
+func NewReadwiseParser() *ReadwiseParser {
+	return &ReadwiseParser{
+		headingRegex:  regexp.MustCompile(`^#{1,3}\s+(.+)$`),
+		authorRegex:   regexp.MustCompile(`^-\s+Author:\s*(.+)$`),
+		titleRegex:    regexp.MustCompile(`^-\s+Full Title:\s*(.+)$`),
+		locationRegex: regexp.MustCompile(`\s*\((?:\[)?(Location|Page)\s+([\w-]+)(?:\]\([^)]*\))?\)\s*$`),
+		wikilinkRegex: regexp.MustCompile(`\[\[([^\]]+)\]\]`),
+	}
+}
+
+func (p *ReadwiseParser) Parse(file io.Reader, filename string) ([]string, map[string]interface{}, error) {
+	documents, err := p.ParseDocuments(file, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	chunks, metadata := flattenDocuments(documents, filename)
+	return chunks, metadata, nil
+}
+
+func (p *ReadwiseParser) ParseDocuments(file io.Reader, filename string) ([]ParsedDocument, error) {
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	content = bytes.TrimPrefix(content, []byte("\ufeff"))
+
+	if strings.EqualFold(filepath.Ext(filename), ".csv") {
+		return p.parseCSV(content, filename)
+	}
+	return p.parseMarkdown(string(content), filename), nil
+}
+
+// parseCSV reads the library-wide CSV export, one row per highlight
+func (p *ReadwiseParser) parseCSV(content []byte, filename string) ([]ParsedDocument, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read readwise csv header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["highlight"]; !ok {
+		return nil, fmt.Errorf("readwise csv is missing the Highlight column")
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	library := newHighlightLibrary()
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read readwise csv: %w", err)
+		}
+
+		text := field(record, "highlight")
+		if text == "" {
+			continue
+		}
+
+		title := field(record, "book title")
+		if title == "" {
+			title = strings.TrimSuffix(filename, filepath.Ext(filename))
+		}
+
+		h := highlight{
+			Text:          text,
+			Note:          field(record, "note"),
+			HighlightedAt: parseHighlightTime(field(record, "highlighted at")),
+			Tags:          splitTagList(field(record, "tags")),
+		}
+		location := field(record, "location")
+		if strings.EqualFold(field(record, "location type"), "page") {
+			h.Page = location
+		} else {
+			h.Location = location
+		}
+
+		b := library.book(title, field(record, "book author"))
+		b.Highlights = append(b.Highlights, h)
+	}
+
+	return library.documents(filename), nil
+}
+
+// parseMarkdown reads a per-book Markdown export with a metadata section
+// followed by a bulleted list of highlights
+func (p *ReadwiseParser) parseMarkdown(content, filename string) []ParsedDocument {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	title := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	var author string
+	var highlights []highlight
+	inHighlights := false
+	titleFromHeading := false
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+
+		if m := p.headingRegex.FindStringSubmatch(trimmed); m != nil {
+			heading := strings.TrimSpace(m[1])
+			switch {
+			case strings.EqualFold(heading, "highlights"), strings.HasPrefix(strings.ToLower(heading), "highlights "):
+				inHighlights = true
+			case strings.EqualFold(heading, "metadata"):
+				inHighlights = false
+			case strings.HasPrefix(trimmed, "# ") && !titleFromHeading:
+				title = heading
+				titleFromHeading = true
+			}
+			continue
+		}
+
+		if !inHighlights {
+			if m := p.authorRegex.FindStringSubmatch(trimmed); m != nil {
+				author = p.stripWikilinks(m[1])
+			} else if m := p.titleRegex.FindStringSubmatch(trimmed); m != nil {
+				title = strings.TrimSpace(m[1])
+			}
+			continue
+		}
+
+		indented := line != strings.TrimLeft(line, " \t")
+		switch {
+		case !indented && strings.HasPrefix(trimmed, "- "):
+			highlights = append(highlights, p.parseHighlightLine(strings.TrimPrefix(trimmed, "- ")))
+		case len(highlights) == 0:
+			continue
+		case strings.HasPrefix(trimmed, "- Note:"):
+			last := &highlights[len(highlights)-1]
+			last.Note = joinNotes(last.Note, strings.TrimSpace(strings.TrimPrefix(trimmed, "- Note:")))
+		case strings.HasPrefix(trimmed, "- Tags:"):
+			last := &highlights[len(highlights)-1]
+			last.Tags = append(last.Tags, splitTagList(p.stripWikilinks(strings.TrimPrefix(trimmed, "- Tags:")))...)
+		default:
+			last := &highlights[len(highlights)-1]
+			last.Text += "\n" + trimmed
+		}
+	}
+
+	library := newHighlightLibrary()
+	b := library.book(title, author)
+	b.Highlights = highlights
+	return library.documents(filename)
+}
+
+// parseHighlightLine separates the trailing "([Location 123](url))" link
+func (p *ReadwiseParser) parseHighlightLine(line string) highlight {
+	var h highlight
+	if m := p.locationRegex.FindStringSubmatchIndex(line); m != nil {
+		kind := line[m[2]:m[3]]
+		value := line[m[4]:m[5]]
+		if strings.EqualFold(kind, "page") {
+			h.Page = value
+		} else {
+			h.Location = value
+		}
+		line = line[:m[0]]
+	}
+	h.Text = strings.TrimSpace(line)
+	return h
+}
+
+func (p *ReadwiseParser) stripWikilinks(text string) string {
+	return strings.TrimSpace(p.wikilinkRegex.ReplaceAllString(text, "$1"))
+}
+
+// splitTagList splits a comma or space separated tag list, dropping any
+// leading '#'
+func splitTagList(value string) []string {
+	var tags []string
+	for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if tag = strings.TrimPrefix(strings.TrimSpace(tag), "#"); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -6,13 +6,23 @@ import (
 	"strings"
 )
 
-type RoamParser struct{}
+type RoamParser struct {
+	chunking
+}
 
 func NewRoamParser() *RoamParser {
 	return &RoamParser{}
 }
 
 func (p *RoamParser) Parse(file io.Reader, filename string) ([]string, map[string]interface{}, error) {
+	chunks, metadata, err := p.ParseChunks(file, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	return chunkContents(chunks), metadata, nil
+}
+
+func (p *RoamParser) ParseChunks(file io.Reader, filename string) ([]Chunk, map[string]interface{}, error) {
 	var roamData []map[string]interface{}
 	if err := json.NewDecoder(file).Decode(&roamData); err != nil {
 		return nil, nil, err
@@ -32,8 +42,10 @@ func (p *RoamParser) Parse(file io.Reader, filename string) ([]string, map[strin
 		}
 	}
 
-	// Use paragraph-based chunking with minimum 100 words per chunk
-	chunks := ChunkByParagraphs(allContent.String(), 100)
+	chunks, err := p.chunkStructured(allContent.String())
+	if err != nil {
+		return nil, nil, err
+	}
 
 	return chunks, metadata, nil
 }
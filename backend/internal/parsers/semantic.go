@@ -0,0 +1,266 @@
+package parsers
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"zettelkasten/internal/tokenizer"
+)
+
+// Embedder generates embeddings for a batch of texts
+type Embedder interface {
+	GenerateEmbeddings(texts []string) ([][]float32, error)
+}
+
+// SemanticChunker places chunk boundaries where the meaning of the text
+// shifts. The sentences on either side of each gap are embedded and a new
+// chunk starts wherever their similarity falls into the lowest
+// (100 - BreakpointPercentile) percent of the document. Chunks are also
+// closed before they exceed MaxTokens.
+type SemanticChunker struct {
+	embedder             Embedder
+	tokenizer            tokenizer.Tokenizer
+	BreakpointPercentile float64
+	BufferSize           int
+	MaxTokens            int
+}
+
+func NewSemanticChunker(embedder Embedder, tok tokenizer.Tokenizer) *SemanticChunker {
+	if tok == nil {
+		tok = tokenizer.NewEstimator()
+	}
+	return &SemanticChunker{
+		embedder:             embedder,
+		tokenizer:            tok,
+		BreakpointPercentile: 90,
+		BufferSize:           1,
+		MaxTokens:            DefaultMaxTokens,
+	}
+}
+
+// sentence is a sentence of the input and whether it starts a paragraph
+type sentence struct {
+	text           string
+	paragraphStart bool
+}
+
+func (c *SemanticChunker) Chunk(text string) ([]string, error) {
+	sentences := c.splitSentences(text)
+	if len(sentences) == 0 {
+		return nil, nil
+	}
+	if len(sentences) < 3 {
+		return []string{joinSentences(sentences)}, nil
+	}
+
+	// Compare the text on either side of every gap between sentences,
+	// using BufferSize extra sentences per side to smooth out noise from
+	// very short sentences. The text after one gap is the text before
+	// another, so each window of sentences is embedded once.
+	type span struct{ start, end int }
+	var windows []string
+	positions := make(map[span]int)
+	window := func(start, end int) int {
+		key := span{start, end}
+		if i, ok := positions[key]; ok {
+			return i
+		}
+		positions[key] = len(windows)
+		windows = append(windows, joinSentenceTexts(sentences[start:end]))
+		return positions[key]
+	}
+
+	before := make([]int, len(sentences)-1)
+	after := make([]int, len(sentences)-1)
+	for i := range before {
+		start := max(i-c.BufferSize, 0)
+		end := min(i+c.BufferSize+2, len(sentences))
+		before[i] = window(start, i+1)
+		after[i] = window(i+1, end)
+	}
+
+	embeddings, err := c.embedder.GenerateEmbeddings(windows)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) != len(windows) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(windows), len(embeddings))
+	}
+
+	similarities := make([]float64, len(before))
+	for i := range similarities {
+		similarities[i] = cosineSimilarity(embeddings[before[i]], embeddings[after[i]])
+	}
+	threshold := percentile(similarities, 100-c.BreakpointPercentile)
+
+	var chunks []string
+	var current []sentence
+	currentTokens := 0
+	for i, s := range sentences {
+		tokens := c.tokenizer.Count(s.text) + 1
+		if len(current) > 0 && c.MaxTokens > 0 && currentTokens+tokens > c.MaxTokens {
+			chunks = append(chunks, joinSentences(current))
+			current, currentTokens = nil, 0
+		}
+
+		current = append(current, s)
+		currentTokens += tokens
+
+		if i < len(similarities) && similarities[i] < threshold {
+			chunks = append(chunks, joinSentences(current))
+			current, currentTokens = nil, 0
+		}
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, joinSentences(current))
+	}
+
+	return chunks, nil
+}
+
+// splitSentences splits text into sentences, cutting sentences longer than
+// MaxTokens into pieces
+func (c *SemanticChunker) splitSentences(text string) []sentence {
+	sentences := splitIntoSentences(text)
+	if c.MaxTokens <= 0 {
+		return sentences
+	}
+
+	sizer := tokenSizer{tokenizer: c.tokenizer, config: ChunkingConfig{MaxTokens: c.MaxTokens}}
+	var result []sentence
+	for _, s := range sentences {
+		if c.tokenizer.Count(s.text) <= c.MaxTokens {
+			result = append(result, s)
+			continue
+		}
+		for i, piece := range sizer.splitTokens(s.text, c.MaxTokens, 0) {
+			result = append(result, sentence{text: piece, paragraphStart: s.paragraphStart && i == 0})
+		}
+	}
+	return result
+}
+
+// splitIntoSentences splits text into sentences, remembering paragraph
+// boundaries so they can be restored when sentences are joined again
+func splitIntoSentences(text string) []sentence {
+	var sentences []sentence
+	for _, paragraph := range splitIntoParagraphs(text) {
+		first := true
+		for _, s := range splitParagraphSentences(paragraph) {
+			sentences = append(sentences, sentence{text: s, paragraphStart: first})
+			first = false
+		}
+	}
+	return sentences
+}
+
+// sentenceAbbreviations end with a period without ending a sentence
+var sentenceAbbreviations = map[string]bool{
+	"mr.": true, "mrs.": true, "ms.": true, "dr.": true, "prof.": true, "st.": true,
+	"e.g.": true, "i.e.": true, "etc.": true, "vs.": true, "cf.": true, "fig.": true,
+	"no.": true, "vol.": true, "p.": true, "pp.": true,
+}
+
+func splitParagraphSentences(paragraph string) []string {
+	var sentences []string
+	runes := []rune(paragraph)
+	start := 0
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r != '.' && r != '!' && r != '?' {
+			continue
+		}
+
+		// Include closing punctuation and quotes in the sentence
+		end := i + 1
+		for end < len(runes) && strings.ContainsRune(".!?\"')]”’", runes[end]) {
+			end++
+		}
+		if end < len(runes) && !unicode.IsSpace(runes[end]) {
+			continue
+		}
+
+		if r == '.' {
+			words := strings.Fields(string(runes[start:end]))
+			if len(words) > 0 && sentenceAbbreviations[strings.ToLower(words[len(words)-1])] {
+				continue
+			}
+		}
+
+		if s := strings.TrimSpace(string(runes[start:end])); s != "" {
+			sentences = append(sentences, s)
+		}
+		start = end
+		i = end - 1
+	}
+
+	if s := strings.TrimSpace(string(runes[start:])); s != "" {
+		sentences = append(sentences, s)
+	}
+	return sentences
+}
+
+func joinSentenceTexts(sentences []sentence) string {
+	texts := make([]string, len(sentences))
+	for i, s := range sentences {
+		texts[i] = s.text
+	}
+	return strings.Join(texts, " ")
+}
+
+func joinSentences(sentences []sentence) string {
+	var b strings.Builder
+	for i, s := range sentences {
+		if i > 0 {
+			if s.paragraphStart {
+				b.WriteString("\n\n")
+			} else {
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString(s.text)
+	}
+	return b.String()
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		if i >= len(b) {
+			break
+		}
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// percentile returns the p-th percentile of values using linear
+// interpolation between closest ranks
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	if p <= 0 {
+		return sorted[0]
+	}
+	if p >= 100 {
+		return sorted[len(sorted)-1]
+	}
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
@@ -6,13 +6,23 @@ import (
 	"strings"
 )
 
-type StandardParser struct{}
+type StandardParser struct {
+	chunking
+}
 
 func NewStandardParser() *StandardParser {
 	return &StandardParser{}
 }
 
 func (p *StandardParser) Parse(file io.Reader, filename string) ([]string, map[string]interface{}, error) {
+	chunks, metadata, err := p.ParseChunks(file, filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	return chunkContents(chunks), metadata, nil
+}
+
+func (p *StandardParser) ParseChunks(file io.Reader, filename string) ([]Chunk, map[string]interface{}, error) {
 	// Read entire file content
 	content, err := io.ReadAll(file)
 	if err != nil {
@@ -30,8 +40,10 @@ func (p *StandardParser) Parse(file io.Reader, filename string) ([]string, map[s
 	metadata["title"] = title
 	metadata["original_path"] = filename
 
-	// Use paragraph-based chunking with minimum 100 words per chunk
-	chunks := ChunkByParagraphs(text, 100)
+	chunks, err := p.chunkStructured(text)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	return chunks, metadata, nil
 }
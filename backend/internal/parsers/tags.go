@@ -0,0 +1,47 @@
+package parsers
+
+import (
+	"sort"
+	"strings"
+)
+
+// TagsKey is the metadata key holding the tags of a document or chunk
+const TagsKey = "tags"
+
+// NormalizeTags lowercases tags and strips their leading '#', dropping
+// blanks and duplicates. The result is sorted.
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(tag), "#")))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// MetadataTags returns the tags stored in metadata, whether as a string
+// list or as a generic list decoded from JSON
+func MetadataTags(metadata map[string]interface{}) []string {
+	switch tags := metadata[TagsKey].(type) {
+	case []string:
+		return tags
+	case []interface{}:
+		var list []string
+		for _, tag := range tags {
+			if s, ok := tag.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	case string:
+		// Front matter may hold a comma separated list
+		return strings.Split(tags, ",")
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package parsers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags := NormalizeTags([]string{"#Project", "project", " reading/books ", "", "#", "Alpha"})
+	expected := []string{"alpha", "project", "reading/books"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("NormalizeTags = %q, want %q", tags, expected)
+	}
+
+	if tags := NormalizeTags(nil); tags == nil || len(tags) != 0 {
+		t.Errorf("NormalizeTags(nil) = %#v, want an empty list", tags)
+	}
+}
+
+func TestMetadataTags(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected []string
+	}{
+		{[]string{"a", "b"}, []string{"a", "b"}},
+		{[]interface{}{"a", 1, "b"}, []string{"a", "b"}},
+		{"a, b", []string{"a", " b"}},
+		{nil, nil},
+	}
+
+	for _, tt := range tests {
+		tags := MetadataTags(map[string]interface{}{TagsKey: tt.value})
+		if !reflect.DeepEqual(tags, tt.expected) {
+			t.Errorf("MetadataTags(%#v) = %q, want %q", tt.value, tags, tt.expected)
+		}
+	}
+}
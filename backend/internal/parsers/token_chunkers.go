@@ -0,0 +1,276 @@
+package parsers
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"zettelkasten/internal/tokenizer"
+)
+
+// tokenSizer holds the tokenizer and size limits shared by the token-based
+// chunkers
+type tokenSizer struct {
+	tokenizer tokenizer.Tokenizer
+	config    ChunkingConfig
+}
+
+// unit is a paragraph, sentence or fragment that chunks are built from
+type unit struct {
+	sentence
+	tokens int
+}
+
+// ParagraphChunker groups whole paragraphs until a chunk reaches MinTokens,
+// never exceeding MaxTokens. Paragraphs longer than MaxTokens are split on
+// sentence boundaries.
+type ParagraphChunker struct {
+	tokenSizer
+}
+
+func (c *ParagraphChunker) Chunk(text string) ([]string, error) {
+	return c.pack(c.paragraphUnits(text)), nil
+}
+
+// SentenceChunker groups sentences until a chunk reaches MinTokens, never
+// exceeding MaxTokens, so chunks may end in the middle of a paragraph
+type SentenceChunker struct {
+	tokenSizer
+}
+
+func (c *SentenceChunker) Chunk(text string) ([]string, error) {
+	var units []unit
+	for _, paragraph := range splitIntoParagraphs(text) {
+		units = append(units, c.sentenceUnits(paragraph)...)
+	}
+	return c.pack(units), nil
+}
+
+// FixedTokenChunker slides a window of MaxTokens tokens over the text,
+// advancing by MaxTokens - Overlap tokens. The last window is aligned with
+// the end of the text so that it is never shorter than the others.
+type FixedTokenChunker struct {
+	tokenSizer
+}
+
+func (c *FixedTokenChunker) Chunk(text string) ([]string, error) {
+	text = strings.TrimSpace(normalizeNewlines(text))
+	if text == "" {
+		return nil, nil
+	}
+	return c.splitTokens(text, c.config.MaxTokens, c.config.Overlap), nil
+}
+
+// HeadingChunker starts a new chunk at every Markdown heading. Sections
+// longer than MaxTokens are split into paragraph chunks within the section.
+type HeadingChunker struct {
+	tokenSizer
+}
+
+func (c *HeadingChunker) Chunk(text string) ([]string, error) {
+	var chunks []string
+	for _, section := range splitHeadingSections(text) {
+		chunks = append(chunks, c.pack(c.paragraphUnits(section))...)
+	}
+	return chunks, nil
+}
+
+// paragraphUnits returns one unit per paragraph, breaking paragraphs that
+// exceed MaxTokens into sentences
+func (s tokenSizer) paragraphUnits(text string) []unit {
+	var units []unit
+	for _, paragraph := range splitIntoParagraphs(text) {
+		tokens := s.tokenizer.Count(paragraph)
+		if tokens <= s.config.MaxTokens {
+			units = append(units, unit{sentence{text: paragraph, paragraphStart: true}, tokens})
+			continue
+		}
+		units = append(units, s.sentenceUnits(paragraph)...)
+	}
+	return units
+}
+
+// sentenceUnits returns one unit per sentence of paragraph. Sentences that
+// exceed MaxTokens on their own are cut into MaxTokens pieces.
+func (s tokenSizer) sentenceUnits(paragraph string) []unit {
+	var units []unit
+	first := true
+	for _, text := range splitParagraphSentences(paragraph) {
+		pieces := []string{text}
+		if s.tokenizer.Count(text) > s.config.MaxTokens {
+			pieces = s.splitTokens(text, s.config.MaxTokens, 0)
+		}
+		for _, piece := range pieces {
+			units = append(units, unit{sentence{text: piece, paragraphStart: first}, s.tokenizer.Count(piece)})
+			first = false
+		}
+	}
+	return units
+}
+
+// cost is the number of tokens u adds to a chunk, counting the blank line
+// that separates paragraphs
+func (u unit) cost() int {
+	if u.paragraphStart {
+		return u.tokens + 1
+	}
+	return u.tokens
+}
+
+// pack groups units into chunks of at least MinTokens and at most
+// MaxTokens tokens. Each chunk after the first starts with the trailing
+// units of the previous chunk that fit into Overlap tokens.
+func (s tokenSizer) pack(units []unit) []string {
+	var chunks []string
+	var current []unit
+	tokens := 0
+	// pending is set while current holds units not yet part of a chunk, as
+	// opposed to only the overlap carried over from the previous chunk
+	pending := false
+
+	flush := func() {
+		if !pending {
+			return
+		}
+		chunks = append(chunks, joinUnits(current))
+		current = s.overlapUnits(current)
+		tokens = 0
+		for _, u := range current {
+			tokens += u.cost()
+		}
+		pending = false
+	}
+
+	for _, u := range units {
+		if pending && tokens+u.cost() > s.config.MaxTokens {
+			flush()
+		}
+		// Drop the overlap when it leaves no room for the next unit
+		if tokens+u.cost() > s.config.MaxTokens {
+			current, tokens = nil, 0
+		}
+
+		current = append(current, u)
+		tokens += u.cost()
+		pending = true
+
+		if tokens >= s.config.MinTokens {
+			flush()
+		}
+	}
+	flush()
+
+	return chunks
+}
+
+// overlapUnits returns the longest run of trailing units that fits into
+// Overlap tokens
+func (s tokenSizer) overlapUnits(units []unit) []unit {
+	if s.config.Overlap <= 0 {
+		return nil
+	}
+
+	tokens := 0
+	start := len(units)
+	for start > 0 && tokens+units[start-1].cost() <= s.config.Overlap {
+		start--
+		tokens += units[start].cost()
+	}
+	return append([]unit(nil), units[start:]...)
+}
+
+func joinUnits(units []unit) string {
+	sentences := make([]sentence, len(units))
+	for i, u := range units {
+		sentences[i] = u.sentence
+	}
+	return joinSentences(sentences)
+}
+
+// splitTokens cuts text into windows of size tokens, each starting
+// size - overlap tokens after the previous one
+func (s tokenSizer) splitTokens(text string, size, overlap int) []string {
+	offsets := s.tokenizer.Offsets(text)
+	if len(offsets) <= size {
+		return []string{strings.TrimSpace(text)}
+	}
+
+	stride := size - overlap
+	if stride < 1 {
+		stride = 1
+	}
+
+	var pieces []string
+	for start := 0; ; start += stride {
+		end := start + size
+		if end >= len(offsets) {
+			start, end = len(offsets)-size, len(offsets)
+		}
+
+		from := runeStart(text, offsets[start])
+		to := len(text)
+		if end < len(offsets) {
+			to = runeStart(text, offsets[end])
+		}
+		if piece := strings.TrimSpace(text[from:to]); piece != "" {
+			pieces = append(pieces, piece)
+		}
+
+		if end == len(offsets) {
+			break
+		}
+	}
+	return pieces
+}
+
+// runeStart moves offset back to the start of the character it falls in,
+// since byte-level tokens can end in the middle of a UTF-8 sequence
+func runeStart(text string, offset int) int {
+	for offset > 0 && offset < len(text) && !utf8.RuneStart(text[offset]) {
+		offset--
+	}
+	return offset
+}
+
+// splitHeadingSections splits Markdown text before every ATX heading,
+// ignoring lines inside fenced code blocks
+func splitHeadingSections(text string) []string {
+	var sections []string
+	var current strings.Builder
+	inFence := false
+
+	for _, line := range strings.Split(normalizeNewlines(text), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+
+		if !inFence && isATXHeading(trimmed) && strings.TrimSpace(current.String()) != "" {
+			sections = append(sections, current.String())
+			current.Reset()
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+	}
+	if strings.TrimSpace(current.String()) != "" {
+		sections = append(sections, current.String())
+	}
+
+	return sections
+}
+
+// isATXHeading reports whether line is a Markdown heading such as "## Title"
+func isATXHeading(line string) bool {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return false
+	}
+	return level == len(line) || line[level] == ' ' || line[level] == '\t'
+}
+
+func normalizeNewlines(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.ReplaceAll(text, "\r", "\n")
+}
@@ -0,0 +1,73 @@
+package parsers
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// wikilinkRegex matches [[Page]] links, which also covers Logseq and Roam
+// page references such as #[[Page]] and embeds such as ![[Page]]
+var wikilinkRegex = regexp.MustCompile(`\[\[([^\[\]]+)\]\]`)
+
+// WikiLink is a link found in text. Start and End are the byte offsets of
+// the whole link, brackets included.
+type WikiLink struct {
+	Target string
+	Start  int
+	End    int
+}
+
+// FindWikiLinks returns every link in text with the page it links to.
+// Aliases, headings and block references are dropped, so
+// [[Page#Heading|text]] links to "Page"; links to a heading of the same
+// note are skipped.
+func FindWikiLinks(text string) []WikiLink {
+	var links []WikiLink
+	for _, loc := range wikilinkRegex.FindAllStringSubmatchIndex(text, -1) {
+		target := text[loc[2]:loc[3]]
+		if i := strings.IndexByte(target, '|'); i >= 0 {
+			target = target[:i]
+		}
+		if i := strings.IndexAny(target, "#^"); i >= 0 {
+			target = target[:i]
+		}
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+		links = append(links, WikiLink{Target: target, Start: loc[0], End: loc[1]})
+	}
+	return links
+}
+
+// ExtractWikiLinks returns the pages linked from text, in order of first
+// appearance, as FindWikiLinks reads them
+func ExtractWikiLinks(text string) []string {
+	var links []string
+	seen := make(map[string]bool)
+	for _, link := range FindWikiLinks(text) {
+		key := NormalizeLinkTarget(link.Target)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		links = append(links, link.Target)
+	}
+	return links
+}
+
+// NormalizeLinkTarget returns the key a link target or note title is
+// matched by: its last path element without a .md extension, lowercased
+// and with collapsed whitespace
+func NormalizeLinkTarget(target string) string {
+	target = strings.TrimSpace(strings.ReplaceAll(target, "\\", "/"))
+	if target == "" {
+		return ""
+	}
+	target = path.Base(target)
+	if strings.EqualFold(path.Ext(target), ".md") {
+		target = target[:len(target)-len(".md")]
+	}
+	return strings.ToLower(strings.Join(strings.Fields(target), " "))
+}
@@ -0,0 +1,52 @@
+package parsers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractWikiLinks(t *testing.T) {
+	text := "See [[Deep Work]] and [[deep work|the book]], #[[Reading List]], ![[Diagram.png]],\n" +
+		"[[Habits#Keystone habits]], [[Journal^abc123]], [[#Local heading]] and [[ ]]."
+
+	links := ExtractWikiLinks(text)
+	expected := []string{"Deep Work", "Reading List", "Diagram.png", "Habits", "Journal"}
+	if !reflect.DeepEqual(links, expected) {
+		t.Errorf("ExtractWikiLinks = %q, want %q", links, expected)
+	}
+
+	if links := ExtractWikiLinks("no links"); links != nil {
+		t.Errorf("ExtractWikiLinks without links = %q, want nil", links)
+	}
+}
+
+func TestFindWikiLinks(t *testing.T) {
+	text := "Read [[Deep Work|it]] twice."
+	links := FindWikiLinks(text)
+	if len(links) != 1 {
+		t.Fatalf("FindWikiLinks found %d links, want 1", len(links))
+	}
+	if link := links[0]; link.Target != "Deep Work" || text[link.Start:link.End] != "[[Deep Work|it]]" {
+		t.Errorf("FindWikiLinks = %+v", link)
+	}
+}
+
+func TestNormalizeLinkTarget(t *testing.T) {
+	tests := []struct {
+		target   string
+		expected string
+	}{
+		{"Deep Work", "deep work"},
+		{"  Deep   Work ", "deep work"},
+		{"vault/Books/Deep Work.md", "deep work"},
+		{`Books\Deep Work`, "deep work"},
+		{"Diagram.png", "diagram.png"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if key := NormalizeLinkTarget(tt.target); key != tt.expected {
+			t.Errorf("NormalizeLinkTarget(%q) = %q, want %q", tt.target, key, tt.expected)
+		}
+	}
+}
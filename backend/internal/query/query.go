@@ -0,0 +1,163 @@
+// Package query parses the search query language: free text mixed with
+// field operators such as tag:project or after:2025-01-01 and negations
+// such as -draft
+package query
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Fields of the query language
+const (
+	FieldTag    = "tag"
+	FieldSource = "source"
+	FieldAfter  = "after"
+	FieldBefore = "before"
+	FieldTitle  = "title"
+	FieldFrom   = "from"
+	FieldTo     = "to"
+	// FieldText is a negated word or phrase, e.g. -draft
+	FieldText = "text"
+)
+
+// aliases maps accepted field names to their canonical name
+var aliases = map[string]string{
+	"tag":    FieldTag,
+	"tags":   FieldTag,
+	"source": FieldSource,
+	"type":   FieldSource,
+	"after":  FieldAfter,
+	"since":  FieldAfter,
+	"before": FieldBefore,
+	"until":  FieldBefore,
+	"title":  FieldTitle,
+	"from":   FieldFrom,
+	"to":     FieldTo,
+}
+
+// negatable are the fields that can be excluded with a leading '-'
+var negatable = map[string]bool{
+	FieldTag:    true,
+	FieldSource: true,
+	FieldText:   true,
+}
+
+// Filter is a field operator found in a query
+type Filter struct {
+	Field   string `json:"field"`
+	Value   string `json:"value"`
+	Negated bool   `json:"negated"`
+}
+
+// Parsed is a query split into its free text and filters
+type Parsed struct {
+	// Text is the free text left once operators are removed
+	Text     string   `json:"text"`
+	Filters  []Filter `json:"filters"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Parse splits a query into free text and filters. Words that look like
+// operators but name an unknown field, such as "note:", stay in the text.
+func Parse(input string) Parsed {
+	parsed := Parsed{Filters: []Filter{}}
+	var text []string
+
+	for _, token := range tokenize(input) {
+		negated := false
+		body := token
+		if len(body) > 1 && body[0] == '-' {
+			negated = true
+			body = body[1:]
+		}
+
+		if field, value, ok := splitOperator(body); ok {
+			if value == "" {
+				parsed.Warnings = append(parsed.Warnings, fmt.Sprintf("%s: needs a value", field))
+				continue
+			}
+			if negated && !negatable[field] {
+				parsed.Warnings = append(parsed.Warnings, fmt.Sprintf("%s: cannot be negated", field))
+				continue
+			}
+			if field == FieldAfter || field == FieldBefore {
+				if _, err := ParseDate(value); err != nil {
+					parsed.Warnings = append(parsed.Warnings, fmt.Sprintf("%s:%s is not a date", field, value))
+					continue
+				}
+			}
+			parsed.Filters = append(parsed.Filters, Filter{Field: field, Value: value, Negated: negated})
+			continue
+		}
+
+		if negated {
+			if value := unquote(body); value != "" {
+				parsed.Filters = append(parsed.Filters, Filter{Field: FieldText, Value: value, Negated: true})
+			}
+			continue
+		}
+		text = append(text, unquote(token))
+	}
+
+	parsed.Text = strings.TrimSpace(strings.Join(text, " "))
+	return parsed
+}
+
+// splitOperator recognizes field:value, where value may be quoted
+func splitOperator(token string) (string, string, bool) {
+	i := strings.IndexByte(token, ':')
+	if i <= 0 {
+		return "", "", false
+	}
+	field, ok := aliases[strings.ToLower(token[:i])]
+	if !ok {
+		return "", "", false
+	}
+	return field, unquote(token[i+1:]), true
+}
+
+// tokenize splits on whitespace outside double quotes
+func tokenize(input string) []string {
+	var tokens []string
+	var current strings.Builder
+	quoted := false
+
+	for _, r := range input {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			current.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
+
+func unquote(value string) string {
+	return strings.TrimSpace(strings.ReplaceAll(value, `"`, ""))
+}
+
+// dateLayouts are the accepted date formats, most precise first
+var dateLayouts = []string{time.RFC3339, "2006-01-02", "2006-01", "2006"}
+
+// ParseDate parses a date operator value as UTC
+func ParseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
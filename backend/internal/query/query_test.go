@@ -0,0 +1,90 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	parsed := Parse(`tag:project source:obsidian after:2025-01-01 title:"weekly review" -draft how do I plan`)
+
+	if parsed.Text != "how do I plan" {
+		t.Errorf("text = %q", parsed.Text)
+	}
+
+	expected := []Filter{
+		{Field: FieldTag, Value: "project"},
+		{Field: FieldSource, Value: "obsidian"},
+		{Field: FieldAfter, Value: "2025-01-01"},
+		{Field: FieldTitle, Value: "weekly review"},
+		{Field: FieldText, Value: "draft", Negated: true},
+	}
+	if !reflect.DeepEqual(parsed.Filters, expected) {
+		t.Errorf("filters = %+v, want %+v", parsed.Filters, expected)
+	}
+	if len(parsed.Warnings) != 0 {
+		t.Errorf("unexpected warnings %q", parsed.Warnings)
+	}
+}
+
+func TestParseNegationsAndAliases(t *testing.T) {
+	parsed := Parse(`-tag:archived Type:notion -"first draft" since:2024-06`)
+
+	expected := []Filter{
+		{Field: FieldTag, Value: "archived", Negated: true},
+		{Field: FieldSource, Value: "notion"},
+		{Field: FieldText, Value: "first draft", Negated: true},
+		{Field: FieldAfter, Value: "2024-06"},
+	}
+	if !reflect.DeepEqual(parsed.Filters, expected) {
+		t.Errorf("filters = %+v, want %+v", parsed.Filters, expected)
+	}
+	if parsed.Text != "" {
+		t.Errorf("text = %q, want none", parsed.Text)
+	}
+}
+
+func TestParseKeepsText(t *testing.T) {
+	tests := []struct {
+		input string
+		text  string
+	}{
+		{`note: remember "exact phrase"`, "note: remember exact phrase"},
+		{`see https://example.com`, "see https://example.com"},
+		{`a - b`, "a - b"},
+	}
+
+	for _, tt := range tests {
+		parsed := Parse(tt.input)
+		if parsed.Text != tt.text || len(parsed.Filters) != 0 {
+			t.Errorf("Parse(%q) = %+v, want text %q and no filters", tt.input, parsed, tt.text)
+		}
+	}
+}
+
+func TestParseWarnings(t *testing.T) {
+	parsed := Parse(`after:yesterday tag: -before:2025-01-01 ideas`)
+	if len(parsed.Filters) != 0 {
+		t.Errorf("unexpected filters %+v", parsed.Filters)
+	}
+	if len(parsed.Warnings) != 3 {
+		t.Errorf("warnings = %q, want 3", parsed.Warnings)
+	}
+	if parsed.Text != "ideas" {
+		t.Errorf("text = %q", parsed.Text)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	date, err := ParseDate("2025-03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !date.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("got %v", date)
+	}
+	if _, err := ParseDate("03/2025"); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -1,11 +1,9 @@
 package queue
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"time"
 
@@ -22,13 +20,21 @@ type JobQueue struct {
 }
 
 type JobItem struct {
-	JobID      string    `json:"job_id"`
-	UserID     string    `json:"user_id"`
-	Filename   string    `json:"filename"`
-	SourceType string    `json:"source_type"`
-	FileData   []byte    `json:"file_data"`
-	CreatedAt  time.Time `json:"created_at"`
-	Status     string    `json:"status"`
+	JobID      string                  `json:"job_id"`
+	UserID     string                  `json:"user_id"`
+	Filename   string                  `json:"filename"`
+	SourceType string                  `json:"source_type"`
+	Options    services.ProcessOptions `json:"options"`
+	// DocumentID is set for jobs that rechunk an existing document
+	DocumentID string `json:"document_id,omitempty"`
+	// Reindex marks jobs that add the user's documents, or only DocumentID,
+	// to the keyword index
+	Reindex bool `json:"reindex,omitempty"`
+	// FileID is the GridFS file holding the uploaded file, so that large
+	// uploads are not copied into Redis
+	FileID    string    `json:"file_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Status    string    `json:"status"`
 }
 
 func NewJobQueue(redis *database.RedisClient, docService *services.DocumentService, eventService *services.EventService) *JobQueue {
@@ -165,23 +171,63 @@ func (q *JobQueue) resumeUnprocessedJobs() {
 	}
 }
 
-func (q *JobQueue) QueueFile(jobID, userID string, file io.Reader, filename, sourceType string) error {
-	job := JobItem{
+func (q *JobQueue) QueueFile(jobID, userID string, data []byte, filename, sourceType string, opts services.ProcessOptions) error {
+	// Keep the file in GridFS and only queue its ID
+	fileID, err := q.documentService.StoreUpload(userID, filename, sourceType, data)
+	if err != nil {
+		return err
+	}
+
+	err = q.enqueue(JobItem{
 		JobID:      jobID,
 		UserID:     userID,
 		Filename:   filename,
 		SourceType: sourceType,
+		Options:    opts,
+		FileID:     fileID,
 		CreatedAt:  time.Now(),
 		Status:     "pending",
-	}
-
-	// Read file data
-	data, err := io.ReadAll(file)
+	})
 	if err != nil {
-		return err
+		if err := q.documentService.DiscardUpload(context.Background(), fileID); err != nil {
+			log.Printf("Warning: Failed to delete uploaded file %s: %v", fileID, err)
+		}
 	}
-	job.FileData = data
+	return err
+}
+
+// QueueRechunk queues a job that rechunks and re-embeds an existing document
+func (q *JobQueue) QueueRechunk(jobID, userID, documentID string, opts services.ProcessOptions) error {
+	q.updateJobStatus(jobID, "pending", 0)
 
+	return q.enqueue(JobItem{
+		JobID:      jobID,
+		UserID:     userID,
+		Filename:   documentID,
+		Options:    opts,
+		DocumentID: documentID,
+		CreatedAt:  time.Now(),
+		Status:     "pending",
+	})
+}
+
+// QueueReindex queues a job that adds the user's documents, or only
+// documentID if set, to the keyword index
+func (q *JobQueue) QueueReindex(jobID, userID, documentID string) error {
+	q.updateJobStatus(jobID, "pending", 0)
+
+	return q.enqueue(JobItem{
+		JobID:      jobID,
+		UserID:     userID,
+		Filename:   "keyword index",
+		DocumentID: documentID,
+		Reindex:    true,
+		CreatedAt:  time.Now(),
+		Status:     "pending",
+	})
+}
+
+func (q *JobQueue) enqueue(job JobItem) error {
 	// Store persistent copy of job data for recovery
 	persistentJobData, err := json.Marshal(job)
 	if err != nil {
@@ -189,8 +235,8 @@ func (q *JobQueue) QueueFile(jobID, userID string, file io.Reader, filename, sou
 	}
 
 	// Store with longer expiration (7 days) for recovery purposes
-	if err := q.redis.Set(fmt.Sprintf("persistent_job:%s", jobID), string(persistentJobData), 7*24*time.Hour); err != nil {
-		log.Printf("Warning: Could not store persistent job data for %s: %v", jobID, err)
+	if err := q.redis.Set(fmt.Sprintf("persistent_job:%s", job.JobID), string(persistentJobData), 7*24*time.Hour); err != nil {
+		log.Printf("Warning: Could not store persistent job data for %s: %v", job.JobID, err)
 	}
 
 	// Push to queue
@@ -200,7 +246,7 @@ func (q *JobQueue) QueueFile(jobID, userID string, file io.Reader, filename, sou
 	}
 
 	log.Printf("Queued file for processing: %s (Job ID: %s, User ID: %s, Source: %s)",
-		filename, jobID, userID, sourceType)
+		job.Filename, job.JobID, job.UserID, job.SourceType)
 
 	return q.redis.LPush("job_queue", string(jobData))
 }
@@ -228,15 +274,25 @@ func (q *JobQueue) processJobWithContext(ctx context.Context, job *JobItem) {
 		return
 	}
 
-	// Process file
-	err := q.documentService.ProcessFile(
-		ctx,
-		job.JobID,
-		job.UserID,
-		bytes.NewReader(job.FileData),
-		job.Filename,
-		job.SourceType,
-	)
+	// Process file, rechunk the document or fill the keyword index
+	var err error
+	if job.Reindex {
+		var indexed int
+		indexed, err = q.documentService.ReindexKeywords(ctx, job.UserID, job.DocumentID)
+		log.Printf("Added %d chunks to the keyword index for user %s", indexed, job.UserID)
+	} else if job.DocumentID != "" {
+		err = q.documentService.RechunkDocument(ctx, job.UserID, job.DocumentID, job.Options)
+	} else {
+		err = q.documentService.ProcessStoredFile(
+			ctx,
+			job.JobID,
+			job.UserID,
+			job.FileID,
+			job.Filename,
+			job.SourceType,
+			job.Options,
+		)
+	}
 
 	if err != nil {
 		log.Printf("Failed to process file %s: %v", job.Filename, err)
@@ -0,0 +1,88 @@
+// Package ranking scores and merges search candidates
+package ranking
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// BM25 parameters
+const (
+	K1 = 1.2
+	B  = 0.75
+)
+
+// Terms splits text into lowercase index terms. Letters and digits joined
+// by '-', '_', '.' or ':' stay together, so identifiers such as error codes
+// and versions are matched exactly. A "#tag" yields both "#tag" and "tag".
+func Terms(text string) []string {
+	var terms []string
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); {
+		if !isTermRune(runes[i]) {
+			i++
+			continue
+		}
+
+		tag := i > 0 && runes[i-1] == '#'
+		start := i
+		for i < len(runes) {
+			if isTermRune(runes[i]) {
+				i++
+				continue
+			}
+			// Keep joiners between two term characters
+			if isJoiner(runes[i]) && i+1 < len(runes) && isTermRune(runes[i+1]) {
+				i++
+				continue
+			}
+			break
+		}
+
+		term := strings.ToLower(string(runes[start:i]))
+		terms = append(terms, term)
+		if tag {
+			terms = append(terms, "#"+term)
+		}
+	}
+	return terms
+}
+
+func isTermRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isJoiner(r rune) bool {
+	return r == '-' || r == '_' || r == '.' || r == ':' || r == '/'
+}
+
+// TermFrequencies counts the terms of text
+func TermFrequencies(text string) (map[string]int, int) {
+	terms := Terms(text)
+	frequencies := make(map[string]int, len(terms))
+	for _, term := range terms {
+		frequencies[term]++
+	}
+	return frequencies, len(terms)
+}
+
+// IDF is the inverse document frequency of a term found in df of n
+// documents
+func IDF(df, n int) float64 {
+	return math.Log(1 + (float64(n)-float64(df)+0.5)/(float64(df)+0.5))
+}
+
+// BM25 scores one term of a document of docLen terms in which it occurs tf
+// times
+func BM25(tf, docLen int, avgDocLen, idf float64) float64 {
+	if tf == 0 {
+		return 0
+	}
+	if avgDocLen <= 0 {
+		avgDocLen = 1
+	}
+	f := float64(tf)
+	return idf * f * (K1 + 1) / (f + K1*(1-B+B*float64(docLen)/avgDocLen))
+}
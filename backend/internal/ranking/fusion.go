@@ -0,0 +1,41 @@
+package ranking
+
+import "sort"
+
+// RRFConstant dampens the advantage of the top ranks in reciprocal rank
+// fusion
+const RRFConstant = 60
+
+// Fused is an item ranked by reciprocal rank fusion
+type Fused struct {
+	ID    string
+	Score float64
+}
+
+// Fuse merges ranked lists of IDs with weighted reciprocal rank fusion. An
+// item scores weight/(RRFConstant+rank) in every list it appears in. Ties
+// keep the order in which items were first seen.
+func Fuse(lists [][]string, weights []float64) []Fused {
+	scores := make(map[string]float64)
+	var order []string
+
+	for i, list := range lists {
+		weight := 1.0
+		if i < len(weights) {
+			weight = weights[i]
+		}
+		for rank, id := range list {
+			if _, seen := scores[id]; !seen {
+				order = append(order, id)
+			}
+			scores[id] += weight / float64(RRFConstant+rank+1)
+		}
+	}
+
+	fused := make([]Fused, len(order))
+	for i, id := range order {
+		fused[i] = Fused{ID: id, Score: scores[id]}
+	}
+	sort.SliceStable(fused, func(i, j int) bool { return fused[i].Score > fused[j].Score })
+	return fused
+}
@@ -0,0 +1,92 @@
+package ranking
+
+import "math"
+
+// MMR orders up to k items by maximal marginal relevance: each pick
+// maximizes lambda*relevance - (1-lambda)*similarity to the items already
+// picked. relevance should be on a 0-1 scale. Items without a vector are
+// treated as unlike every other item. It returns indexes in pick order.
+func MMR(relevance []float64, vectors [][]float32, lambda float64, k int) []int {
+	if k > len(relevance) {
+		k = len(relevance)
+	}
+
+	picked := make([]int, 0, k)
+	used := make([]bool, len(relevance))
+	// maxSimilarity[i] is the highest similarity of item i to a picked item
+	maxSimilarity := make([]float64, len(relevance))
+
+	for len(picked) < k {
+		best, bestScore := -1, math.Inf(-1)
+		for i := range relevance {
+			if used[i] {
+				continue
+			}
+			score := lambda*relevance[i] - (1-lambda)*maxSimilarity[i]
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		used[best] = true
+		picked = append(picked, best)
+		for i := range relevance {
+			if used[i] || i >= len(vectors) || best >= len(vectors) {
+				continue
+			}
+			if similarity := Cosine(vectors[i], vectors[best]); similarity > maxSimilarity[i] {
+				maxSimilarity[i] = similarity
+			}
+		}
+	}
+	return picked
+}
+
+// Cosine returns the cosine similarity of two vectors, or 0 if either is
+// empty or they differ in length
+func Cosine(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+// Centroid returns the mean of the vectors that have the length of the
+// first one, or nil if there are none
+func Centroid(vectors [][]float32) []float32 {
+	var sum []float64
+	count := 0
+	for _, vector := range vectors {
+		if len(vector) == 0 {
+			continue
+		}
+		if sum == nil {
+			sum = make([]float64, len(vector))
+		}
+		if len(vector) != len(sum) {
+			continue
+		}
+		for i, value := range vector {
+			sum[i] += float64(value)
+		}
+		count++
+	}
+	if count == 0 {
+		return nil
+	}
+
+	centroid := make([]float32, len(sum))
+	for i, value := range sum {
+		centroid[i] = float32(value / float64(count))
+	}
+	return centroid
+}
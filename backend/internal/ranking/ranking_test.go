@@ -0,0 +1,129 @@
+package ranking
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"Hello, World!", []string{"hello", "world"}},
+		{"Got ERR_CONN_RESET on v1.2.3", []string{"got", "err_conn_reset", "on", "v1.2.3"}},
+		{"see #Reading-List.", []string{"see", "reading-list", "#reading-list"}},
+		{"end. Next", []string{"end", "next"}},
+		{"naïve café", []string{"naïve", "café"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if terms := Terms(tt.text); !reflect.DeepEqual(terms, tt.expected) {
+				t.Errorf("Terms(%q) = %q, want %q", tt.text, terms, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBM25(t *testing.T) {
+	rare, common := IDF(1, 100), IDF(50, 100)
+	if rare <= common {
+		t.Errorf("rare term idf %f should exceed common term idf %f", rare, common)
+	}
+
+	if BM25(0, 10, 10, rare) != 0 {
+		t.Error("absent term should not score")
+	}
+	if BM25(2, 10, 10, rare) <= BM25(1, 10, 10, rare) {
+		t.Error("score should grow with term frequency")
+	}
+	if BM25(1, 5, 10, rare) <= BM25(1, 20, 10, rare) {
+		t.Error("shorter documents should score higher")
+	}
+}
+
+func TestFuse(t *testing.T) {
+	fused := Fuse([][]string{{"a", "b", "c"}, {"c", "b", "d"}}, []float64{1, 1})
+
+	var ids []string
+	for _, f := range fused {
+		ids = append(ids, f.ID)
+	}
+	// c ranks 3rd and 1st, narrowly beating b at 2nd and 2nd
+	if !reflect.DeepEqual(ids, []string{"c", "b", "a", "d"}) {
+		t.Errorf("unexpected order %v", ids)
+	}
+
+	// With all weight on the first list its order wins
+	fused = Fuse([][]string{{"a", "b"}, {"b", "a"}}, []float64{1, 0})
+	if fused[0].ID != "a" {
+		t.Errorf("expected a first, got %v", fused)
+	}
+}
+
+func TestMMR(t *testing.T) {
+	relevance := []float64{1, 0.95, 0.6}
+	vectors := [][]float32{{1, 0}, {0.99, 0.1}, {0, 1}}
+
+	// Pure relevance keeps the original order
+	if order := MMR(relevance, vectors, 1, 3); !reflect.DeepEqual(order, []int{0, 1, 2}) {
+		t.Errorf("lambda 1: got %v", order)
+	}
+
+	// Balancing relevance and diversity skips the near duplicate
+	if order := MMR(relevance, vectors, 0.5, 2); !reflect.DeepEqual(order, []int{0, 2}) {
+		t.Errorf("lambda 0.5: got %v", order)
+	}
+
+	// Items without vectors are never penalized
+	if order := MMR(relevance, [][]float32{{1, 0}, nil, {1, 0}}, 0.5, 3); !reflect.DeepEqual(order, []int{0, 1, 2}) {
+		t.Errorf("missing vectors: got %v", order)
+	}
+}
+
+func TestCosine(t *testing.T) {
+	if c := Cosine([]float32{1, 0}, []float32{2, 0}); c < 0.999 {
+		t.Errorf("parallel vectors: %f", c)
+	}
+	if c := Cosine([]float32{1, 0}, []float32{0, 3}); c != 0 {
+		t.Errorf("orthogonal vectors: %f", c)
+	}
+	if c := Cosine([]float32{1}, []float32{1, 0}); c != 0 {
+		t.Errorf("mismatched lengths: %f", c)
+	}
+}
+
+func TestCentroid(t *testing.T) {
+	centroid := Centroid([][]float32{{1, 0}, nil, {0, 1}, {3}})
+	if len(centroid) != 2 || centroid[0] != 0.5 || centroid[1] != 0.5 {
+		t.Errorf("centroid = %v, want [0.5 0.5]", centroid)
+	}
+	if Centroid(nil) != nil {
+		t.Error("expected no centroid of no vectors")
+	}
+}
+
+func TestTopTerms(t *testing.T) {
+	groups := []map[string]int{
+		{"the": 20, "garden": 5, "compost": 4, "notes": 6, "#garden": 3, "2024": 9},
+		{"the": 25, "sourdough": 6, "starter": 3, "notes": 6, "of": 12},
+		{},
+	}
+
+	top := TopTerms(groups, 2)
+	expected := [][]string{{"garden", "compost"}, {"sourdough", "starter"}, nil}
+	if !reflect.DeepEqual(top, expected) {
+		t.Errorf("TopTerms = %q, want %q", top, expected)
+	}
+}
+
+func TestIsLabelTerm(t *testing.T) {
+	for term, expected := range map[string]bool{
+		"garden": true, "the": false, "ok": false, "#garden": false, "2024": false, "1.2.3": false, "covid-19": true,
+	} {
+		if IsLabelTerm(term) != expected {
+			t.Errorf("IsLabelTerm(%q) = %v, want %v", term, !expected, expected)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package ranking
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// stopwords are common English words that never describe a group of notes
+var stopwords = toSet(`a about above after again against all also am an and any are as at be because been
+before being below between both but by can could did do does doing down during each few for from
+further had has have having he her here hers herself him himself his how i if in into is it its itself
+just like may me might more most much must my myself no nor not now of off on once one only or other
+our ours ourselves out over own same she should so some such than that the their theirs them
+themselves then there these they this those through to too under until up use used using very was
+we were what when where which while who whom why will with would you your yours yourself yourselves`)
+
+func toSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
+
+// IsLabelTerm reports whether a term can describe a group of notes: it is
+// not a stopword, a number or a #tag and has at least three characters
+func IsLabelTerm(term string) bool {
+	if utf8.RuneCountInString(term) < 3 || stopwords[term] || strings.HasPrefix(term, "#") {
+		return false
+	}
+	for _, r := range term {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// TopTerms returns up to n terms per group that are most frequent in it
+// relative to the other groups, by TF-IDF with the groups as documents.
+// Counts are the term frequencies of each group.
+func TopTerms(groups []map[string]int, n int) [][]string {
+	df := make(map[string]int)
+	nonEmpty := 0
+	for _, counts := range groups {
+		if len(counts) > 0 {
+			nonEmpty++
+		}
+		for term, count := range counts {
+			if count > 0 {
+				df[term]++
+			}
+		}
+	}
+
+	top := make([][]string, len(groups))
+	for i, counts := range groups {
+		total := 0
+		for _, count := range counts {
+			total += count
+		}
+		if total == 0 {
+			continue
+		}
+
+		type scored struct {
+			term  string
+			score float64
+		}
+		var candidates []scored
+		for term, count := range counts {
+			if count <= 0 || !IsLabelTerm(term) {
+				continue
+			}
+			// Smoothed so that terms found in every group, as with a single
+			// group, still count
+			idf := math.Log(float64(1+nonEmpty) / float64(df[term]))
+			candidates = append(candidates, scored{term, float64(count) / float64(total) * idf})
+		}
+		sort.Slice(candidates, func(a, b int) bool {
+			if candidates[a].score != candidates[b].score {
+				return candidates[a].score > candidates[b].score
+			}
+			return candidates[a].term < candidates[b].term
+		})
+
+		for j := 0; j < len(candidates) && j < n; j++ {
+			top[i] = append(top[i], candidates[j].term)
+		}
+	}
+	return top
+}
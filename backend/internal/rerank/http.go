@@ -0,0 +1,91 @@
+package rerank
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPReranker calls a cross-encoder behind a Cohere-style rerank API, as
+// offered by Cohere, Jina and self-hosted inference servers
+type HTTPReranker struct {
+	url    string
+	apiKey string
+	model  string
+	client *http.Client
+}
+
+func NewHTTPReranker(baseURL, apiKey, model string) *HTTPReranker {
+	url := strings.TrimRight(baseURL, "/")
+	if !strings.HasSuffix(url, "/rerank") {
+		url += "/rerank"
+	}
+	return &HTTPReranker{
+		url:    url,
+		apiKey: apiKey,
+		model:  model,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (r *HTTPReranker) Rerank(ctx context.Context, query string, passages []string) ([]float64, error) {
+	if len(passages) == 0 {
+		return nil, nil
+	}
+
+	reqBody := map[string]interface{}{
+		"query":     query,
+		"documents": passages,
+		"top_n":     len(passages),
+	}
+	if r.model != "" {
+		reqBody["model"] = r.model
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", r.url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	if r.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+r.apiKey)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rerank request failed with status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Results []struct {
+			Index          int     `json:"index"`
+			RelevanceScore float64 `json:"relevance_score"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	scores := make([]float64, len(passages))
+	for _, item := range result.Results {
+		if item.Index < 0 || item.Index >= len(passages) {
+			return nil, fmt.Errorf("rerank result index %d out of range", item.Index)
+		}
+		scores[item.Index] = item.RelevanceScore
+	}
+	return scores, nil
+}
@@ -0,0 +1,90 @@
+package rerank
+
+import (
+	"context"
+	"strings"
+
+	"zettelkasten/internal/ranking"
+)
+
+// LexicalReranker scores passages by how many query terms they contain,
+// their BM25 weight within the candidate set and whether they contain the
+// query as a phrase. It needs no external service.
+type LexicalReranker struct{}
+
+func NewLexicalReranker() *LexicalReranker {
+	return &LexicalReranker{}
+}
+
+// Weights of the lexical score components, which sum to 1
+const (
+	coverageWeight = 0.6
+	bm25Weight     = 0.3
+	phraseWeight   = 0.1
+)
+
+func (r *LexicalReranker) Rerank(ctx context.Context, query string, passages []string) ([]float64, error) {
+	scores := make([]float64, len(passages))
+
+	queryTerms := uniqueTerms(query)
+	if len(queryTerms) == 0 {
+		return scores, nil
+	}
+	phrase := strings.Join(ranking.Terms(query), " ")
+
+	frequencies := make([]map[string]int, len(passages))
+	lengths := make([]int, len(passages))
+	df := make(map[string]int)
+	totalLength := 0
+	for i, passage := range passages {
+		frequencies[i], lengths[i] = ranking.TermFrequencies(passage)
+		totalLength += lengths[i]
+		for _, term := range queryTerms {
+			if frequencies[i][term] > 0 {
+				df[term]++
+			}
+		}
+	}
+	avgLength := float64(totalLength) / float64(len(passages))
+
+	bm25 := make([]float64, len(passages))
+	maxBM25 := 0.0
+	for i := range passages {
+		for _, term := range queryTerms {
+			bm25[i] += ranking.BM25(frequencies[i][term], lengths[i], avgLength, ranking.IDF(df[term], len(passages)))
+		}
+		if bm25[i] > maxBM25 {
+			maxBM25 = bm25[i]
+		}
+	}
+
+	for i, passage := range passages {
+		matched := 0
+		for _, term := range queryTerms {
+			if frequencies[i][term] > 0 {
+				matched++
+			}
+		}
+		score := coverageWeight * float64(matched) / float64(len(queryTerms))
+		if maxBM25 > 0 {
+			score += bm25Weight * bm25[i] / maxBM25
+		}
+		if len(queryTerms) > 1 && strings.Contains(strings.Join(ranking.Terms(passage), " "), phrase) {
+			score += phraseWeight
+		}
+		scores[i] = score
+	}
+	return scores, nil
+}
+
+func uniqueTerms(text string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, term := range ranking.Terms(text) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
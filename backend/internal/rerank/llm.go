@@ -0,0 +1,68 @@
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"zettelkasten/internal/llm"
+)
+
+// maxPassageBytes truncates passages sent to the model for scoring
+const maxPassageBytes = 1500
+
+const rerankPrompt = `You judge search results. For each numbered passage, rate from 0 to 10 how well it answers the query,
+where 10 is a direct and complete answer and 0 is unrelated.
+Reply with a JSON object of the form {"scores":[7,0,3]} with one score per passage, in order.`
+
+// LLMReranker asks a chat model to score passages
+type LLMReranker struct {
+	provider llm.Provider
+}
+
+func NewLLMReranker(provider llm.Provider) *LLMReranker {
+	return &LLMReranker{provider: provider}
+}
+
+func (r *LLMReranker) Rerank(ctx context.Context, query string, passages []string) ([]float64, error) {
+	if len(passages) == 0 {
+		return nil, nil
+	}
+
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Query: %s\n\n", query)
+	for i, passage := range passages {
+		if len(passage) > maxPassageBytes {
+			passage = strings.ToValidUTF8(passage[:maxPassageBytes], "")
+		}
+		fmt.Fprintf(&prompt, "[%d]\n%s\n\n", i+1, passage)
+	}
+
+	resp, err := r.provider.Complete(ctx, llm.Request{
+		Messages: []llm.Message{
+			{Role: "system", Content: rerankPrompt},
+			{Role: "user", Content: prompt.String()},
+		},
+		JSON: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var reply struct {
+		Scores []float64 `json:"scores"`
+	}
+	if err := json.Unmarshal([]byte(resp.Content), &reply); err != nil {
+		return nil, fmt.Errorf("invalid model response: %w", err)
+	}
+	if len(reply.Scores) != len(passages) {
+		return nil, fmt.Errorf("expected %d scores, got %d", len(passages), len(reply.Scores))
+	}
+
+	scores := make([]float64, len(passages))
+	for i, score := range reply.Scores {
+		scores[i] = score / 10
+	}
+	return scores, nil
+}
@@ -0,0 +1,36 @@
+// Package rerank scores query-passage pairs to reorder search candidates
+package rerank
+
+import (
+	"context"
+	"fmt"
+
+	"zettelkasten/internal/llm"
+)
+
+// Reranker scores how well each passage answers the query. Scores are
+// returned in passage order; higher is more relevant.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, passages []string) ([]float64, error)
+}
+
+// New returns the reranker named by kind: "http" for a reranker API at
+// baseURL, "llm" to let the model score passages, or "lexical"
+func New(kind, baseURL, apiKey, model string, provider llm.Provider) (Reranker, error) {
+	switch kind {
+	case "lexical", "":
+		return NewLexicalReranker(), nil
+	case "http":
+		if baseURL == "" {
+			return nil, fmt.Errorf("the http reranker needs a URL")
+		}
+		return NewHTTPReranker(baseURL, apiKey, model), nil
+	case "llm":
+		if provider == nil {
+			return nil, fmt.Errorf("the llm reranker needs an LLM provider")
+		}
+		return NewLLMReranker(provider), nil
+	default:
+		return nil, fmt.Errorf("unknown reranker %q", kind)
+	}
+}
@@ -0,0 +1,67 @@
+package rerank
+
+import (
+	"context"
+	"testing"
+
+	"zettelkasten/internal/llm"
+)
+
+var passages = []string{
+	"Gardening tips for the spring season.",
+	"The server logged ERR_CONN_RESET after the proxy restarted.",
+	"A connection reset means the peer closed the socket.",
+}
+
+func TestLexicalReranker(t *testing.T) {
+	scores, err := NewLexicalReranker().Rerank(context.Background(), "ERR_CONN_RESET proxy", passages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scores) != len(passages) {
+		t.Fatalf("got %d scores, want %d", len(scores), len(passages))
+	}
+	if scores[1] <= scores[0] || scores[1] <= scores[2] {
+		t.Errorf("exact match should rank first: %v", scores)
+	}
+	if scores[0] != 0 {
+		t.Errorf("unrelated passage scored %f", scores[0])
+	}
+	for _, score := range scores {
+		if score < 0 || score > 1 {
+			t.Errorf("score %f out of range", score)
+		}
+	}
+}
+
+func TestLexicalRerankerPhrase(t *testing.T) {
+	scores, _ := NewLexicalReranker().Rerank(context.Background(), "connection reset", []string{
+		"reset the connection pool",
+		"a connection reset by peer",
+	})
+	if scores[1] <= scores[0] {
+		t.Errorf("phrase match should rank first: %v", scores)
+	}
+}
+
+func TestLLMReranker(t *testing.T) {
+	scores, err := NewLLMReranker(llm.NewStubProvider()).Rerank(context.Background(), "connection reset", passages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scores[2] != 1 || scores[0] != 0 {
+		t.Errorf("unexpected scores %v", scores)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New("http", "", "", "", nil); err == nil {
+		t.Error("expected an error for an http reranker without a URL")
+	}
+	if _, err := New("llm", "", "", "", nil); err == nil {
+		t.Error("expected an error for an llm reranker without a provider")
+	}
+	if _, err := New("lexical", "", "", "", nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"zettelkasten/internal/llm"
+	"zettelkasten/internal/models"
+	"zettelkasten/internal/tokenizer"
+)
+
+// Answer defaults
+const (
+	DefaultAnswerSources       = 8
+	DefaultAnswerContextTokens = 3000
+	DefaultAnswerMaxTokens     = 800
+	DefaultAnswerTemperature   = 0.2
+)
+
+const answerPrompt = `You answer questions using only the user's notes.
+You are given numbered sources. Cite every statement with the number of the source it comes from, e.g. [1] or [2][3].
+If the sources do not contain the answer, say so instead of guessing.`
+
+// noSourcesAnswer is returned without calling the model when the search
+// found nothing
+const noSourcesAnswer = "I could not find anything about this in your notes."
+
+// citationPattern matches citation markers such as [2]
+var citationPattern = regexp.MustCompile(`\[(\d+)\]`)
+
+// AnswerService answers questions from the chunks found by search
+type AnswerService struct {
+	searchService *SearchService
+	llm           llm.Provider
+	tokenizer     tokenizer.Tokenizer
+}
+
+func NewAnswerService(searchService *SearchService, llmProvider llm.Provider, tok tokenizer.Tokenizer) *AnswerService {
+	return &AnswerService{
+		searchService: searchService,
+		llm:           llmProvider,
+		tokenizer:     tok,
+	}
+}
+
+type AnswerRequest struct {
+	Query        string        `json:"query"`
+	SearchParams SearchRequest `json:"search_params"`
+	LLMParams    LLMParams     `json:"llm_params"`
+}
+
+type LLMParams struct {
+	MaxTokens   int      `json:"max_tokens"`
+	Temperature *float64 `json:"temperature"`
+	// ContextTokens bounds the source text sent to the model
+	ContextTokens int `json:"context_tokens"`
+}
+
+type AnswerResponse struct {
+	LLMResponse string `json:"llm_response"`
+	// Citations are the sources the answer cites, by number
+	Citations []models.Citation `json:"citations"`
+	// SourcesUsed are all the sources given to the model, numbered from 1
+	SourcesUsed []SearchResult `json:"sources_used"`
+	TokensUsed  int            `json:"tokens_used"`
+	Usage       llm.Usage      `json:"usage"`
+}
+
+// Answer searches the user's notes and asks the model to answer from the
+// top results
+func (s *AnswerService) Answer(ctx context.Context, userID string, req AnswerRequest) (*AnswerResponse, error) {
+	return s.StreamAnswer(ctx, userID, req, nil, nil)
+}
+
+// StreamAnswer answers like Answer, passing the sources to onSources once
+// retrieved and each piece of the answer to onToken as the model produces
+// it. Either callback may be nil; an error from one stops the answer.
+func (s *AnswerService) StreamAnswer(ctx context.Context, userID string, req AnswerRequest, onSources func([]SearchResult) error, onToken llm.TokenFunc) (*AnswerResponse, error) {
+	if s.llm == nil {
+		return nil, ErrNoLLMProvider
+	}
+
+	search := req.SearchParams
+	if search.Query == "" {
+		search.Query = req.Query
+	}
+	if search.Limit == 0 {
+		search.Limit = DefaultAnswerSources
+	}
+	searchResp, err := s.searchService.Search(ctx, userID, search)
+	if err != nil {
+		return nil, err
+	}
+
+	budget := req.LLMParams.ContextTokens
+	if budget <= 0 {
+		budget = DefaultAnswerContextTokens
+	}
+	sources := s.contextSources(searchResp.Results, budget)
+	if onSources != nil {
+		if err := onSources(sources); err != nil {
+			return nil, err
+		}
+	}
+
+	resp := &AnswerResponse{
+		Citations:   []models.Citation{},
+		SourcesUsed: sources,
+	}
+	if len(sources) == 0 {
+		resp.LLMResponse = noSourcesAnswer
+		if onToken != nil {
+			if err := onToken(noSourcesAnswer); err != nil {
+				return nil, err
+			}
+		}
+		return resp, nil
+	}
+
+	completionReq := s.answerRequest(req, sources)
+	var completion *llm.Response
+	if onToken != nil {
+		completion, err = llm.Stream(ctx, s.llm, completionReq, onToken)
+	} else {
+		completion, err = s.llm.Complete(ctx, completionReq)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("answer generation failed: %w", err)
+	}
+	log.Printf("Answer used %d tokens (%d prompt, %d completion)",
+		completion.Usage.TotalTokens, completion.Usage.PromptTokens, completion.Usage.CompletionTokens)
+
+	resp.LLMResponse = completion.Content
+	resp.Citations = citations(completion.Content, sources)
+	resp.TokensUsed = completion.Usage.TotalTokens
+	resp.Usage = completion.Usage
+	return resp, nil
+}
+
+// answerRequest builds the model request for numbered sources
+func (s *AnswerService) answerRequest(req AnswerRequest, sources []SearchResult) llm.Request {
+	maxTokens := req.LLMParams.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = DefaultAnswerMaxTokens
+	}
+	temperature := DefaultAnswerTemperature
+	if req.LLMParams.Temperature != nil {
+		temperature = *req.LLMParams.Temperature
+	}
+
+	var passages strings.Builder
+	for i, source := range sources {
+		fmt.Fprintf(&passages, "[%d] %s\n%s\n\n", i+1, source.Source.Title, source.Content)
+	}
+
+	return llm.Request{
+		Messages: []llm.Message{
+			{Role: "system", Content: answerPrompt},
+			{Role: "user", Content: fmt.Sprintf("Sources:\n\n%sQuestion: %s", passages.String(), req.Query)},
+		},
+		MaxTokens:   maxTokens,
+		Temperature: &temperature,
+	}
+}
+
+// contextSources keeps the results, in order, whose content fits within
+// budget tokens. A result too large for the remaining budget is skipped so
+// that smaller ones after it can still be used.
+func (s *AnswerService) contextSources(results []SearchResult, budget int) []SearchResult {
+	sources := []SearchResult{}
+	used := 0
+	for _, result := range results {
+		n := s.tokenizer.Count(result.Source.Title) + s.tokenizer.Count(result.Content)
+		if used+n > budget {
+			continue
+		}
+		used += n
+		sources = append(sources, result)
+	}
+	return sources
+}
+
+// citations returns the sources cited in answer, ordered by number. Numbers
+// that do not match a source are ignored.
+func citations(answer string, sources []SearchResult) []models.Citation {
+	cited := []models.Citation{}
+	seen := make(map[int]bool)
+	for _, match := range citationPattern.FindAllStringSubmatch(answer, -1) {
+		number, err := strconv.Atoi(match[1])
+		if err != nil || number < 1 || number > len(sources) || seen[number] {
+			continue
+		}
+		seen[number] = true
+
+		source := sources[number-1]
+		cited = append(cited, models.Citation{
+			Number:     number,
+			ChunkID:    source.ID,
+			DocumentID: source.Source.DocumentID,
+			Title:      source.Source.Title,
+		})
+	}
+	sort.Slice(cited, func(i, j int) bool { return cited[i].Number < cited[j].Number })
+	return cited
+}
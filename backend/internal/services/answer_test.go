@@ -0,0 +1,81 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"zettelkasten/internal/models"
+	"zettelkasten/internal/tokenizer"
+)
+
+func TestCitations(t *testing.T) {
+	sources := []SearchResult{
+		{ID: "a_0", Source: SearchSource{DocumentID: "a", Title: "Alpha"}},
+		{ID: "b_2", Source: SearchSource{DocumentID: "b", Title: "Beta"}},
+		{ID: "c_1", Source: SearchSource{DocumentID: "c", Title: "Gamma"}},
+	}
+
+	tests := []struct {
+		name   string
+		answer string
+		want   []int
+	}{
+		{name: "No citations", answer: "Nothing cited.", want: []int{}},
+		{name: "Ordered by number", answer: "Later [3], earlier [1].", want: []int{1, 3}},
+		{name: "Repeated once", answer: "[2] and again [2][2].", want: []int{2}},
+		{name: "Out of range ignored", answer: "[0] [4] [12] [2]", want: []int{2}},
+		{name: "Adjacent brackets", answer: "Both [1][3].", want: []int{1, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cited := citations(tt.answer, sources)
+			numbers := []int{}
+			for _, citation := range cited {
+				numbers = append(numbers, citation.Number)
+			}
+			if !reflect.DeepEqual(numbers, tt.want) {
+				t.Fatalf("citations(%q) numbers = %v, want %v", tt.answer, numbers, tt.want)
+			}
+		})
+	}
+
+	cited := citations("See [2].", sources)
+	want := models.Citation{Number: 2, ChunkID: "b_2", DocumentID: "b", Title: "Beta"}
+	if len(cited) != 1 || cited[0] != want {
+		t.Errorf("citations() = %+v, want source 2 mapped to %+v", cited, want)
+	}
+}
+
+func TestContextSources(t *testing.T) {
+	// Each "abcd" is one estimated token
+	sized := func(id string, tokens int) SearchResult {
+		return SearchResult{ID: id, Content: strings.Repeat("abcd", tokens)}
+	}
+	service := &AnswerService{tokenizer: tokenizer.NewEstimator()}
+
+	tests := []struct {
+		name    string
+		results []SearchResult
+		budget  int
+		want    []string
+	}{
+		{name: "All fit", results: []SearchResult{sized("a", 10), sized("b", 10)}, budget: 20, want: []string{"a", "b"}},
+		{name: "Over budget skipped", results: []SearchResult{sized("a", 10), sized("b", 15), sized("c", 5)}, budget: 20, want: []string{"a", "c"}},
+		{name: "First too large", results: []SearchResult{sized("a", 30), sized("b", 10)}, budget: 20, want: []string{"b"}},
+		{name: "Nothing fits", results: []SearchResult{sized("a", 30)}, budget: 20, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := []string{}
+			for _, source := range service.contextSources(tt.results, tt.budget) {
+				ids = append(ids, source.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("contextSources() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"zettelkasten/internal/models"
+	"zettelkasten/internal/parsers"
+)
+
+// backlinkSnippetRadius is roughly how much text is shown on either side of
+// a link
+const backlinkSnippetRadius = 100
+
+// Backlink is a document linking to another, with the links found in its
+// chunks. References are missing for links that are not in the text, such
+// as those suggested with zettels.
+type Backlink struct {
+	DocumentID string              `json:"document_id"`
+	Title      string              `json:"title"`
+	SourceType string              `json:"source_type"`
+	References []BacklinkReference `json:"references"`
+}
+
+// BacklinkReference is one link in a chunk with the text around it
+type BacklinkReference struct {
+	ChunkID    string `json:"chunk_id"`
+	ChunkIndex int    `json:"chunk_index"`
+	LinkText   string `json:"link_text"`
+	Snippet    string `json:"snippet"`
+}
+
+type BacklinksResponse struct {
+	DocumentID string     `json:"document_id"`
+	Title      string     `json:"title"`
+	Backlinks  []Backlink `json:"backlinks"`
+}
+
+// UnresolvedLink is a link target that matches none of the user's
+// documents, with the documents linking to it
+type UnresolvedLink struct {
+	Target       string        `json:"target"`
+	ReferencedBy []DocumentRef `json:"referenced_by"`
+}
+
+type DocumentRef struct {
+	DocumentID string `bson:"document_id" json:"document_id"`
+	Title      string `bson:"title" json:"title"`
+}
+
+// Backlinks returns the documents whose wikilinks resolve to a document,
+// oldest first, with a snippet around each link
+func (s *GraphService) Backlinks(ctx context.Context, userID, documentID string) (*BacklinksResponse, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := s.graphDocuments(ctx, userObjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var target *models.Document
+	for i := range documents {
+		if documents[i].ID.Hex() == documentID {
+			target = &documents[i]
+		}
+	}
+	if target == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	byTitle := titleIndex(documents)
+	resolves := func(link string) bool {
+		return byTitle[parsers.NormalizeLinkTarget(link)] == documentID
+	}
+
+	backlinks := []Backlink{}
+	positions := make(map[string]int)
+	for _, doc := range documents {
+		id := doc.ID.Hex()
+		if id == documentID {
+			continue
+		}
+		for _, link := range doc.Links {
+			if resolves(link) {
+				positions[id] = len(backlinks)
+				backlinks = append(backlinks, Backlink{
+					DocumentID: id,
+					Title:      doc.Title,
+					SourceType: doc.SourceType,
+					References: []BacklinkReference{},
+				})
+				break
+			}
+		}
+	}
+	if len(backlinks) == 0 {
+		return &BacklinksResponse{DocumentID: documentID, Title: target.Title, Backlinks: backlinks}, nil
+	}
+
+	ids := make([]string, 0, len(positions))
+	for id := range positions {
+		ids = append(ids, id)
+	}
+	chunks, err := s.keywordIndex.DocumentChunks(ctx, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, chunk := range chunks {
+		for _, link := range parsers.FindWikiLinks(chunk.Content) {
+			if !resolves(link.Target) {
+				continue
+			}
+			i := positions[chunk.DocumentID]
+			backlinks[i].References = append(backlinks[i].References, BacklinkReference{
+				ChunkID:    chunk.ID,
+				ChunkIndex: chunk.ChunkIndex,
+				LinkText:   chunk.Content[link.Start:link.End],
+				Snippet:    linkSnippet(chunk.Content, link.Start, link.End),
+			})
+		}
+	}
+	for _, backlink := range backlinks {
+		sort.SliceStable(backlink.References, func(i, j int) bool {
+			return backlink.References[i].ChunkIndex < backlink.References[j].ChunkIndex
+		})
+	}
+
+	return &BacklinksResponse{DocumentID: documentID, Title: target.Title, Backlinks: backlinks}, nil
+}
+
+// UnresolvedLinks returns the link targets of the user's documents that
+// match no document's title, most referenced first
+func (s *GraphService) UnresolvedLinks(ctx context.Context, userID string) ([]UnresolvedLink, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := s.graphDocuments(ctx, userObjectID)
+	if err != nil {
+		return nil, err
+	}
+	byTitle := titleIndex(documents)
+
+	unresolved := []UnresolvedLink{}
+	positions := make(map[string]int)
+	for _, doc := range documents {
+		for _, link := range doc.Links {
+			key := parsers.NormalizeLinkTarget(link)
+			if _, ok := byTitle[key]; ok {
+				continue
+			}
+			i, ok := positions[key]
+			if !ok {
+				i = len(unresolved)
+				positions[key] = i
+				unresolved = append(unresolved, UnresolvedLink{Target: link})
+			}
+			unresolved[i].ReferencedBy = append(unresolved[i].ReferencedBy, DocumentRef{
+				DocumentID: doc.ID.Hex(),
+				Title:      doc.Title,
+			})
+		}
+	}
+
+	sort.SliceStable(unresolved, func(i, j int) bool {
+		if len(unresolved[i].ReferencedBy) != len(unresolved[j].ReferencedBy) {
+			return len(unresolved[i].ReferencedBy) > len(unresolved[j].ReferencedBy)
+		}
+		return strings.ToLower(unresolved[i].Target) < strings.ToLower(unresolved[j].Target)
+	})
+	return unresolved, nil
+}
+
+// chunkIndex reads the index from a chunk ID of the form documentID_index
+func chunkIndex(chunkID string) int {
+	index, _ := strconv.Atoi(chunkID[strings.LastIndexByte(chunkID, '_')+1:])
+	return index
+}
+
+// linkSnippet returns the text around text[start:end], cut at whitespace
+func linkSnippet(text string, start, end int) string {
+	from := max(start-backlinkSnippetRadius, 0)
+	to := min(end+backlinkSnippetRadius, len(text))
+	if from > 0 {
+		if i := strings.IndexAny(text[from:start], " \t\n"); i >= 0 {
+			from += i + 1
+		} else {
+			from = start
+		}
+	}
+	if to < len(text) {
+		if i := strings.LastIndexAny(text[end:to], " \t\n"); i >= 0 {
+			to = end + i
+		} else {
+			to = end
+		}
+	}
+
+	snippet := strings.Join(strings.Fields(text[from:to]), " ")
+	if from > 0 {
+		snippet = "…" + snippet
+	}
+	if to < len(text) {
+		snippet += "…"
+	}
+	return snippet
+}
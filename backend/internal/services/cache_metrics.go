@@ -0,0 +1,87 @@
+package services
+
+import (
+	"log"
+	"strconv"
+
+	"zettelkasten/internal/database"
+)
+
+// Counters kept per user in a Redis hash
+const (
+	metricSearchHits      = "search_hits"
+	metricSearchMisses    = "search_misses"
+	metricSemanticHits    = "semantic_hits"
+	metricEmbeddingHits   = "embedding_hits"
+	metricEmbeddingMisses = "embedding_misses"
+	// metricEmbeddingTimeMs is the time spent embedding queries that were
+	// not cached
+	metricEmbeddingTimeMs = "embedding_time_ms"
+)
+
+// CacheMetrics counts how often the search caches save work
+type CacheMetrics struct {
+	redis *database.RedisClient
+}
+
+func NewCacheMetrics(redis *database.RedisClient) *CacheMetrics {
+	return &CacheMetrics{redis: redis}
+}
+
+// CacheStats summarizes a user's cache counters
+type CacheStats struct {
+	SearchHits      int64 `json:"search_hits"`
+	SearchMisses    int64 `json:"search_misses"`
+	SemanticHits    int64 `json:"semantic_hits"`
+	EmbeddingHits   int64 `json:"embedding_hits"`
+	EmbeddingMisses int64 `json:"embedding_misses"`
+	// EstimatedTimeSavedMs prices each embedding cache hit at the average
+	// time of the embedding calls made. Cached searches save more, as they
+	// skip retrieval as well.
+	EstimatedTimeSavedMs int64 `json:"estimated_time_saved_ms"`
+	// SearchHitRate is the share of searches served from the cache, by
+	// exact or semantic match
+	SearchHitRate float64 `json:"search_hit_rate"`
+}
+
+// Add increments one of the user's counters. It is safe to call on nil
+// metrics.
+func (m *CacheMetrics) Add(userID, metric string, n int64) {
+	if m == nil {
+		return
+	}
+	if err := m.redis.HIncrBy(cacheMetricsKey(userID), metric, n); err != nil {
+		log.Printf("Warning: Failed to record cache metric %s: %v", metric, err)
+	}
+}
+
+func (m *CacheMetrics) Stats(userID string) (CacheStats, error) {
+	values, err := m.redis.HGetAll(cacheMetricsKey(userID))
+	if err != nil {
+		return CacheStats{}, err
+	}
+	counter := func(metric string) int64 {
+		n, _ := strconv.ParseInt(values[metric], 10, 64)
+		return n
+	}
+
+	stats := CacheStats{
+		SearchHits:      counter(metricSearchHits),
+		SearchMisses:    counter(metricSearchMisses),
+		SemanticHits:    counter(metricSemanticHits),
+		EmbeddingHits:   counter(metricEmbeddingHits),
+		EmbeddingMisses: counter(metricEmbeddingMisses),
+	}
+	if stats.EmbeddingMisses > 0 {
+		stats.EstimatedTimeSavedMs = stats.EmbeddingHits * counter(metricEmbeddingTimeMs) / stats.EmbeddingMisses
+	}
+	hits := stats.SearchHits + stats.SemanticHits
+	if searches := hits + stats.SearchMisses; searches > 0 {
+		stats.SearchHitRate = float64(hits) / float64(searches)
+	}
+	return stats, nil
+}
+
+func cacheMetricsKey(userID string) string {
+	return "cache:metrics:" + userID
+}
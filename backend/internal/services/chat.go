@@ -0,0 +1,339 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zettelkasten/internal/llm"
+	"zettelkasten/internal/models"
+	"zettelkasten/internal/parsers"
+)
+
+// chatHistoryMessages bounds the earlier messages used to rewrite a
+// follow-up question
+const chatHistoryMessages = 6
+
+const rewritePrompt = `You rewrite follow-up questions for a search engine over the user's notes.
+Given the conversation so far and a follow-up question, reply with a single standalone search query
+that can be understood without the conversation. Reply with the query only.`
+
+// ErrChatNotFound is returned for sessions that do not exist or belong to
+// another user
+var ErrChatNotFound = errors.New("chat not found")
+
+// ChatService stores chat sessions and answers their turns from the user's
+// notes
+type ChatService struct {
+	db      *mongo.Database
+	answers *AnswerService
+	llm     llm.Provider
+}
+
+func NewChatService(mongodb *mongo.Client, answerService *AnswerService, llmProvider llm.Provider) *ChatService {
+	db := mongodb.Database("zettelkasten")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := db.Collection("chats").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "updated_at", Value: -1}},
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create chat index: %v", err)
+	}
+
+	return &ChatService{
+		db:      db,
+		answers: answerService,
+		llm:     llmProvider,
+	}
+}
+
+// ChatTurn is the result of sending a message
+type ChatTurn struct {
+	UserMessage      models.ChatMessage `json:"user_message"`
+	AssistantMessage models.ChatMessage `json:"assistant_message"`
+	SourcesUsed      []SearchResult     `json:"sources_used"`
+}
+
+func (s *ChatService) CreateSession(ctx context.Context, userID, title string, scope models.ChatScope) (*models.ChatSession, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &models.ChatSession{
+		UserID:    userObjectID,
+		Title:     strings.TrimSpace(title),
+		Scope:     normalizeChatScope(scope),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	result, err := s.db.Collection("chats").InsertOne(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	session.ID = result.InsertedID.(primitive.ObjectID)
+	return session, nil
+}
+
+// ListSessions returns the user's sessions without their messages, most
+// recently active first
+func (s *ChatService) ListSessions(ctx context.Context, userID string) ([]models.ChatSession, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	findOpts := options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+		SetProjection(bson.M{"messages": 0})
+	cursor, err := s.db.Collection("chats").Find(ctx, bson.M{"user_id": userObjectID}, findOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []models.ChatSession{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (s *ChatService) GetSession(ctx context.Context, userID, chatID string) (*models.ChatSession, error) {
+	filter, err := chatFilter(userID, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	var session models.ChatSession
+	err = s.db.Collection("chats").FindOne(ctx, filter).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrChatNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// UpdateSession renames a session and replaces its scope; nil values are
+// left unchanged
+func (s *ChatService) UpdateSession(ctx context.Context, userID, chatID string, title *string, scope *models.ChatScope) (*models.ChatSession, error) {
+	filter, err := chatFilter(userID, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	if title != nil {
+		set["title"] = strings.TrimSpace(*title)
+	}
+	if scope != nil {
+		set["scope"] = normalizeChatScope(*scope)
+	}
+
+	var session models.ChatSession
+	err = s.db.Collection("chats").FindOneAndUpdate(ctx, filter, bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{"messages": 0}),
+	).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrChatNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *ChatService) DeleteSession(ctx context.Context, userID, chatID string) error {
+	filter, err := chatFilter(userID, chatID)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.Collection("chats").DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrChatNotFound
+	}
+	return nil
+}
+
+// SendMessage answers a message in a session. The message is rewritten
+// into a standalone query using the earlier turns, searched within the
+// session's scope and answered with citations; both messages are stored.
+func (s *ChatService) SendMessage(ctx context.Context, userID, chatID, content string, searchParams SearchRequest, llmParams LLMParams) (*ChatTurn, error) {
+	session, err := s.GetSession(ctx, userID, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	standalone := s.standaloneQuery(ctx, session.Messages, content)
+
+	searchParams.Query = standalone
+	if len(session.Scope.DocumentIDs) > 0 {
+		searchParams.Filters.DocumentIDs = session.Scope.DocumentIDs
+	}
+	if len(session.Scope.Tags) > 0 {
+		searchParams.Filters.Tags = session.Scope.Tags
+	}
+
+	answer, err := s.answers.Answer(ctx, userID, AnswerRequest{
+		Query:        standalone,
+		SearchParams: searchParams,
+		LLMParams:    llmParams,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	turn := &ChatTurn{
+		UserMessage: models.ChatMessage{
+			Role:            "user",
+			Content:         content,
+			StandaloneQuery: standalone,
+			CreatedAt:       now,
+		},
+		AssistantMessage: models.ChatMessage{
+			Role:       "assistant",
+			Content:    answer.LLMResponse,
+			Citations:  answer.Citations,
+			TokensUsed: answer.TokensUsed,
+			CreatedAt:  now,
+		},
+		SourcesUsed: answer.SourcesUsed,
+	}
+
+	set := bson.M{"updated_at": now}
+	if session.Title == "" {
+		set["title"] = truncateString(content, 60)
+	}
+	_, err = s.db.Collection("chats").UpdateOne(ctx, bson.M{"_id": session.ID}, bson.M{
+		"$push": bson.M{"messages": bson.M{"$each": []models.ChatMessage{turn.UserMessage, turn.AssistantMessage}}},
+		"$set":  set,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return turn, nil
+}
+
+// standaloneQuery rewrites a follow-up question so that it can be searched
+// without the conversation. The question is used as is when it opens the
+// conversation or cannot be rewritten.
+func (s *ChatService) standaloneQuery(ctx context.Context, history []models.ChatMessage, question string) string {
+	if len(history) == 0 || s.llm == nil {
+		return question
+	}
+	if len(history) > chatHistoryMessages {
+		history = history[len(history)-chatHistoryMessages:]
+	}
+
+	var conversation strings.Builder
+	for _, message := range history {
+		speaker := "Assistant"
+		if message.Role == "user" {
+			speaker = "User"
+		}
+		fmt.Fprintf(&conversation, "%s: %s\n", speaker, message.Content)
+	}
+
+	resp, err := s.llm.Complete(ctx, llm.Request{
+		Messages: []llm.Message{
+			{Role: "system", Content: rewritePrompt},
+			{Role: "user", Content: fmt.Sprintf("Conversation:\n%s\nFollow-up: %s", conversation.String(), question)},
+		},
+		MaxTokens: 100,
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to rewrite follow-up question, searching it as asked: %v", err)
+		return question
+	}
+
+	standalone := strings.Trim(strings.TrimSpace(resp.Content), `"`)
+	if standalone == "" {
+		return question
+	}
+	return standalone
+}
+
+// ExportMarkdown renders a session as a Markdown transcript with the
+// sources cited by each answer
+func (s *ChatService) ExportMarkdown(ctx context.Context, userID, chatID string) (string, error) {
+	session, err := s.GetSession(ctx, userID, chatID)
+	if err != nil {
+		return "", err
+	}
+
+	title := session.Title
+	if title == "" {
+		title = "Untitled chat"
+	}
+
+	var md strings.Builder
+	fmt.Fprintf(&md, "# %s\n\n", title)
+	fmt.Fprintf(&md, "_Started %s_\n", session.CreatedAt.UTC().Format("2006-01-02 15:04 MST"))
+	if len(session.Scope.DocumentIDs) > 0 {
+		fmt.Fprintf(&md, "\n_Documents: %s_\n", strings.Join(session.Scope.DocumentIDs, ", "))
+	}
+	if len(session.Scope.Tags) > 0 {
+		fmt.Fprintf(&md, "\n_Tags: %s_\n", strings.Join(session.Scope.Tags, ", "))
+	}
+
+	for _, message := range session.Messages {
+		if message.Role == "user" {
+			fmt.Fprintf(&md, "\n## You\n\n%s\n", message.Content)
+			continue
+		}
+
+		fmt.Fprintf(&md, "\n## Assistant\n\n%s\n", message.Content)
+		if len(message.Citations) > 0 {
+			md.WriteString("\nSources:\n\n")
+			for _, citation := range message.Citations {
+				fmt.Fprintf(&md, "- [%d] %s (`%s`)\n", citation.Number, citation.Title, citation.ChunkID)
+			}
+		}
+	}
+	return md.String(), nil
+}
+
+func chatFilter(userID, chatID string) (bson.M, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+	chatObjectID, err := primitive.ObjectIDFromHex(chatID)
+	if err != nil {
+		return nil, ErrChatNotFound
+	}
+	return bson.M{"_id": chatObjectID, "user_id": userObjectID}, nil
+}
+
+func normalizeChatScope(scope models.ChatScope) models.ChatScope {
+	var documentIDs []string
+	for _, id := range scope.DocumentIDs {
+		if id = strings.TrimSpace(id); id != "" {
+			documentIDs = append(documentIDs, id)
+		}
+	}
+
+	normalized := models.ChatScope{DocumentIDs: documentIDs}
+	if tags := parsers.NormalizeTags(scope.Tags); len(tags) > 0 {
+		normalized.Tags = tags
+	}
+	return normalized
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"zettelkasten/internal/parsers"
+)
+
+// chunkLocations reads where a document's stored chunks appear in its
+// original file from their vector metadata. Chunks stored without a
+// location are left out.
+func (s *DocumentService) chunkLocations(documentID string, count int) (map[string]*ChunkLocation, error) {
+	ids := make([]string, count)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("%s_%d", documentID, i)
+	}
+
+	locations := make(map[string]*ChunkLocation, count)
+	for start := 0; start < len(ids); start += fetchBatchSize {
+		end := min(start+fetchBatchSize, len(ids))
+		stored, err := s.pinecone.Fetch(ids[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for id, vector := range stored {
+			if location := chunkLocation(vector.Metadata); location != nil {
+				locations[id] = location
+			}
+		}
+	}
+	return locations, nil
+}
+
+// remapChunkIDs maps the IDs of a document's previous chunks to the IDs of
+// its new chunks covering the same text of the original file, those
+// overlapping most first. Chunks without a location, or whose text no new
+// chunk covers, map to nothing.
+func remapChunkIDs(documentID string, previous map[string]*ChunkLocation, chunks []parsers.Chunk) map[string][]string {
+	mapping := make(map[string][]string, len(previous))
+	for id, old := range previous {
+		type overlap struct {
+			index, size int
+		}
+		var overlaps []overlap
+		for i, chunk := range chunks {
+			location := chunkLocation(chunk.Metadata)
+			if location == nil {
+				continue
+			}
+			if size := min(old.EndOffset, location.EndOffset) - max(old.StartOffset, location.StartOffset); size > 0 {
+				overlaps = append(overlaps, overlap{index: i, size: size})
+			}
+		}
+		sort.SliceStable(overlaps, func(i, j int) bool { return overlaps[i].size > overlaps[j].size })
+
+		ids := make([]string, len(overlaps))
+		for i, o := range overlaps {
+			ids[i] = fmt.Sprintf("%s_%d", documentID, o.index)
+		}
+		mapping[id] = ids
+	}
+	return mapping
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"zettelkasten/internal/parsers"
+)
+
+func TestRemapChunkIDs(t *testing.T) {
+	located := func(start, end int) parsers.Chunk {
+		return parsers.Chunk{Metadata: map[string]interface{}{
+			parsers.StartOffsetKey: start,
+			parsers.EndOffsetKey:   end,
+		}}
+	}
+	chunks := []parsers.Chunk{
+		located(0, 100),
+		located(100, 250),
+		{Content: "not located"},
+		located(250, 400),
+	}
+
+	previous := map[string]*ChunkLocation{
+		"doc_0": {StartOffset: 0, EndOffset: 80},
+		"doc_1": {StartOffset: 80, EndOffset: 260},
+		"doc_2": {StartOffset: 500, EndOffset: 600},
+	}
+
+	mapping := remapChunkIDs("doc", previous, chunks)
+
+	expected := map[string][]string{
+		"doc_0": {"doc_0"},
+		"doc_1": {"doc_1", "doc_0", "doc_3"},
+		"doc_2": {},
+	}
+	if !reflect.DeepEqual(mapping, expected) {
+		t.Errorf("remapChunkIDs() = %v, want %v", mapping, expected)
+	}
+}
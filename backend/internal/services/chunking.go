@@ -0,0 +1,112 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"zettelkasten/internal/models"
+	"zettelkasten/internal/parsers"
+)
+
+// chunkSizePresets map UserPreferences.ChunkSizePreference to chunk sizes
+var chunkSizePresets = map[string]parsers.ChunkingConfig{
+	"small":  {MinTokens: 64, MaxTokens: 256, Overlap: 32},
+	"medium": {MinTokens: parsers.DefaultMinTokens, MaxTokens: parsers.DefaultMaxTokens},
+	"large":  {MinTokens: 384, MaxTokens: 1536, Overlap: 64},
+}
+
+// IsValidChunkSizePreference reports whether preference names a preset
+func IsValidChunkSizePreference(preference string) bool {
+	_, ok := chunkSizePresets[preference]
+	return ok || preference == "custom"
+}
+
+// ValidatePreferences checks the chunking fields of a preferences update
+func ValidatePreferences(preferences map[string]interface{}) error {
+	data, err := json.Marshal(preferences)
+	if err != nil {
+		return err
+	}
+	var prefs models.UserPreferences
+	if err := json.Unmarshal(data, &prefs); err != nil {
+		return fmt.Errorf("invalid preferences: %w", err)
+	}
+
+	if prefs.ChunkSizePreference != "" && !IsValidChunkSizePreference(prefs.ChunkSizePreference) {
+		return fmt.Errorf("chunk_size_preference must be small, medium, large or custom")
+	}
+	if prefs.ChunkSizePreference == "custom" && prefs.CustomChunking == nil {
+		return fmt.Errorf("custom_chunking is required for the custom chunk size preference")
+	}
+	if prefs.CustomChunking != nil {
+		if err := chunkingConfig(*prefs.CustomChunking).Validate(); err != nil {
+			return fmt.Errorf("invalid custom_chunking: %w", err)
+		}
+	}
+	return nil
+}
+
+// resolveChunking starts from the user's chunk size preference, or preset
+// when given, and applies the non-zero fields of override. defaultStrategy is
+// used when neither the preset nor override chooses a strategy.
+func resolveChunking(prefs models.UserPreferences, preset string, override parsers.ChunkingConfig, defaultStrategy string) (models.ChunkingSettings, error) {
+	if preset == "" {
+		preset = prefs.ChunkSizePreference
+	}
+
+	var config parsers.ChunkingConfig
+	switch {
+	case preset == "custom" && prefs.CustomChunking != nil:
+		config = chunkingConfig(*prefs.CustomChunking)
+	case chunkSizePresets[preset] != (parsers.ChunkingConfig{}):
+		config = chunkSizePresets[preset]
+	default:
+		preset = "medium"
+		config = chunkSizePresets[preset]
+	}
+
+	if override.Strategy != "" {
+		config.Strategy = override.Strategy
+	}
+	if override.MinTokens != 0 {
+		config.MinTokens = override.MinTokens
+	}
+	if override.MaxTokens != 0 {
+		config.MaxTokens = override.MaxTokens
+		// Keep the rest of the preset compatible with a smaller maximum
+		if override.MinTokens == 0 && config.MinTokens > config.MaxTokens {
+			config.MinTokens = config.MaxTokens
+		}
+		if override.Overlap == 0 && config.Overlap >= config.MaxTokens {
+			config.Overlap = 0
+		}
+	}
+	if override.Overlap != 0 {
+		config.Overlap = override.Overlap
+	}
+	if config.Strategy == "" {
+		config.Strategy = defaultStrategy
+	}
+
+	config = config.WithDefaults()
+	if err := config.Validate(); err != nil {
+		return models.ChunkingSettings{}, err
+	}
+
+	return models.ChunkingSettings{
+		Preset:    preset,
+		Strategy:  config.Strategy,
+		MinTokens: config.MinTokens,
+		MaxTokens: config.MaxTokens,
+		Overlap:   config.Overlap,
+	}, nil
+}
+
+func chunkingConfig(settings models.ChunkingSettings) parsers.ChunkingConfig {
+	return parsers.ChunkingConfig{
+		Strategy:  settings.Strategy,
+		MinTokens: settings.MinTokens,
+		MaxTokens: settings.MaxTokens,
+		Overlap:   settings.Overlap,
+	}
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"testing"
+
+	"zettelkasten/internal/models"
+	"zettelkasten/internal/parsers"
+)
+
+func TestResolveChunking(t *testing.T) {
+	custom := models.UserPreferences{
+		ChunkSizePreference: "custom",
+		CustomChunking:      &models.ChunkingSettings{Strategy: parsers.StrategySentence, MinTokens: 50, MaxTokens: 200},
+	}
+
+	tests := []struct {
+		name            string
+		prefs           models.UserPreferences
+		preset          string
+		override        parsers.ChunkingConfig
+		defaultStrategy string
+		wantStrategy    string
+		wantErr         bool
+	}{
+		{name: "Source default", preset: "medium", defaultStrategy: parsers.StrategyMarkdown, wantStrategy: parsers.StrategyMarkdown},
+		{name: "Override wins", preset: "medium", override: parsers.ChunkingConfig{Strategy: parsers.StrategyFixed}, defaultStrategy: parsers.StrategyMarkdown, wantStrategy: parsers.StrategyFixed},
+		{name: "Custom preference wins", prefs: custom, defaultStrategy: parsers.StrategyMarkdown, wantStrategy: parsers.StrategySentence},
+		{name: "Overlap beyond the preset maximum", preset: "small", override: parsers.ChunkingConfig{Overlap: 300}, wantErr: true},
+		{name: "Minimum beyond the custom maximum", prefs: custom, override: parsers.ChunkingConfig{MinTokens: 300}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := resolveChunking(tt.prefs, tt.preset, tt.override, tt.defaultStrategy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveChunking() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && settings.Strategy != tt.wantStrategy {
+				t.Errorf("resolveChunking() strategy = %q, want %q", settings.Strategy, tt.wantStrategy)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -14,11 +16,14 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"zettelkasten/internal/database"
+	"zettelkasten/internal/llm"
 	"zettelkasten/internal/models"
 	"zettelkasten/internal/parsers"
+	"zettelkasten/internal/tokenizer"
 )
 
 type DocumentService struct {
@@ -26,15 +31,25 @@ type DocumentService struct {
 	pinecone         *database.PineconeClient
 	embeddingService *EmbeddingService
 	eventService     *EventService
+	tokenizer        tokenizer.Tokenizer
+	llm              llm.Provider
+	keywords         *KeywordIndex
+	searchCache      *SearchCache
+	links            *LinkService
 }
 
 // NewDocumentService constructor
-func NewDocumentService(mongodb *mongo.Client, pinecone *database.PineconeClient, embeddingService *EmbeddingService, eventService *EventService) *DocumentService {
+func NewDocumentService(mongodb *mongo.Client, pinecone *database.PineconeClient, embeddingService *EmbeddingService, eventService *EventService, tok tokenizer.Tokenizer, llmProvider llm.Provider, keywords *KeywordIndex, searchCache *SearchCache, links *LinkService) *DocumentService {
 	return &DocumentService{
 		db:               mongodb.Database("zettelkasten"),
 		pinecone:         pinecone,
 		embeddingService: embeddingService,
 		eventService:     eventService,
+		tokenizer:        tok,
+		llm:              llmProvider,
+		keywords:         keywords,
+		searchCache:      searchCache,
+		links:            links,
 	}
 }
 
@@ -51,44 +66,272 @@ func (s *DocumentService) ProcessUpload(ctx context.Context, userID string, file
 	return job, nil
 }
 
-func (s *DocumentService) ProcessFile(ctx context.Context, jobID, userID string, file io.Reader, filename, sourceType string) error {
+// ProcessOptions carries per-upload settings for ProcessFile
+type ProcessOptions struct {
+	// OriginalPath replaces the path reported by the parser, e.g. the path
+	// of a file inside an uploaded archive
+	OriginalPath string `json:"original_path,omitempty"`
+	// ChunkSizePreference replaces the user's chunk size preference
+	ChunkSizePreference string `json:"chunk_size_preference,omitempty"`
+	// Chunking overrides individual fields of the chunk size preference
+	Chunking parsers.ChunkingConfig `json:"chunking"`
+	// Mode is ModeZettel to also extract atomic notes from the document
+	Mode string `json:"mode,omitempty"`
+}
+
+// ErrSourceUnavailable is returned when rechunking a document whose
+// original file was not kept
+var ErrSourceUnavailable = errors.New("original file is not available for this document")
+
+func (s *DocumentService) ProcessFile(ctx context.Context, jobID, userID string, file io.Reader, filename, sourceType string, opts ProcessOptions) error {
 	log.Printf("Starting document processing for file: %s (Job: %s)", filename, jobID)
 
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	return s.processData(ctx, jobID, userID, data, filename, sourceType, opts, nil)
+}
+
+// StoreUpload keeps an uploaded file in GridFS until a job processes it
+// with ProcessStoredFile, and returns its file ID
+func (s *DocumentService) StoreUpload(userID, filename, sourceType string, data []byte) (string, error) {
+	id, err := s.storeSource(userID, filename, sourceType, data)
+	if err != nil {
+		return "", err
+	}
+	return id.Hex(), nil
+}
+
+// DiscardUpload removes a file stored with StoreUpload that will not be
+// processed
+func (s *DocumentService) DiscardUpload(ctx context.Context, fileID string) error {
+	id, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return err
+	}
+	return s.deleteSource(ctx, id)
+}
+
+// ProcessStoredFile is ProcessFile for a file stored with StoreUpload. The
+// stored file is kept as the original file of the documents that can be
+// rechunked and removed otherwise.
+func (s *DocumentService) ProcessStoredFile(ctx context.Context, jobID, userID, fileID, filename, sourceType string, opts ProcessOptions) error {
+	id, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return err
+	}
+	data, _, err := s.loadSource(id)
+	if err != nil {
+		return fmt.Errorf("failed to load uploaded file: %w", err)
+	}
+	defer func() {
+		if err := s.deleteSource(context.Background(), id); err != nil {
+			log.Printf("Warning: Failed to delete uploaded file %s: %v", fileID, err)
+		}
+	}()
+
+	return s.processData(ctx, jobID, userID, data, filename, sourceType, opts, &id)
+}
+
+// processData parses and stores the documents of an uploaded file. storedID
+// is the GridFS file already holding data, if any.
+func (s *DocumentService) processData(ctx context.Context, jobID, userID string, data []byte, filename, sourceType string, opts ProcessOptions, storedID *primitive.ObjectID) error {
 	// Select parser based on source type
-	var parser parsers.Parser
-	switch sourceType {
-	case "notion":
-		parser = parsers.NewNotionParser()
-	case "obsidian":
-		parser = parsers.NewObsidianParser()
-	case "roam":
-		parser = parsers.NewRoamParser()
-	case "logseq":
-		parser = parsers.NewLogseqParser()
-	default:
-		parser = parsers.NewStandardParser()
-	}
-
-	chunks, metadata, err := parser.Parse(file, filename)
+	parser := parsers.NewParser(sourceType)
+	chunking, err := s.configureChunker(ctx, parser, userID, parsers.DefaultStrategy(sourceType, filename), opts)
+	if err != nil {
+		return err
+	}
+
+	documents, err := parsers.ParseDocuments(parser, bytes.NewReader(data), filename)
 	if err != nil {
 		return fmt.Errorf("parsing failed: %w", err)
 	}
 
+	// Keep the original file of chunked documents so they can be rechunked
+	var sourceFileID *primitive.ObjectID
+	if chunking != nil && storedID != nil {
+		sourceFileID = storedID
+	} else if chunking != nil {
+		id, err := s.storeSource(userID, filename, sourceType, data)
+		if err != nil {
+			log.Printf("Warning: Failed to store original file %s, it will not be possible to rechunk it: %v", filename, err)
+		} else {
+			sourceFileID = &id
+		}
+	}
+
+	for i, parsed := range documents {
+		if parsed.Metadata == nil {
+			parsed.Metadata = make(map[string]interface{})
+		}
+		if opts.OriginalPath != "" {
+			parsed.Metadata["original_path"] = opts.OriginalPath
+		}
+		parsers.LocateChunks(data, parsed.Chunks)
+
+		doc := &models.Document{
+			Title:        getTitle(parsed.Metadata, filename),
+			SourceType:   sourceType,
+			Metadata:     parsed.Metadata,
+			Tags:         documentTags(parsed.Metadata),
+			Links:        documentLinks(parsed.Chunks),
+			Chunking:     chunking,
+			SourceFileID: sourceFileID,
+			SourceIndex:  i,
+		}
+		if err := s.storeDocument(ctx, userID, doc, parsed.Chunks); err != nil {
+			return err
+		}
+
+		// The document is stored and searchable whether or not notes can
+		// be extracted from it
+		if opts.Mode == ModeZettel {
+			if err := s.extractZettels(ctx, userID, doc, parsed.Chunks); err != nil {
+				log.Printf("Warning: Failed to extract zettels from document %s: %v", doc.ID.Hex(), err)
+			}
+		}
+	}
+
+	// Emit job completed event
+	if s.eventService != nil {
+		s.eventService.JobCompleted(userID, jobID)
+	}
+
+	return nil
+}
+
+// configureChunker sets up the chunker of parsers that support configurable
+// chunking from the user's preferences and opts, falling back to
+// defaultStrategy, and returns the settings used. Parsers with fixed chunking
+// return nil settings.
+func (s *DocumentService) configureChunker(ctx context.Context, parser parsers.Parser, userID, defaultStrategy string, opts ProcessOptions) (*models.ChunkingSettings, error) {
+	setter, ok := parser.(parsers.ChunkerSetter)
+	if !ok {
+		return nil, nil
+	}
+
+	chunker, settings, err := s.newChunker(ctx, userID, defaultStrategy, opts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chunking options: %w", err)
+	}
+	setter.SetChunker(chunker)
+
+	return &settings, nil
+}
+
+// newChunker builds the chunker for the user's preferences merged with opts
+func (s *DocumentService) newChunker(ctx context.Context, userID, defaultStrategy string, opts ProcessOptions) (parsers.Chunker, models.ChunkingSettings, error) {
+	settings, err := resolveChunking(s.userPreferences(ctx, userID), opts.ChunkSizePreference, opts.Chunking, defaultStrategy)
+	if err != nil {
+		return nil, settings, err
+	}
+	chunker, err := parsers.NewChunker(chunkingConfig(settings), s.tokenizer, s.embeddingService)
+	return chunker, settings, err
+}
+
+// CheckChunking reports an error if a file of sourceType named filename
+// cannot be chunked with opts once they are merged with the user's
+// preferences, so that such uploads are rejected before they are queued.
+// Parsers with fixed chunking ignore the options.
+func (s *DocumentService) CheckChunking(ctx context.Context, userID, sourceType, filename string, opts ProcessOptions) error {
+	if _, ok := parsers.NewParser(sourceType).(parsers.ChunkerSetter); !ok {
+		return nil
+	}
+	_, _, err := s.newChunker(ctx, userID, parsers.DefaultStrategy(sourceType, filename), opts)
+	return err
+}
+
+// CheckRechunking is CheckChunking for rechunking doc
+func (s *DocumentService) CheckRechunking(ctx context.Context, userID string, doc *models.Document, opts ProcessOptions) error {
+	filename := doc.Title
+	if originalPath, _ := doc.Metadata["original_path"].(string); originalPath != "" {
+		filename = originalPath
+	}
+	return s.CheckChunking(ctx, userID, doc.SourceType, filename, opts)
+}
+
+// userPreferences loads the preferences of a user, falling back to the
+// defaults when the user cannot be found
+func (s *DocumentService) userPreferences(ctx context.Context, userID string) models.UserPreferences {
 	userObjectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
+		return models.UserPreferences{}
+	}
+
+	var user models.User
+	if err := s.db.Collection("users").FindOne(ctx, bson.M{"_id": userObjectID}).Decode(&user); err != nil {
+		return models.UserPreferences{}
+	}
+	return user.Preferences
+}
+
+// sources stores original uploads in GridFS
+func (s *DocumentService) sources() (*gridfs.Bucket, error) {
+	return gridfs.NewBucket(s.db, options.GridFSBucket().SetName("sources"))
+}
+
+func (s *DocumentService) storeSource(userID, filename, sourceType string, data []byte) (primitive.ObjectID, error) {
+	bucket, err := s.sources()
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	uploadOpts := options.GridFSUpload().SetMetadata(bson.M{
+		"user_id":     userID,
+		"source_type": sourceType,
+	})
+	return bucket.UploadFromStream(filename, bytes.NewReader(data), uploadOpts)
+}
+
+// loadSource returns the contents and filename of an original upload
+func (s *DocumentService) loadSource(fileID primitive.ObjectID) ([]byte, string, error) {
+	bucket, err := s.sources()
+	if err != nil {
+		return nil, "", err
+	}
+
+	stream, err := bucket.OpenDownloadStream(fileID)
+	if err != nil {
+		return nil, "", err
+	}
+	defer stream.Close()
+
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, stream.GetFile().Name, nil
+}
+
+// deleteSource removes an original upload once no document refers to it
+func (s *DocumentService) deleteSource(ctx context.Context, fileID primitive.ObjectID) error {
+	count, err := s.db.Collection("documents").CountDocuments(ctx, bson.M{"source_file_id": fileID})
+	if err != nil || count > 0 {
 		return err
 	}
 
-	doc := &models.Document{
-		UserID:     userObjectID,
-		Title:      getTitle(metadata, filename),
-		SourceType: sourceType,
-		ChunkCount: len(chunks),
-		UploadedAt: time.Now(),
-		Metadata:   metadata,
-		Status:     "processing_chunks",
+	bucket, err := s.sources()
+	if err != nil {
+		return err
+	}
+	return bucket.Delete(fileID)
+}
+
+// storeDocument creates the record for one parsed document and embeds its
+// chunks
+func (s *DocumentService) storeDocument(ctx context.Context, userID string, doc *models.Document, chunks []parsers.Chunk) error {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
 	}
 
+	doc.UserID = userObjectID
+	doc.ChunkCount = len(chunks)
+	doc.UploadedAt = time.Now()
+	doc.Status = "processing_chunks"
+
 	result, err := s.db.Collection("documents").InsertOne(ctx, doc)
 	if err != nil {
 		return err
@@ -96,48 +339,163 @@ func (s *DocumentService) ProcessFile(ctx context.Context, jobID, userID string,
 
 	// Set the document ID and emit document created event
 	doc.ID = result.InsertedID.(primitive.ObjectID)
-	docID := doc.ID.Hex()
-	log.Printf("Created document record with ID: %s for file: %s", docID, filename)
+	log.Printf("Created document record with ID: %s for file: %s", doc.ID.Hex(), doc.Title)
 
 	// Emit document created event
 	if s.eventService != nil {
 		s.eventService.DocumentCreated(userID, doc)
 	}
 
-	// Process chunks with embeddings
-	log.Printf("Processing %d chunks for file: %s", len(chunks), filename)
+	if err := s.embedChunks(ctx, userID, doc, chunks); err != nil {
+		return err
+	}
+
+	_, err = s.db.Collection("documents").UpdateOne(
+		ctx,
+		bson.M{"_id": doc.ID},
+		bson.M{"$set": bson.M{"status": "completed"}},
+	)
+
+	return err
+}
+
+// embedChunks embeds the chunks of doc and stores them in Pinecone
+func (s *DocumentService) embedChunks(ctx context.Context, userID string, doc *models.Document, chunks []parsers.Chunk) error {
+	// Cached searches may miss or show stale chunks, even if only some
+	// were stored
+	defer s.searchCache.Invalidate(userID)
+
+	log.Printf("Processing %d chunks for document: %s", len(chunks), doc.Title)
+	originalPath, _ := doc.Metadata["original_path"].(string)
 	for i, chunk := range chunks {
-		log.Printf("Processing chunk %d/%d for file: %s", i+1, len(chunks), filename)
-		err := s.processChunk(ctx, userID, docID, chunk, i, sourceType)
+		log.Printf("Processing chunk %d/%d for document: %s", i+1, len(chunks), doc.Title)
+		chunk.Metadata = withDocumentMetadata(chunk.Metadata, doc.Title, originalPath, doc.Tags)
+		err := s.processChunk(ctx, userID, doc.ID.Hex(), chunk, i, doc.SourceType)
 		if err != nil {
-			log.Printf("Failed to process chunk %d/%d for file %s: %v", i+1, len(chunks), filename, err)
+			log.Printf("Failed to process chunk %d/%d for document %s: %v", i+1, len(chunks), doc.Title, err)
 			return err
 		}
 	}
 
-	log.Printf("Successfully processed all %d chunks for file: %s", len(chunks), filename)
+	log.Printf("Successfully processed all %d chunks for document: %s", len(chunks), doc.Title)
+	s.links.QueueSuggestions(userID, doc.ID.Hex(), len(chunks))
+	return nil
+}
 
-	_, err = s.db.Collection("documents").UpdateOne(
-		ctx,
-		bson.M{"_id": result.InsertedID},
-		bson.M{"$set": bson.M{"status": "completed"}},
-	)
+// RechunkDocument splits a document again from its original file with new
+// chunking settings and replaces its embeddings
+func (s *DocumentService) RechunkDocument(ctx context.Context, userID, documentID string, opts ProcessOptions) error {
+	doc, err := s.GetDocument(ctx, userID, documentID)
+	if err != nil {
+		return err
+	}
+	if doc.SourceFileID == nil {
+		return ErrSourceUnavailable
+	}
 
-	// Emit job completed event
-	if err == nil && s.eventService != nil {
-		s.eventService.JobCompleted(userID, jobID)
+	data, filename, err := s.loadSource(*doc.SourceFileID)
+	if err != nil {
+		return fmt.Errorf("failed to load original file: %w", err)
 	}
 
-	return err
+	parser := parsers.NewParser(doc.SourceType)
+	chunking, err := s.configureChunker(ctx, parser, userID, parsers.DefaultStrategy(doc.SourceType, filename), opts)
+	if err != nil {
+		return err
+	}
+	if chunking == nil {
+		return fmt.Errorf("%s documents cannot be rechunked", doc.SourceType)
+	}
+
+	documents, err := parsers.ParseDocuments(parser, bytes.NewReader(data), filename)
+	if err != nil {
+		return fmt.Errorf("parsing failed: %w", err)
+	}
+	if doc.SourceIndex >= len(documents) {
+		return fmt.Errorf("document %d not found in original file", doc.SourceIndex)
+	}
+	chunks := documents[doc.SourceIndex].Chunks
+	parsers.LocateChunks(data, chunks)
+
+	// Documents stored before tags were normalized only have them in their
+	// metadata
+	if doc.Tags == nil {
+		doc.Tags = documentTags(doc.Metadata)
+	}
+	doc.Links = documentLinks(chunks)
+
+	collection := s.db.Collection("documents")
+	if _, err := collection.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$set": bson.M{"status": "processing_chunks"}}); err != nil {
+		return err
+	}
+
+	// The previous chunks are located before their IDs are reused, so
+	// that references to them can follow their text
+	previous, err := s.chunkLocations(documentID, doc.ChunkCount)
+	if err != nil {
+		log.Printf("Warning: Failed to read the chunks of document %s, references to them will be cleared: %v", documentID, err)
+	}
+
+	if err := s.embedChunks(ctx, userID, doc, chunks); err != nil {
+		collection.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$set": bson.M{"status": "failed"}})
+		return err
+	}
+
+	mapping := remapChunkIDs(documentID, previous, chunks)
+	if err := s.remapZettelChunks(ctx, userID, documentID, mapping); err != nil {
+		log.Printf("Warning: Failed to update the source chunks of zettels of document %s: %v", documentID, err)
+	}
+	if err := s.links.RemapDocumentChunks(ctx, userID, documentID, mapping); err != nil {
+		log.Printf("Warning: Failed to update the chunks of links of document %s: %v", documentID, err)
+	}
+
+	// Chunk IDs are reused by index, so only chunks beyond the new count
+	// are left over from the previous settings
+	var stale []string
+	for i := len(chunks); i < doc.ChunkCount; i++ {
+		stale = append(stale, fmt.Sprintf("%s_%d", documentID, i))
+	}
+	if len(stale) > 0 {
+		if err := s.deleteVectors(documentID, len(chunks), doc.ChunkCount); err != nil {
+			log.Printf("Warning: Failed to delete %d stale chunks of document %s: %v", len(stale), documentID, err)
+		}
+		if s.keywords != nil {
+			if err := s.keywords.DeleteChunks(ctx, stale); err != nil {
+				log.Printf("Warning: Failed to remove %d stale chunks of document %s from the keyword index: %v", len(stale), documentID, err)
+			}
+		}
+		s.searchCache.Invalidate(userID)
+	}
+
+	doc.ChunkCount = len(chunks)
+	doc.Chunking = chunking
+	doc.Status = "completed"
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$set": bson.M{
+		"chunk_count": doc.ChunkCount,
+		"chunking":    doc.Chunking,
+		"status":      doc.Status,
+		"tags":        doc.Tags,
+		"links":       doc.Links,
+	}})
+	if err != nil {
+		return err
+	}
+
+	if s.eventService != nil {
+		s.eventService.DocumentsUpdated(userID, []models.Document{*doc})
+	}
+	return nil
 }
 
-func (s *DocumentService) processChunk(ctx context.Context, userID, docID, content string, index int, sourceType string) error {
+func (s *DocumentService) processChunk(ctx context.Context, userID, docID string, chunk parsers.Chunk, index int, sourceType string) error {
+	content := chunk.Content
 	log.Printf("=== Processing Chunk %d ===", index+1)
 	log.Printf("Chunk content preview (first 200 chars): %s", truncateString(content, 200))
 	log.Printf("Chunk word count: %d", countWords(content))
 
-	// Generate embedding
-	embedding, err := s.embeddingService.GenerateEmbedding(content)
+	// Generate embedding, including the heading breadcrumb in the input
+	// while storing the clean content
+	embedding, err := s.embeddingService.GenerateEmbedding(parsers.EmbeddingInput(chunk))
 	if err != nil {
 		log.Printf("Failed to generate embedding for chunk %d: %v", index+1, err)
 		return err
@@ -165,6 +523,13 @@ func (s *DocumentService) processChunk(ctx context.Context, userID, docID, conte
 		},
 	}
 
+	// Chunk-level metadata from the parser never overrides the fields above
+	for key, value := range chunk.Metadata {
+		if _, exists := vector.Metadata[key]; !exists {
+			vector.Metadata[key] = value
+		}
+	}
+
 	err = s.pinecone.Upsert([]database.Vector{vector})
 	if err != nil {
 		log.Printf("Failed to store embedding in Pinecone for chunk %d: %v", index+1, err)
@@ -172,11 +537,102 @@ func (s *DocumentService) processChunk(ctx context.Context, userID, docID, conte
 	}
 
 	log.Printf("Successfully stored chunk %d in Pinecone with ID: %s", index+1, chunkID)
+
+	// Keyword search is secondary, so a failure only degrades it
+	if s.keywords != nil {
+		if err := s.keywords.IndexChunk(ctx, userID, docID, chunkID, vector.Metadata); err != nil {
+			log.Printf("Warning: Failed to add chunk %s to the keyword index: %v", chunkID, err)
+		}
+	}
 	log.Printf("=== End Chunk %d ===\n", index+1)
 
 	return nil
 }
 
+// withDocumentMetadata adds the document title and source path to a chunk's
+// metadata so search results can show where a chunk came from, and merges
+// the document's tags into the chunk's so that search can filter on them
+func withDocumentMetadata(metadata map[string]interface{}, title, originalPath string, tags []string) map[string]interface{} {
+	merged := make(map[string]interface{}, len(metadata)+3)
+	for key, value := range metadata {
+		merged[key] = value
+	}
+	if _, exists := merged["title"]; !exists {
+		merged["title"] = title
+	}
+	if _, exists := merged["original_path"]; !exists && originalPath != "" {
+		merged["original_path"] = originalPath
+	}
+	merged[parsers.TagsKey] = parsers.NormalizeTags(append(parsers.MetadataTags(metadata), tags...))
+	return merged
+}
+
+// documentTags returns the normalized tags found in document metadata,
+// which may have been decoded from MongoDB
+func documentTags(metadata map[string]interface{}) []string {
+	if tags, ok := metadata[parsers.TagsKey].(primitive.A); ok {
+		metadata = map[string]interface{}{parsers.TagsKey: []interface{}(tags)}
+	}
+	return parsers.NormalizeTags(parsers.MetadataTags(metadata))
+}
+
+// documentLinks returns the targets of the wikilinks in a document's chunks
+func documentLinks(chunks []parsers.Chunk) []string {
+	var links []string
+	for _, chunk := range chunks {
+		links = appendLinks(links, parsers.ExtractWikiLinks(chunk.Content)...)
+	}
+	return links
+}
+
+// appendLinks adds link targets that are not yet in links, comparing them
+// as parsers.NormalizeLinkTarget does
+func appendLinks(links []string, targets ...string) []string {
+	seen := make(map[string]bool, len(links))
+	for _, link := range links {
+		seen[parsers.NormalizeLinkTarget(link)] = true
+	}
+	for _, target := range targets {
+		key := parsers.NormalizeLinkTarget(target)
+		if key != "" && !seen[key] {
+			seen[key] = true
+			links = append(links, target)
+		}
+	}
+	return links
+}
+
+// TagCount is a tag and the number of documents carrying it
+type TagCount struct {
+	Tag           string `bson:"_id" json:"tag"`
+	DocumentCount int    `bson:"count" json:"document_count"`
+}
+
+// ListTags returns the user's tags, most used first
+func (s *DocumentService) ListTags(ctx context.Context, userID string) ([]TagCount, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := s.db.Collection("documents").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userObjectID}}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tags := []TagCount{}
+	if err := cursor.All(ctx, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
 // Helper function to truncate strings for logging
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -281,22 +737,103 @@ func (s *DocumentService) DeleteDocument(ctx context.Context, userID, documentID
 	}
 
 	// Delete from MongoDB
-	_, err = s.db.Collection("documents").DeleteOne(ctx, bson.M{
+	var doc models.Document
+	err = s.db.Collection("documents").FindOneAndDelete(ctx, bson.M{
 		"_id":     docObjectID,
 		"user_id": userObjectID,
-	})
+	}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		err = nil
+	}
+
+	if err == nil && doc.SourceFileID != nil {
+		if err := s.deleteSource(ctx, *doc.SourceFileID); err != nil {
+			log.Printf("Warning: Failed to delete original file of document %s: %v", documentID, err)
+		}
+	}
+
+	if err == nil && doc.ChunkCount > 0 {
+		if err := s.deleteVectors(documentID, 0, doc.ChunkCount); err != nil {
+			log.Printf("Warning: Failed to delete the vectors of document %s: %v", documentID, err)
+		}
+	}
+
+	if err == nil && s.keywords != nil {
+		if err := s.keywords.DeleteDocument(ctx, userID, documentID); err != nil {
+			log.Printf("Warning: Failed to remove document %s from the keyword index: %v", documentID, err)
+		}
+	}
+
+	if err == nil {
+		if err := s.links.DeleteDocumentLinks(ctx, userID, documentID); err != nil {
+			log.Printf("Warning: Failed to delete links of document %s: %v", documentID, err)
+		}
+		s.searchCache.Invalidate(userID)
+	}
 
 	if err == nil && s.eventService != nil {
 		// Emit document deleted event
 		s.eventService.DocumentDeleted(userID, documentID)
 	}
 
-	// TODO: Delete vectors from Pinecone
-	// This would require implementing a delete by metadata filter in Pinecone
+	// Notes extracted from the document go with it
+	if err == nil {
+		zettels, err := s.ListZettels(ctx, userID, documentID)
+		if err != nil {
+			log.Printf("Warning: Failed to list zettels of document %s: %v", documentID, err)
+		}
+		for _, zettel := range zettels {
+			if err := s.DeleteDocument(ctx, userID, zettel.ID.Hex()); err != nil {
+				log.Printf("Warning: Failed to delete zettel %s of document %s: %v", zettel.ID.Hex(), documentID, err)
+			}
+		}
+	}
 
 	return err
 }
 
+// vectorDeleteBatchSize bounds the IDs sent in one delete request
+const vectorDeleteBatchSize = 1000
+
+// deleteVectors deletes the vectors of a document's chunks from index from
+// up to, not including, index to
+func (s *DocumentService) deleteVectors(documentID string, from, to int) error {
+	for start := from; start < to; start += vectorDeleteBatchSize {
+		end := min(start+vectorDeleteBatchSize, to)
+		ids := make([]string, 0, end-start)
+		for i := start; i < end; i++ {
+			ids = append(ids, fmt.Sprintf("%s_%d", documentID, i))
+		}
+		if err := s.pinecone.Delete(ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetDocument returns a document owned by the user
+func (s *DocumentService) GetDocument(ctx context.Context, userID, documentID string) (*models.Document, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	docObjectID, err := primitive.ObjectIDFromHex(documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc models.Document
+	err = s.db.Collection("documents").FindOne(ctx, bson.M{
+		"_id":     docObjectID,
+		"user_id": userObjectID,
+	}).Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
 func (s *DocumentService) GetDocumentChunks(ctx context.Context, userID, documentID string) ([]models.Chunk, error) {
 	// Verify user owns the document
 	userObjectID, err := primitive.ObjectIDFromHex(userID)
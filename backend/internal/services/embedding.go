@@ -4,13 +4,29 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
+
+	"zettelkasten/internal/embeddings"
+	"zettelkasten/internal/tokenizer"
+)
+
+// embeddingModel is the OpenAI model used for chunks and queries
+const embeddingModel = "text-embedding-3-small"
+
+// Bounds of a single embeddings request. The API allows 2048 inputs and
+// about 300k tokens; token counts are estimated, so their budget leaves
+// room for the estimate falling short.
+const (
+	maxBatchInputs = 2048
+	maxBatchTokens = 200000
 )
 
 type EmbeddingService struct {
-	apiKey string
-	client *http.Client
+	apiKey  string
+	client  *http.Client
+	offline *embeddings.OfflineEmbedder
 }
 
 func NewEmbeddingService(apiKey string) *EmbeddingService {
@@ -20,10 +36,79 @@ func NewEmbeddingService(apiKey string) *EmbeddingService {
 	}
 }
 
+// NewOfflineEmbeddingService returns an embedding service backed by the
+// deterministic offline embedder, for local development and tests
+func NewOfflineEmbeddingService(dimensions int) *EmbeddingService {
+	return &EmbeddingService{
+		offline: embeddings.NewOfflineEmbedder(dimensions),
+	}
+}
+
+// Model names the model embeddings come from, so that embeddings of
+// different models are never mixed
+func (s *EmbeddingService) Model() string {
+	if s.offline != nil {
+		return fmt.Sprintf("offline-%d", s.offline.Dimensions())
+	}
+	return embeddingModel
+}
+
 func (s *EmbeddingService) GenerateEmbedding(text string) ([]float32, error) {
+	if s.offline != nil {
+		return s.offline.GenerateEmbedding(text)
+	}
+
+	embeddings, err := s.requestEmbeddings(text)
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// GenerateEmbeddings embeds several texts, in as few API requests as the
+// API's bounds on inputs and tokens allow
+func (s *EmbeddingService) GenerateEmbeddings(texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	if s.offline != nil {
+		return s.offline.GenerateEmbeddings(texts)
+	}
+
+	embeddings := make([][]float32, 0, len(texts))
+	for _, batch := range embeddingBatches(texts, tokenizer.NewEstimator()) {
+		batchEmbeddings, err := s.requestEmbeddings(batch)
+		if err != nil {
+			return nil, err
+		}
+		if len(batchEmbeddings) != len(batch) {
+			return nil, fmt.Errorf("expected %d embeddings, got %d", len(batch), len(batchEmbeddings))
+		}
+		embeddings = append(embeddings, batchEmbeddings...)
+	}
+	return embeddings, nil
+}
+
+// embeddingBatches splits texts into consecutive batches within the bounds
+// of a single request
+func embeddingBatches(texts []string, tok tokenizer.Tokenizer) [][]string {
+	var batches [][]string
+	start, tokens := 0, 0
+	for i, text := range texts {
+		count := tok.Count(text)
+		if i > start && (i-start == maxBatchInputs || tokens+count > maxBatchTokens) {
+			batches = append(batches, texts[start:i])
+			start, tokens = i, 0
+		}
+		tokens += count
+	}
+	return append(batches, texts[start:])
+}
+
+func (s *EmbeddingService) requestEmbeddings(input interface{}) ([][]float32, error) {
 	reqBody := map[string]interface{}{
-		"input": text,
-		"model": "text-embedding-3-small",
+		"input": input,
+		"model": embeddingModel,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -45,8 +130,14 @@ func (s *EmbeddingService) GenerateEmbedding(text string) ([]float32, error) {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("embeddings request failed with status %d: %s", resp.StatusCode, body)
+	}
+
 	var result struct {
 		Data []struct {
+			Index     int       `json:"index"`
 			Embedding []float32 `json:"embedding"`
 		} `json:"data"`
 	}
@@ -59,5 +150,14 @@ func (s *EmbeddingService) GenerateEmbedding(text string) ([]float32, error) {
 		return nil, fmt.Errorf("no embedding returned")
 	}
 
-	return result.Data[0].Embedding, nil
+	// The API may return embeddings out of order for batched input
+	embeddings := make([][]float32, len(result.Data))
+	for _, item := range result.Data {
+		if item.Index < 0 || item.Index >= len(embeddings) {
+			return nil, fmt.Errorf("embedding index %d out of range", item.Index)
+		}
+		embeddings[item.Index] = item.Embedding
+	}
+
+	return embeddings, nil
 }
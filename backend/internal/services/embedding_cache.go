@@ -0,0 +1,88 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"zettelkasten/internal/database"
+)
+
+// queryEmbeddingTTL is how long a query embedding is cached. Embeddings of
+// a model do not change, so this only bounds the cache's size.
+const queryEmbeddingTTL = 7 * 24 * time.Hour
+
+// EmbeddingCache caches query embeddings in Redis by model and normalized
+// query text
+type EmbeddingCache struct {
+	redis            *database.RedisClient
+	embeddingService *EmbeddingService
+	metrics          *CacheMetrics
+}
+
+func NewEmbeddingCache(redis *database.RedisClient, embeddingService *EmbeddingService, metrics *CacheMetrics) *EmbeddingCache {
+	return &EmbeddingCache{
+		redis:            redis,
+		embeddingService: embeddingService,
+		metrics:          metrics,
+	}
+}
+
+// Embed returns the embedding of a query, reporting whether it was cached
+func (c *EmbeddingCache) Embed(userID, text string) ([]float32, bool, error) {
+	key := c.key(text)
+	if cached, err := c.redis.Get(key); err == nil {
+		if embedding, ok := decodeEmbedding(cached); ok {
+			c.metrics.Add(userID, metricEmbeddingHits, 1)
+			return embedding, true, nil
+		}
+	}
+
+	start := time.Now()
+	embedding, err := c.embeddingService.GenerateEmbedding(text)
+	if err != nil {
+		return nil, false, err
+	}
+	c.metrics.Add(userID, metricEmbeddingMisses, 1)
+	c.metrics.Add(userID, metricEmbeddingTimeMs, time.Since(start).Milliseconds())
+
+	if err := c.redis.Set(key, encodeEmbedding(embedding), queryEmbeddingTTL); err != nil {
+		log.Printf("Warning: Failed to cache query embedding: %v", err)
+	}
+	return embedding, false, nil
+}
+
+func (c *EmbeddingCache) key(text string) string {
+	sum := sha256.Sum256([]byte(normalizeQueryText(text)))
+	return "embedding:" + c.embeddingService.Model() + ":" + hex.EncodeToString(sum[:])
+}
+
+// normalizeQueryText ignores case and spacing, which barely move an
+// embedding
+func normalizeQueryText(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+// encodeEmbedding packs an embedding as little-endian float32s
+func encodeEmbedding(embedding []float32) []byte {
+	data := make([]byte, 4*len(embedding))
+	for i, value := range embedding {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(value))
+	}
+	return data
+}
+
+func decodeEmbedding(data string) ([]float32, bool) {
+	if len(data) == 0 || len(data)%4 != 0 {
+		return nil, false
+	}
+	embedding := make([]float32, len(data)/4)
+	for i := range embedding {
+		embedding[i] = math.Float32frombits(binary.LittleEndian.Uint32([]byte(data[4*i : 4*i+4])))
+	}
+	return embedding, true
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"zettelkasten/internal/tokenizer"
+)
+
+func TestEmbeddingBatches(t *testing.T) {
+	// About 40% of the token budget, at two estimated tokens per word
+	long := strings.Repeat("word ", maxBatchTokens/5)
+	tests := []struct {
+		name  string
+		texts []string
+		sizes []int
+	}{
+		{
+			name:  "Single batch",
+			texts: []string{"one", "two", "three"},
+			sizes: []int{3},
+		},
+		{
+			name:  "Input count",
+			texts: make([]string, maxBatchInputs*2+1),
+			sizes: []int{maxBatchInputs, maxBatchInputs, 1},
+		},
+		{
+			name:  "Token budget",
+			texts: []string{long, long, long, "short"},
+			sizes: []int{2, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batches := embeddingBatches(tt.texts, tokenizer.NewEstimator())
+			var sizes []int
+			total := 0
+			for _, batch := range batches {
+				sizes = append(sizes, len(batch))
+				total += len(batch)
+			}
+			if len(sizes) != len(tt.sizes) {
+				t.Fatalf("batch sizes = %v, want %v", sizes, tt.sizes)
+			}
+			for i := range sizes {
+				if sizes[i] != tt.sizes[i] {
+					t.Fatalf("batch sizes = %v, want %v", sizes, tt.sizes)
+				}
+			}
+			if total != len(tt.texts) {
+				t.Errorf("batches hold %d texts, want %d", total, len(tt.texts))
+			}
+		})
+	}
+}
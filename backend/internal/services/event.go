@@ -37,6 +37,22 @@ func (s *EventService) DocumentsUpdated(userID string, documents []models.Docume
 	})
 }
 
+// LinksSuggested reports links suggested for a newly embedded document
+func (s *EventService) LinksSuggested(userID, documentID string, count int) {
+	s.hub.SendToUser(userID, "links:suggested", map[string]interface{}{
+		"document_id": documentID,
+		"count":       count,
+		"timestamp":   time.Now().Unix(),
+	})
+}
+
+// GraphInsightsReady reports that the user's graph insights were computed
+func (s *EventService) GraphInsightsReady(userID string) {
+	s.hub.SendToUser(userID, "graph:insights", map[string]interface{}{
+		"timestamp": time.Now().Unix(),
+	})
+}
+
 // Job Events
 func (s *EventService) JobProgressUpdate(userID, jobID string, progress int, status string) {
 	s.hub.SendToUser(userID, "job-progress", map[string]interface{}{
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zettelkasten/internal/graph"
+	"zettelkasten/internal/models"
+	"zettelkasten/internal/parsers"
+)
+
+// Bounds of the distance from a seed node
+const (
+	DefaultGraphDepth = 1
+	MaxGraphDepth     = 5
+)
+
+// tagEdgeLimit skips tags carried by more documents than this when
+// connecting documents by shared tags, as such tags say little about how
+// two notes relate and would connect every pair of them
+const tagEdgeLimit = 50
+
+// GraphService builds the knowledge graph of a user's notes from their
+// wikilinks, shared tags and accepted links
+type GraphService struct {
+	db *mongo.Database
+	// keywordIndex holds the chunk text that backlink snippets are cut from
+	keywordIndex *KeywordIndex
+}
+
+func NewGraphService(mongodb *mongo.Client, keywordIndex *KeywordIndex) *GraphService {
+	return &GraphService{
+		db:           mongodb.Database("zettelkasten"),
+		keywordIndex: keywordIndex,
+	}
+}
+
+// GraphOptions select the part of the graph to build
+type GraphOptions struct {
+	// Seed limits the graph to the nodes within Depth edges of a document,
+	// or of a chunk when chunks are included
+	Seed  string
+	Depth int
+	// EdgeTypes are the types of edges between notes to include, all of
+	// them when empty
+	EdgeTypes []string
+	// IncludeChunks adds the chunks of documents as nodes contained in
+	// them; accepted links then connect the chunks they were found between
+	IncludeChunks bool
+	// IncludeSuggested adds suggested links as edges of their own type
+	IncludeSuggested bool
+}
+
+// Graph returns the user's graph, ordered by distance from the seed or
+// else by degree
+func (s *GraphService) Graph(ctx context.Context, userID string, opts GraphOptions) (*graph.Graph, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := s.graphDocuments(ctx, userObjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	include := func(edgeType string) bool {
+		if len(opts.EdgeTypes) == 0 {
+			return true
+		}
+		for _, t := range opts.EdgeTypes {
+			if t == edgeType {
+				return true
+			}
+		}
+		return false
+	}
+
+	g := &graph.Graph{Nodes: []graph.Node{}, Edges: []graph.Edge{}}
+	nodes := make(map[string]bool)
+	for _, doc := range documents {
+		id := doc.ID.Hex()
+		g.Nodes = append(g.Nodes, graph.Node{
+			ID:         id,
+			Type:       graph.NodeDocument,
+			Label:      doc.Title,
+			SourceType: doc.SourceType,
+			Tags:       doc.Tags,
+		})
+		nodes[id] = true
+	}
+
+	if opts.IncludeChunks {
+		for _, doc := range documents {
+			for i := 0; i < doc.ChunkCount; i++ {
+				chunkID := fmt.Sprintf("%s_%d", doc.ID.Hex(), i)
+				g.Nodes = append(g.Nodes, graph.Node{
+					ID:         chunkID,
+					Type:       graph.NodeChunk,
+					Label:      fmt.Sprintf("%s #%d", doc.Title, i+1),
+					DocumentID: doc.ID.Hex(),
+					SourceType: doc.SourceType,
+				})
+				nodes[chunkID] = true
+				g.Edges = append(g.Edges, graph.Edge{
+					Source:   doc.ID.Hex(),
+					Target:   chunkID,
+					Type:     graph.EdgeContains,
+					Directed: true,
+					Weight:   1,
+				})
+			}
+		}
+	}
+
+	if include(graph.EdgeWikilink) {
+		g.Edges = append(g.Edges, wikilinkEdges(documents)...)
+	}
+	if include(graph.EdgeTag) {
+		g.Edges = append(g.Edges, tagEdges(documents)...)
+	}
+	if include(graph.EdgeSemantic) {
+		edges, err := s.semanticEdges(ctx, userObjectID, LinkAccepted, nodes, opts.IncludeChunks)
+		if err != nil {
+			return nil, err
+		}
+		g.Edges = append(g.Edges, edges...)
+	}
+	if opts.IncludeSuggested {
+		edges, err := s.semanticEdges(ctx, userObjectID, LinkSuggested, nodes, opts.IncludeChunks)
+		if err != nil {
+			return nil, err
+		}
+		g.Edges = append(g.Edges, edges...)
+	}
+
+	g.SetDegrees()
+	if opts.Seed != "" {
+		depth := opts.Depth
+		if depth <= 0 {
+			depth = DefaultGraphDepth
+		}
+		return g.Neighborhood(opts.Seed, min(depth, MaxGraphDepth))
+	}
+	g.SortByDegree()
+	return g, nil
+}
+
+// graphDocuments returns the fields of the user's documents that the graph
+// is built from, oldest first
+func (s *GraphService) graphDocuments(ctx context.Context, userObjectID primitive.ObjectID) ([]models.Document, error) {
+	findOpts := options.Find().
+		SetSort(bson.D{{Key: "uploaded_at", Value: 1}}).
+		SetProjection(bson.M{"title": 1, "source_type": 1, "tags": 1, "links": 1, "chunk_count": 1})
+	cursor, err := s.db.Collection("documents").Find(ctx, bson.M{"user_id": userObjectID}, findOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	documents := []models.Document{}
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// titleIndex maps the normalized titles of documents to their IDs. The
+// oldest of several documents with the same title wins.
+func titleIndex(documents []models.Document) map[string]string {
+	byTitle := make(map[string]string, len(documents))
+	for _, doc := range documents {
+		key := parsers.NormalizeLinkTarget(doc.Title)
+		if _, exists := byTitle[key]; !exists && key != "" {
+			byTitle[key] = doc.ID.Hex()
+		}
+	}
+	return byTitle
+}
+
+// wikilinkEdges connects documents to the documents their wikilinks resolve
+// to by title
+func wikilinkEdges(documents []models.Document) []graph.Edge {
+	byTitle := titleIndex(documents)
+
+	var edges []graph.Edge
+	seen := make(map[string]bool)
+	for _, doc := range documents {
+		source := doc.ID.Hex()
+		for _, link := range doc.Links {
+			target, ok := byTitle[parsers.NormalizeLinkTarget(link)]
+			if !ok || target == source || seen[source+":"+target] {
+				continue
+			}
+			seen[source+":"+target] = true
+			edges = append(edges, graph.Edge{
+				Source:   source,
+				Target:   target,
+				Type:     graph.EdgeWikilink,
+				Directed: true,
+				Weight:   1,
+				Label:    link,
+			})
+		}
+	}
+	return edges
+}
+
+// tagEdges connects every pair of documents sharing tags, weighted by the
+// number of tags they share
+func tagEdges(documents []models.Document) []graph.Edge {
+	byTag := make(map[string][]string)
+	for _, doc := range documents {
+		for _, tag := range doc.Tags {
+			byTag[tag] = append(byTag[tag], doc.ID.Hex())
+		}
+	}
+	tags := make([]string, 0, len(byTag))
+	for tag, ids := range byTag {
+		if len(ids) > 1 && len(ids) <= tagEdgeLimit {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+
+	var pairs []string
+	shared := make(map[string][]string)
+	for _, tag := range tags {
+		ids := byTag[tag]
+		for i := range ids {
+			for j := i + 1; j < len(ids); j++ {
+				pair := ids[i] + ":" + ids[j]
+				if _, exists := shared[pair]; !exists {
+					pairs = append(pairs, pair)
+				}
+				shared[pair] = append(shared[pair], tag)
+			}
+		}
+	}
+
+	edges := make([]graph.Edge, 0, len(pairs))
+	for _, pair := range pairs {
+		source, target, _ := strings.Cut(pair, ":")
+		edges = append(edges, graph.Edge{
+			Source: source,
+			Target: target,
+			Type:   graph.EdgeTag,
+			Weight: float64(len(shared[pair])),
+			Label:  strings.Join(shared[pair], ", "),
+		})
+	}
+	return edges
+}
+
+// semanticEdges connects the ends of the user's links with a status, the
+// chunks they were found between if those are nodes
+func (s *GraphService) semanticEdges(ctx context.Context, userObjectID primitive.ObjectID, status string, nodes map[string]bool, chunks bool) ([]graph.Edge, error) {
+	edgeType := graph.EdgeSemantic
+	if status == LinkSuggested {
+		edgeType = graph.EdgeSuggested
+	}
+
+	cursor, err := s.db.Collection("links").Find(ctx, bson.M{"user_id": userObjectID, "status": status})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var links []models.Link
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+
+	var edges []graph.Edge
+	for _, link := range links {
+		source, target := link.SourceDocumentID, link.TargetDocumentID
+		if chunks && nodes[link.SourceChunkID] && nodes[link.TargetChunkID] {
+			source, target = link.SourceChunkID, link.TargetChunkID
+		}
+		if !nodes[source] || !nodes[target] {
+			continue
+		}
+		edges = append(edges, graph.Edge{
+			Source: source,
+			Target: target,
+			Type:   edgeType,
+			Weight: float64(link.Score),
+		})
+	}
+	return edges, nil
+}
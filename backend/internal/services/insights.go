@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zettelkasten/internal/database"
+	"zettelkasten/internal/graph"
+	"zettelkasten/internal/ranking"
+)
+
+// insightsQueue is the Redis list of users whose graph insights are to be
+// computed
+const insightsQueue = "graph_insights_queue"
+
+// insightsPendingTTL bounds how long a queued user is not queued again,
+// should the job be lost
+const insightsPendingTTL = 10 * time.Minute
+
+// Analysis parameters and bounds of the reported lists
+const (
+	pageRankDamping    = 0.85
+	pageRankIterations = 100
+	insightsHubs       = 20
+	insightsBridges    = 20
+	clusterLabelTerms  = 3
+)
+
+// InsightsService analyzes the graph of each user's notes in the
+// background: hub notes by PageRank and degree, orphans without links, topic
+// clusters found by community detection over links and similar notes, and
+// the notes bridging those clusters
+type InsightsService struct {
+	db           *mongo.Database
+	redis        *database.RedisClient
+	graphService *GraphService
+	eventService *EventService
+}
+
+// GraphInsights are the stored results of a user's graph analysis
+type GraphInsights struct {
+	UserID        primitive.ObjectID `bson:"user_id" json:"-"`
+	DocumentCount int                `bson:"document_count" json:"document_count"`
+	EdgeCount     int                `bson:"edge_count" json:"edge_count"`
+	Hubs          []HubNote          `bson:"hubs" json:"hubs"`
+	// Orphans are documents without wikilinks or accepted links, in either
+	// direction
+	Orphans    []DocumentRef `bson:"orphans" json:"orphans"`
+	Clusters   []Cluster     `bson:"clusters" json:"clusters"`
+	Bridges    []BridgeNote  `bson:"bridges" json:"bridges"`
+	ComputedAt time.Time     `bson:"computed_at" json:"computed_at"`
+}
+
+type HubNote struct {
+	DocumentRef `bson:",inline"`
+	PageRank    float64 `bson:"pagerank" json:"pagerank"`
+	Degree      int     `bson:"degree" json:"degree"`
+	// DegreeCentrality is the degree over the number of other documents
+	DegreeCentrality float64 `bson:"degree_centrality" json:"degree_centrality"`
+}
+
+// Cluster is a group of related documents, labeled with the terms that set
+// it apart from the other clusters. Its documents are ordered by PageRank.
+type Cluster struct {
+	ID        int           `bson:"id" json:"id"`
+	Label     string        `bson:"label" json:"label"`
+	Terms     []string      `bson:"terms" json:"terms"`
+	Size      int           `bson:"size" json:"size"`
+	Documents []DocumentRef `bson:"documents" json:"documents"`
+}
+
+// BridgeNote is a document linked to clusters other than its own
+type BridgeNote struct {
+	DocumentRef `bson:",inline"`
+	Cluster     int   `bson:"cluster" json:"cluster"`
+	Clusters    []int `bson:"linked_clusters" json:"linked_clusters"`
+	// Weight is the total weight of its edges into other clusters
+	Weight float64 `bson:"weight" json:"weight"`
+}
+
+func NewInsightsService(mongodb *mongo.Client, redis *database.RedisClient, graphService *GraphService, eventService *EventService) *InsightsService {
+	db := mongodb.Database("zettelkasten")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := db.Collection("graph_insights").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create graph insights index: %v", err)
+	}
+
+	return &InsightsService{
+		db:           db,
+		redis:        redis,
+		graphService: graphService,
+		eventService: eventService,
+	}
+}
+
+// Queue queues the user's insights to be computed again, unless they
+// already are. It is safe to call on a nil service.
+func (s *InsightsService) Queue(userID string) {
+	if s == nil {
+		return
+	}
+	queued, err := s.redis.SetNX(insightsPendingKey(userID), 1, insightsPendingTTL)
+	if err != nil || !queued {
+		return
+	}
+	if err := s.redis.LPush(insightsQueue, userID); err != nil {
+		log.Printf("Warning: Failed to queue graph insights of user %s: %v", userID, err)
+		s.redis.Del(insightsPendingKey(userID))
+	}
+}
+
+// StartWithContext computes queued insights until ctx is done
+func (s *InsightsService) StartWithContext(ctx context.Context) {
+	log.Println("Starting graph insights processor...")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Graph insights processor shutting down...")
+			return
+		default:
+			jobData, err := s.redis.BRPop(2*time.Second, insightsQueue)
+			if err != nil {
+				if ctx.Err() != nil {
+					log.Println("Graph insights processor shutting down...")
+					return
+				}
+				time.Sleep(1 * time.Second)
+				continue
+			}
+
+			if len(jobData) < 2 {
+				continue
+			}
+			userID := jobData[1]
+
+			// Changes made from here on queue the user again
+			s.redis.Del(insightsPendingKey(userID))
+
+			if _, err := s.Compute(ctx, userID); err != nil {
+				log.Printf("Failed to compute graph insights of user %s: %v", userID, err)
+				continue
+			}
+			if s.eventService != nil {
+				s.eventService.GraphInsightsReady(userID)
+			}
+		}
+	}
+}
+
+// Get returns the user's latest insights, or mongo.ErrNoDocuments if they
+// were never computed
+func (s *InsightsService) Get(ctx context.Context, userID string) (*GraphInsights, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var insights GraphInsights
+	if err := s.db.Collection("graph_insights").FindOne(ctx, bson.M{"user_id": userObjectID}).Decode(&insights); err != nil {
+		return nil, err
+	}
+	return &insights, nil
+}
+
+// Compute analyzes the graph of the user's documents and stores the
+// results. Clusters are found over wikilinks and accepted and suggested
+// links, hubs and orphans over the first two only.
+func (s *InsightsService) Compute(ctx context.Context, userID string) (*GraphInsights, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := s.graphService.Graph(ctx, userID, GraphOptions{
+		EdgeTypes:        []string{graph.EdgeWikilink, graph.EdgeSemantic},
+		IncludeSuggested: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	titles := make(map[string]string, len(g.Nodes))
+	for _, node := range g.Nodes {
+		titles[node.ID] = node.Label
+	}
+	ref := func(id string) DocumentRef {
+		return DocumentRef{DocumentID: id, Title: titles[id]}
+	}
+
+	insights := &GraphInsights{
+		UserID:        userObjectID,
+		DocumentCount: len(g.Nodes),
+		EdgeCount:     len(g.Edges),
+		Hubs:          []HubNote{},
+		Orphans:       []DocumentRef{},
+		Clusters:      []Cluster{},
+		Bridges:       []BridgeNote{},
+		ComputedAt:    time.Now(),
+	}
+
+	// Hubs and orphans are found from links alone, as suggestions are only
+	// similar notes
+	links := &graph.Graph{Nodes: append([]graph.Node{}, g.Nodes...)}
+	for _, edge := range g.Edges {
+		if edge.Type != graph.EdgeSuggested {
+			links.Edges = append(links.Edges, edge)
+		}
+	}
+	links.SetDegrees()
+
+	ranks := graph.PageRank(links, pageRankDamping, pageRankIterations)
+	byRank := links.Nodes
+	sort.SliceStable(byRank, func(i, j int) bool {
+		return ranks[byRank[i].ID] > ranks[byRank[j].ID]
+	})
+
+	for _, node := range byRank {
+		if node.Degree == 0 {
+			insights.Orphans = append(insights.Orphans, ref(node.ID))
+			continue
+		}
+		if len(insights.Hubs) < insightsHubs {
+			hub := HubNote{DocumentRef: ref(node.ID), PageRank: ranks[node.ID], Degree: node.Degree}
+			if len(g.Nodes) > 1 {
+				hub.DegreeCentrality = float64(node.Degree) / float64(len(g.Nodes)-1)
+			}
+			insights.Hubs = append(insights.Hubs, hub)
+		}
+	}
+
+	// Communities are numbered by size, so clusters of two or more
+	// documents come first
+	communities := graph.Communities(g)
+	for _, node := range byRank {
+		c := communities[node.ID]
+		for len(insights.Clusters) <= c {
+			insights.Clusters = append(insights.Clusters, Cluster{ID: len(insights.Clusters)})
+		}
+		insights.Clusters[c].Documents = append(insights.Clusters[c].Documents, ref(node.ID))
+		insights.Clusters[c].Size++
+	}
+	for i, cluster := range insights.Clusters {
+		if cluster.Size < 2 {
+			insights.Clusters = insights.Clusters[:i]
+			break
+		}
+	}
+
+	if err := s.labelClusters(ctx, userID, insights.Clusters, communities); err != nil {
+		return nil, err
+	}
+
+	for _, bridge := range graph.Bridges(g, communities) {
+		if len(insights.Bridges) == insightsBridges {
+			break
+		}
+		var clusters []int
+		for _, c := range bridge.Communities {
+			if c < len(insights.Clusters) {
+				clusters = append(clusters, c)
+			}
+		}
+		if len(clusters) == 0 || communities[bridge.ID] >= len(insights.Clusters) {
+			continue
+		}
+		insights.Bridges = append(insights.Bridges, BridgeNote{
+			DocumentRef: ref(bridge.ID),
+			Cluster:     communities[bridge.ID],
+			Clusters:    clusters,
+			Weight:      bridge.Weight,
+		})
+	}
+
+	_, err = s.db.Collection("graph_insights").ReplaceOne(ctx, bson.M{"user_id": userObjectID}, insights, options.Replace().SetUpsert(true))
+	if err != nil {
+		return nil, err
+	}
+	return insights, nil
+}
+
+// labelClusters names clusters by the TF-IDF of the terms of their
+// documents' chunks, taken from the keyword index, or else by their top
+// document
+func (s *InsightsService) labelClusters(ctx context.Context, userID string, clusters []Cluster, communities map[string]int) error {
+	if len(clusters) == 0 {
+		return nil
+	}
+
+	groups := make([]map[string]int, len(clusters))
+	for i := range groups {
+		groups[i] = make(map[string]int)
+	}
+
+	cursor, err := s.db.Collection("keyword_index").Find(ctx,
+		bson.M{"user_id": userID},
+		options.Find().SetProjection(bson.M{"document_id": 1, "terms": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var entry keywordEntry
+		if err := cursor.Decode(&entry); err != nil {
+			return err
+		}
+		c, ok := communities[entry.DocumentID]
+		if !ok || c >= len(clusters) {
+			continue
+		}
+		for _, term := range entry.Terms {
+			groups[c][term.Term] += term.Count
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	for i, terms := range ranking.TopTerms(groups, clusterLabelTerms) {
+		clusters[i].Terms = append([]string{}, terms...)
+		if len(terms) > 0 {
+			clusters[i].Label = strings.Join(terms, ", ")
+		} else {
+			clusters[i].Label = clusters[i].Documents[0].Title
+		}
+	}
+	return nil
+}
+
+func insightsPendingKey(userID string) string {
+	return "graph:insights:pending:" + userID
+}
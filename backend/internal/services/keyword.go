@@ -0,0 +1,523 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zettelkasten/internal/models"
+	"zettelkasten/internal/ranking"
+)
+
+// keywordCandidateLimit bounds the chunks scored for one keyword query
+const keywordCandidateLimit = 2000
+
+// KeywordIndex is a per-user inverted index of chunks, stored in MongoDB
+// and scored with BM25. The statistics BM25 needs, each term's document
+// frequency and the number and total length of a user's chunks, are kept
+// alongside and updated as chunks are indexed and removed.
+type KeywordIndex struct {
+	collection *mongo.Collection
+	terms      *mongo.Collection
+	stats      *mongo.Collection
+}
+
+type keywordEntry struct {
+	ID         string                 `bson:"_id"`
+	UserID     string                 `bson:"user_id"`
+	DocumentID string                 `bson:"document_id"`
+	Terms      []termCount            `bson:"terms"`
+	Length     int                    `bson:"length"`
+	Metadata   map[string]interface{} `bson:"metadata"`
+}
+
+type termCount struct {
+	Term  string `bson:"term"`
+	Count int    `bson:"count"`
+}
+
+// termFrequency is the number of a user's chunks containing a term
+type termFrequency struct {
+	UserID string `bson:"user_id"`
+	Term   string `bson:"term"`
+	Chunks int    `bson:"chunks"`
+}
+
+// keywordStats are the totals over a user's chunks. Built is set once they
+// were counted from the index, as opposed to only accumulated from the
+// changes made since.
+type keywordStats struct {
+	ID     string `bson:"_id"`
+	Chunks int    `bson:"chunks"`
+	Length int    `bson:"length"`
+	Built  bool   `bson:"built"`
+}
+
+// statsChange accumulates the changes to a user's statistics made by
+// indexing and removing chunks
+type statsChange struct {
+	chunks int
+	length int
+	terms  map[string]int
+}
+
+func newStatsChange() *statsChange {
+	return &statsChange{terms: make(map[string]int)}
+}
+
+// add counts entry in the statistics, or removes it when sign is -1
+func (c *statsChange) add(entry keywordEntry, sign int) {
+	c.chunks += sign
+	c.length += sign * entry.Length
+	for _, term := range entry.Terms {
+		c.terms[term.Term] += sign
+	}
+}
+
+func NewKeywordIndex(mongodb *mongo.Client) *KeywordIndex {
+	db := mongodb.Database("zettelkasten")
+	collection := db.Collection("keyword_index")
+	terms := db.Collection("keyword_terms")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "terms.term", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "document_id", Value: 1}}},
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create keyword index: %v", err)
+	}
+	_, err = terms.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "term", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create keyword term index: %v", err)
+	}
+
+	return &KeywordIndex{
+		collection: collection,
+		terms:      terms,
+		stats:      db.Collection("keyword_stats"),
+	}
+}
+
+// IndexChunk adds or replaces a chunk, given the metadata stored with its
+// vector. The title is indexed along with the content.
+func (k *KeywordIndex) IndexChunk(ctx context.Context, userID, documentID, chunkID string, metadata map[string]interface{}) error {
+	text := getStringFromMetadata(metadata, "title") + "\n" + getStringFromMetadata(metadata, "content")
+	frequencies, length := ranking.TermFrequencies(text)
+
+	terms := make([]termCount, 0, len(frequencies))
+	for term, count := range frequencies {
+		terms = append(terms, termCount{Term: term, Count: count})
+	}
+
+	entry := keywordEntry{
+		ID:         chunkID,
+		UserID:     userID,
+		DocumentID: documentID,
+		Terms:      terms,
+		Length:     length,
+		Metadata:   metadata,
+	}
+
+	var previous keywordEntry
+	err := k.collection.FindOneAndReplace(ctx, bson.M{"_id": chunkID}, entry,
+		options.FindOneAndReplace().
+			SetUpsert(true).
+			SetReturnDocument(options.Before).
+			SetProjection(bson.M{"user_id": 1, "terms": 1, "length": 1}),
+	).Decode(&previous)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	changes := map[string]*statsChange{userID: newStatsChange()}
+	changes[userID].add(entry, 1)
+	if err == nil {
+		if changes[previous.UserID] == nil {
+			changes[previous.UserID] = newStatsChange()
+		}
+		changes[previous.UserID].add(previous, -1)
+	}
+	return k.applyStats(ctx, changes)
+}
+
+// DeleteChunks removes chunks from the index
+func (k *KeywordIndex) DeleteChunks(ctx context.Context, chunkIDs []string) error {
+	return k.remove(ctx, bson.M{"_id": bson.M{"$in": chunkIDs}})
+}
+
+// DeleteDocument removes every chunk of a document from the index
+func (k *KeywordIndex) DeleteDocument(ctx context.Context, userID, documentID string) error {
+	return k.remove(ctx, bson.M{"user_id": userID, "document_id": documentID})
+}
+
+// IndexedChunks counts the chunks of a document in the index
+func (k *KeywordIndex) IndexedChunks(ctx context.Context, userID, documentID string) (int, error) {
+	count, err := k.collection.CountDocuments(ctx, bson.M{"user_id": userID, "document_id": documentID})
+	return int(count), err
+}
+
+// remove deletes the chunks matching filter and takes them out of the
+// statistics
+func (k *KeywordIndex) remove(ctx context.Context, filter bson.M) error {
+	cursor, err := k.collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"user_id": 1, "terms": 1, "length": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []keywordEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(entries))
+	changes := make(map[string]*statsChange)
+	for i, entry := range entries {
+		ids[i] = entry.ID
+		if changes[entry.UserID] == nil {
+			changes[entry.UserID] = newStatsChange()
+		}
+		changes[entry.UserID].add(entry, -1)
+	}
+
+	if _, err := k.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+		return err
+	}
+	return k.applyStats(ctx, changes)
+}
+
+// applyStats adds changes to the stored statistics of each user
+func (k *KeywordIndex) applyStats(ctx context.Context, changes map[string]*statsChange) error {
+	for userID, change := range changes {
+		var updates []mongo.WriteModel
+		removed := false
+		for term, delta := range change.terms {
+			if delta == 0 {
+				continue
+			}
+			removed = removed || delta < 0
+			updates = append(updates, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"user_id": userID, "term": term}).
+				SetUpdate(bson.M{"$inc": bson.M{"chunks": delta}}).
+				SetUpsert(true))
+		}
+		if len(updates) > 0 {
+			if _, err := k.terms.BulkWrite(ctx, updates, options.BulkWrite().SetOrdered(false)); err != nil {
+				return err
+			}
+		}
+		if removed {
+			if _, err := k.terms.DeleteMany(ctx, bson.M{"user_id": userID, "chunks": bson.M{"$lte": 0}}); err != nil {
+				return err
+			}
+		}
+
+		if change.chunks != 0 || change.length != 0 {
+			_, err := k.stats.UpdateOne(ctx,
+				bson.M{"_id": userID},
+				bson.M{"$inc": bson.M{"chunks": change.chunks, "length": change.length}},
+				options.Update().SetUpsert(true),
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// userStats returns a user's statistics, counting them from the index when
+// they were never built, e.g. for chunks indexed before statistics were
+// kept. Changes made while counting can be missed, which BM25 tolerates.
+func (k *KeywordIndex) userStats(ctx context.Context, userID string) (keywordStats, error) {
+	var stats keywordStats
+	err := k.stats.FindOne(ctx, bson.M{"_id": userID}).Decode(&stats)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return stats, err
+	}
+	if stats.Built {
+		return stats, nil
+	}
+	return k.rebuildStats(ctx, userID)
+}
+
+// rebuildStats counts a user's statistics from the index and replaces the
+// stored ones
+func (k *KeywordIndex) rebuildStats(ctx context.Context, userID string) (keywordStats, error) {
+	stats := keywordStats{ID: userID, Built: true}
+
+	cursor, err := k.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "chunks": bson.M{"$sum": 1}, "length": bson.M{"$sum": "$length"}}}},
+	})
+	if err != nil {
+		return stats, err
+	}
+	var totals []keywordStats
+	err = cursor.All(ctx, &totals)
+	cursor.Close(ctx)
+	if err != nil {
+		return stats, err
+	}
+	if len(totals) > 0 {
+		stats.Chunks, stats.Length = totals[0].Chunks, totals[0].Length
+	}
+
+	cursor, err = k.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID}}},
+		{{Key: "$unwind", Value: "$terms"}},
+		{{Key: "$group", Value: bson.M{"_id": "$terms.term", "chunks": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return stats, err
+	}
+	var counts []struct {
+		Term   string `bson:"_id"`
+		Chunks int    `bson:"chunks"`
+	}
+	err = cursor.All(ctx, &counts)
+	cursor.Close(ctx)
+	if err != nil {
+		return stats, err
+	}
+
+	if _, err := k.terms.DeleteMany(ctx, bson.M{"user_id": userID}); err != nil {
+		return stats, err
+	}
+	if len(counts) > 0 {
+		frequencies := make([]interface{}, len(counts))
+		for i, count := range counts {
+			frequencies[i] = termFrequency{UserID: userID, Term: count.Term, Chunks: count.Chunks}
+		}
+		if _, err := k.terms.InsertMany(ctx, frequencies, options.InsertMany().SetOrdered(false)); err != nil {
+			return stats, err
+		}
+	}
+
+	_, err = k.stats.ReplaceOne(ctx, bson.M{"_id": userID}, stats, options.Replace().SetUpsert(true))
+	return stats, err
+}
+
+// documentFrequencies returns the number of the user's chunks containing
+// each of terms, leaving out terms no chunk contains
+func (k *KeywordIndex) documentFrequencies(ctx context.Context, userID string, terms []string) (map[string]int, error) {
+	cursor, err := k.terms.Find(ctx, bson.M{"user_id": userID, "term": bson.M{"$in": terms}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var frequencies []termFrequency
+	if err := cursor.All(ctx, &frequencies); err != nil {
+		return nil, err
+	}
+
+	df := make(map[string]int, len(frequencies))
+	for _, frequency := range frequencies {
+		if frequency.Chunks > 0 {
+			df[frequency.Term] = frequency.Chunks
+		}
+	}
+	return df, nil
+}
+
+// Search returns the user's chunks matching query ranked by BM25. filter
+// uses the vector metadata filter syntax, which MongoDB shares.
+func (k *KeywordIndex) Search(ctx context.Context, userID, query string, limit int, filter map[string]interface{}) ([]SearchResult, error) {
+	var queryTerms []string
+	seen := make(map[string]bool)
+	for _, term := range ranking.Terms(query) {
+		if !seen[term] {
+			seen[term] = true
+			queryTerms = append(queryTerms, term)
+		}
+	}
+	if len(queryTerms) == 0 || limit < 1 {
+		return nil, nil
+	}
+
+	// Collection statistics are over all of the user's chunks
+	stats, err := k.userStats(ctx, userID)
+	if err != nil || stats.Chunks <= 0 {
+		return nil, err
+	}
+	avgLength := float64(stats.Length) / float64(stats.Chunks)
+
+	df, err := k.documentFrequencies(ctx, userID, queryTerms)
+	if err != nil {
+		return nil, err
+	}
+	idf := make(map[string]float64, len(df))
+	var matchedTerms []string
+	for _, term := range queryTerms {
+		if count, ok := df[term]; ok {
+			idf[term] = ranking.IDF(count, stats.Chunks)
+			matchedTerms = append(matchedTerms, term)
+		}
+	}
+
+	// Chunks with the rarest terms score highest, so candidates are taken
+	// from them first when a common term matches more than the limit
+	sort.SliceStable(matchedTerms, func(i, j int) bool { return df[matchedTerms[i]] < df[matchedTerms[j]] })
+
+	var entries []keywordEntry
+	var candidateIDs []string
+	for _, term := range matchedTerms {
+		remaining := keywordCandidateLimit - len(entries)
+		if remaining <= 0 {
+			break
+		}
+
+		candidateFilter := keywordFilter(filter)
+		candidateFilter["user_id"] = userID
+		candidateFilter["terms.term"] = term
+		if len(candidateIDs) > 0 {
+			candidateFilter["_id"] = bson.M{"$nin": candidateIDs}
+		}
+		cursor, err := k.collection.Find(ctx, candidateFilter, options.Find().SetLimit(int64(remaining)))
+		if err != nil {
+			return nil, err
+		}
+		var termEntries []keywordEntry
+		err = cursor.All(ctx, &termEntries)
+		cursor.Close(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range termEntries {
+			candidateIDs = append(candidateIDs, entry.ID)
+		}
+		entries = append(entries, termEntries...)
+	}
+
+	results := make([]SearchResult, 0, len(entries))
+	for _, entry := range entries {
+		var score float64
+		for _, term := range entry.Terms {
+			if weight, ok := idf[term.Term]; ok {
+				score += ranking.BM25(term.Count, entry.Length, avgLength, weight)
+			}
+		}
+
+		result := newSearchResult(entry.ID, normalizeStoredMetadata(entry.Metadata))
+		result.KeywordScore = float32(score)
+		result.Score = float32(score)
+		results = append(results, result)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].KeywordScore > results[j].KeywordScore })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// List returns the user's chunks matching filter, newest first
+func (k *KeywordIndex) List(ctx context.Context, userID string, limit int, filter map[string]interface{}) ([]SearchResult, error) {
+	listFilter := keywordFilter(filter)
+	listFilter["user_id"] = userID
+
+	findOpts := options.Find().
+		SetSort(bson.D{{Key: "metadata.created_at", Value: -1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+	cursor, err := k.collection.Find(ctx, listFilter, findOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []keywordEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(entries))
+	for _, entry := range entries {
+		results = append(results, newSearchResult(entry.ID, normalizeStoredMetadata(entry.Metadata)))
+	}
+	return results, nil
+}
+
+// DocumentChunks returns the indexed chunks of documents with their
+// content, in no particular order. Documents uploaded before chunks were
+// indexed have none until they are indexed.
+func (k *KeywordIndex) DocumentChunks(ctx context.Context, userID string, documentIDs []string) ([]models.Chunk, error) {
+	cursor, err := k.collection.Find(ctx,
+		bson.M{"user_id": userID, "document_id": bson.M{"$in": documentIDs}},
+		options.Find().SetProjection(bson.M{"document_id": 1, "metadata.content": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var entries []keywordEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	chunks := make([]models.Chunk, 0, len(entries))
+	for _, entry := range entries {
+		chunks = append(chunks, models.Chunk{
+			ID:         entry.ID,
+			DocumentID: entry.DocumentID,
+			UserID:     userID,
+			Content:    getStringFromMetadata(entry.Metadata, "content"),
+			ChunkIndex: chunkIndex(entry.ID),
+		})
+	}
+	return chunks, nil
+}
+
+// keywordFilter translates a vector metadata filter to the fields of the
+// keyword index
+func keywordFilter(filter map[string]interface{}) bson.M {
+	translated := bson.M{}
+	for key, value := range filter {
+		switch {
+		case key == "user_id":
+			continue
+		case key == "$and" || key == "$or":
+			clauses, _ := value.([]interface{})
+			var translatedClauses bson.A
+			for _, clause := range clauses {
+				if clause, ok := clause.(map[string]interface{}); ok {
+					translatedClauses = append(translatedClauses, keywordFilter(clause))
+				}
+			}
+			translated[key] = translatedClauses
+		default:
+			translated["metadata."+key] = value
+		}
+	}
+	return translated
+}
+
+// normalizeStoredMetadata converts metadata read from MongoDB to the types
+// returned for vector metadata, e.g. float64 numbers
+func normalizeStoredMetadata(metadata map[string]interface{}) map[string]interface{} {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return metadata
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return metadata
+	}
+	return normalized
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zettelkasten/internal/models"
+)
+
+// ReindexKeywords adds the user's documents, or only documentID if set, to
+// the keyword index from the metadata stored with their vectors, e.g. for
+// documents uploaded before keyword search existed. Documents whose chunks
+// are all indexed are skipped. It returns the number of chunks indexed.
+func (s *DocumentService) ReindexKeywords(ctx context.Context, userID, documentID string) (int, error) {
+	if s.keywords == nil {
+		return 0, fmt.Errorf("keyword search is not available")
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return 0, err
+	}
+	filter := bson.M{"user_id": userObjectID}
+	if documentID != "" {
+		docObjectID, err := primitive.ObjectIDFromHex(documentID)
+		if err != nil {
+			return 0, mongo.ErrNoDocuments
+		}
+		filter["_id"] = docObjectID
+	}
+
+	cursor, err := s.db.Collection("documents").Find(ctx, filter, options.Find().SetProjection(bson.M{"chunk_count": 1}))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var documents []models.Document
+	if err := cursor.All(ctx, &documents); err != nil {
+		return 0, err
+	}
+	if documentID != "" && len(documents) == 0 {
+		return 0, mongo.ErrNoDocuments
+	}
+
+	indexed := 0
+	for _, doc := range documents {
+		docID := doc.ID.Hex()
+		count, err := s.keywords.IndexedChunks(ctx, userID, docID)
+		if err != nil {
+			return indexed, err
+		}
+		if count >= doc.ChunkCount {
+			continue
+		}
+
+		ids := make([]string, doc.ChunkCount)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("%s_%d", docID, i)
+		}
+		for start := 0; start < len(ids); start += fetchBatchSize {
+			end := min(start+fetchBatchSize, len(ids))
+			stored, err := s.pinecone.Fetch(ids[start:end])
+			if err != nil {
+				return indexed, err
+			}
+			for id, vector := range stored {
+				if err := s.keywords.IndexChunk(ctx, userID, docID, id, vector.Metadata); err != nil {
+					return indexed, err
+				}
+				indexed++
+			}
+		}
+		log.Printf("Added document %s to the keyword index", docID)
+	}
+
+	if indexed > 0 {
+		s.searchCache.Invalidate(userID)
+	}
+	return indexed, nil
+}
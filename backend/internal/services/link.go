@@ -0,0 +1,557 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zettelkasten/internal/database"
+	"zettelkasten/internal/models"
+)
+
+// Link statuses. Rejected links are kept so that they are not suggested
+// again.
+const (
+	LinkSuggested = "suggested"
+	LinkAccepted  = "accepted"
+	LinkRejected  = "rejected"
+)
+
+// DefaultLinkThreshold is the similarity a pair of chunks needs for their
+// documents to be suggested as linked
+const DefaultLinkThreshold = 0.8
+
+// linksPerChunk bounds the neighbours looked up for each chunk
+const linksPerChunk = 5
+
+// linkQueue is the Redis list of documents waiting for link suggestions
+const linkQueue = "link_queue"
+
+// ErrLinkNotFound is returned for links that do not exist or belong to
+// another user
+var ErrLinkNotFound = errors.New("link not found")
+
+func IsValidLinkStatus(status string) bool {
+	return status == LinkSuggested || status == LinkAccepted || status == LinkRejected
+}
+
+// LinkService suggests links between similar documents in the background
+// and stores the links users accept
+type LinkService struct {
+	db           *mongo.Database
+	pinecone     *database.PineconeClient
+	redis        *database.RedisClient
+	eventService *EventService
+	insights     *InsightsService
+	threshold    float32
+}
+
+// linkJob asks for the links of a newly embedded document
+type linkJob struct {
+	UserID     string `json:"user_id"`
+	DocumentID string `json:"document_id"`
+	ChunkCount int    `json:"chunk_count"`
+}
+
+func NewLinkService(mongodb *mongo.Client, pinecone *database.PineconeClient, redis *database.RedisClient, eventService *EventService, insights *InsightsService, threshold float64) *LinkService {
+	if threshold <= 0 {
+		threshold = DefaultLinkThreshold
+	}
+	db := mongodb.Database("zettelkasten")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := db.Collection("links").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "pair", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "status", Value: 1}, {Key: "score", Value: -1}},
+		},
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to create link indexes: %v", err)
+	}
+
+	return &LinkService{
+		db:           db,
+		pinecone:     pinecone,
+		redis:        redis,
+		eventService: eventService,
+		insights:     insights,
+		threshold:    float32(threshold),
+	}
+}
+
+// QueueSuggestions queues a document whose chunks were embedded to have
+// links suggested. It is safe to call on a nil service.
+func (s *LinkService) QueueSuggestions(userID, documentID string, chunkCount int) {
+	if s == nil || chunkCount == 0 {
+		return
+	}
+	data, err := json.Marshal(linkJob{UserID: userID, DocumentID: documentID, ChunkCount: chunkCount})
+	if err != nil {
+		return
+	}
+	if err := s.redis.LPush(linkQueue, string(data)); err != nil {
+		log.Printf("Warning: Failed to queue link suggestions for document %s: %v", documentID, err)
+	}
+}
+
+// QueueUserDocuments queues the user's documents, or only documentID if
+// set, to have links suggested, e.g. for documents stored before links were
+// suggested. It returns the number of documents queued.
+func (s *LinkService) QueueUserDocuments(ctx context.Context, userID, documentID string) (int, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	filter := bson.M{"user_id": userObjectID}
+	if documentID != "" {
+		docObjectID, err := primitive.ObjectIDFromHex(documentID)
+		if err != nil {
+			return 0, mongo.ErrNoDocuments
+		}
+		filter["_id"] = docObjectID
+	}
+
+	cursor, err := s.db.Collection("documents").Find(ctx, filter, options.Find().SetProjection(bson.M{"chunk_count": 1}))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var documents []models.Document
+	if err := cursor.All(ctx, &documents); err != nil {
+		return 0, err
+	}
+	if documentID != "" && len(documents) == 0 {
+		return 0, mongo.ErrNoDocuments
+	}
+
+	for _, doc := range documents {
+		s.QueueSuggestions(userID, doc.ID.Hex(), doc.ChunkCount)
+	}
+	return len(documents), nil
+}
+
+// StartWithContext suggests links for queued documents until ctx is done
+func (s *LinkService) StartWithContext(ctx context.Context) {
+	log.Println("Starting link suggestion processor...")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Link suggestion processor shutting down...")
+			return
+		default:
+			jobData, err := s.redis.BRPop(2*time.Second, linkQueue)
+			if err != nil {
+				if ctx.Err() != nil {
+					log.Println("Link suggestion processor shutting down...")
+					return
+				}
+				time.Sleep(1 * time.Second)
+				continue
+			}
+
+			if len(jobData) < 2 {
+				continue
+			}
+
+			var job linkJob
+			if err := json.Unmarshal([]byte(jobData[1]), &job); err != nil {
+				log.Printf("Error unmarshaling link job: %v", err)
+				continue
+			}
+
+			suggested, err := s.SuggestLinks(ctx, job.UserID, job.DocumentID, job.ChunkCount)
+			if err != nil {
+				log.Printf("Failed to suggest links for document %s: %v", job.DocumentID, err)
+				continue
+			}
+			log.Printf("Suggested %d links for document %s", suggested, job.DocumentID)
+			s.insights.Queue(job.UserID)
+
+			if suggested > 0 && s.eventService != nil {
+				s.eventService.LinksSuggested(job.UserID, job.DocumentID, suggested)
+			}
+		}
+	}
+}
+
+// SuggestLinks looks up the chunks of the user's other documents nearest to
+// each chunk of a document and suggests a link to every document with a
+// chunk above the threshold, scored by its best pair of chunks. Zettels are
+// not linked to the document they were extracted from, whose text they
+// repeat. It returns the number of links suggested or given a better score.
+func (s *LinkService) SuggestLinks(ctx context.Context, userID, documentID string, chunkCount int) (int, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]string, chunkCount)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("%s_%d", documentID, i)
+	}
+	vectors, err := fetchVectors(s.pinecone, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	filter := map[string]interface{}{
+		"user_id":     userID,
+		"document_id": map[string]interface{}{"$ne": documentID},
+	}
+	best := make(map[string]models.Link)
+	for _, id := range ids {
+		vector, ok := vectors[id]
+		if !ok {
+			continue
+		}
+		queryResponse, err := s.pinecone.Query(vector, linksPerChunk, filter)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, match := range queryResponse.Matches {
+			if match.Score < s.threshold {
+				continue
+			}
+			target := getStringFromMetadata(match.Vector.Metadata.AsMap(), "document_id")
+			if target == "" || target == documentID {
+				continue
+			}
+			if link, ok := best[target]; ok && link.Score >= match.Score {
+				continue
+			}
+			best[target] = models.Link{
+				UserID:           userObjectID,
+				SourceDocumentID: documentID,
+				TargetDocumentID: target,
+				SourceChunkID:    id,
+				TargetChunkID:    match.Vector.Id,
+				Score:            match.Score,
+			}
+		}
+	}
+	if len(best) == 0 {
+		return 0, nil
+	}
+
+	// Vectors of deleted documents may still be found
+	targets := make([]string, 0, len(best))
+	for target := range best {
+		targets = append(targets, target)
+	}
+	titles, err := s.documentTitles(ctx, userObjectID, targets)
+	if err != nil {
+		return 0, err
+	}
+	sources, err := s.zettelSources(ctx, userObjectID, append(targets, documentID))
+	if err != nil {
+		return 0, err
+	}
+
+	suggested := 0
+	for target, link := range best {
+		if _, ok := titles[target]; !ok {
+			continue
+		}
+		if sources[target] == documentID || sources[documentID] == target {
+			continue
+		}
+		stored, err := s.suggest(ctx, link)
+		if err != nil {
+			return suggested, err
+		}
+		if stored {
+			suggested++
+		}
+	}
+	return suggested, nil
+}
+
+// suggest stores a suggested link, unless the documents already have a
+// better suggestion or a link the user accepted or rejected
+func (s *LinkService) suggest(ctx context.Context, link models.Link) (bool, error) {
+	now := time.Now()
+	link.Pair = linkPair(link.SourceDocumentID, link.TargetDocumentID)
+	collection := s.db.Collection("links")
+
+	result, err := collection.UpdateOne(ctx, bson.M{
+		"user_id": link.UserID,
+		"pair":    link.Pair,
+		"status":  LinkSuggested,
+		"score":   bson.M{"$lt": link.Score},
+	}, bson.M{"$set": bson.M{
+		"source_document_id": link.SourceDocumentID,
+		"target_document_id": link.TargetDocumentID,
+		"source_chunk_id":    link.SourceChunkID,
+		"target_chunk_id":    link.TargetChunkID,
+		"score":              link.Score,
+		"updated_at":         now,
+	}})
+	if err != nil {
+		return false, err
+	}
+	if result.MatchedCount > 0 {
+		return true, nil
+	}
+
+	link.Status = LinkSuggested
+	link.CreatedAt = now
+	link.UpdatedAt = now
+	if _, err := collection.InsertOne(ctx, link); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ListLinks returns the user's links with the titles of their documents,
+// best first. status and documentID are optional filters; a document's
+// links include those suggested from either end.
+func (s *LinkService) ListLinks(ctx context.Context, userID, status, documentID string, page, limit int) ([]models.Link, int64, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filter := bson.M{"user_id": userObjectID}
+	if status != "" {
+		filter["status"] = status
+	}
+	if documentID != "" {
+		filter["$or"] = []bson.M{
+			{"source_document_id": documentID},
+			{"target_document_id": documentID},
+		}
+	}
+
+	collection := s.db.Collection("links")
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOpts := options.Find().
+		SetSort(bson.D{{Key: "score", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := collection.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	links := []models.Link{}
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, 0, err
+	}
+
+	var ids []string
+	for _, link := range links {
+		ids = append(ids, link.SourceDocumentID, link.TargetDocumentID)
+	}
+	titles, err := s.documentTitles(ctx, userObjectID, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range links {
+		links[i].SourceTitle = titles[links[i].SourceDocumentID]
+		links[i].TargetTitle = titles[links[i].TargetDocumentID]
+	}
+	return links, total, nil
+}
+
+// SetStatus accepts or rejects a link
+func (s *LinkService) SetStatus(ctx context.Context, userID, linkID, status string) (*models.Link, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+	linkObjectID, err := primitive.ObjectIDFromHex(linkID)
+	if err != nil {
+		return nil, ErrLinkNotFound
+	}
+
+	var link models.Link
+	err = s.db.Collection("links").FindOneAndUpdate(ctx,
+		bson.M{"_id": linkObjectID, "user_id": userObjectID},
+		bson.M{"$set": bson.M{"status": status, "updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&link)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrLinkNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.insights.Queue(userID)
+
+	titles, err := s.documentTitles(ctx, userObjectID, []string{link.SourceDocumentID, link.TargetDocumentID})
+	if err == nil {
+		link.SourceTitle = titles[link.SourceDocumentID]
+		link.TargetTitle = titles[link.TargetDocumentID]
+	}
+	return &link, nil
+}
+
+// DeleteDocumentLinks removes the links of a deleted document
+func (s *LinkService) DeleteDocumentLinks(ctx context.Context, userID, documentID string) error {
+	if s == nil {
+		return nil
+	}
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Collection("links").DeleteMany(ctx, bson.M{
+		"user_id": userObjectID,
+		"$or": []bson.M{
+			{"source_document_id": documentID},
+			{"target_document_id": documentID},
+		},
+	})
+	s.insights.Queue(userID)
+	return err
+}
+
+// RemapDocumentChunks points the chunks recorded on a rechunked document's
+// links at the new chunk covering most of the same text, as given by
+// mapping, or clears them when no new chunk does. It is safe to call on a
+// nil service.
+func (s *LinkService) RemapDocumentChunks(ctx context.Context, userID, documentID string, mapping map[string][]string) error {
+	if s == nil {
+		return nil
+	}
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, side := range []string{"source", "target"} {
+		cursor, err := s.db.Collection("links").Find(ctx, bson.M{
+			"user_id":             userObjectID,
+			side + "_document_id": documentID,
+			side + "_chunk_id":    bson.M{"$ne": ""},
+		}, options.Find().SetProjection(bson.M{side + "_chunk_id": 1}))
+		if err != nil {
+			return err
+		}
+		var links []models.Link
+		err = cursor.All(ctx, &links)
+		cursor.Close(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, link := range links {
+			chunkID := link.SourceChunkID
+			if side == "target" {
+				chunkID = link.TargetChunkID
+			}
+			remapped := ""
+			if mapped := mapping[chunkID]; len(mapped) > 0 {
+				remapped = mapped[0]
+			}
+			if remapped == chunkID {
+				continue
+			}
+			_, err := s.db.Collection("links").UpdateOne(ctx, bson.M{"_id": link.ID}, bson.M{"$set": bson.M{side + "_chunk_id": remapped}})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// documentTitles returns the titles of those of the given documents that
+// belong to the user
+func (s *LinkService) documentTitles(ctx context.Context, userObjectID primitive.ObjectID, documentIDs []string) (map[string]string, error) {
+	objectIDs := make([]primitive.ObjectID, 0, len(documentIDs))
+	for _, id := range documentIDs {
+		if objectID, err := primitive.ObjectIDFromHex(id); err == nil {
+			objectIDs = append(objectIDs, objectID)
+		}
+	}
+
+	titles := make(map[string]string, len(objectIDs))
+	if len(objectIDs) == 0 {
+		return titles, nil
+	}
+
+	cursor, err := s.db.Collection("documents").Find(ctx,
+		bson.M{"_id": bson.M{"$in": objectIDs}, "user_id": userObjectID},
+		options.Find().SetProjection(bson.M{"title": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var documents []models.Document
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+	for _, doc := range documents {
+		titles[doc.ID.Hex()] = doc.Title
+	}
+	return titles, nil
+}
+
+// zettelSources maps those of the given documents that are zettels to the
+// document they were extracted from
+func (s *LinkService) zettelSources(ctx context.Context, userObjectID primitive.ObjectID, documentIDs []string) (map[string]string, error) {
+	objectIDs := make([]primitive.ObjectID, 0, len(documentIDs))
+	for _, id := range documentIDs {
+		if objectID, err := primitive.ObjectIDFromHex(id); err == nil {
+			objectIDs = append(objectIDs, objectID)
+		}
+	}
+
+	cursor, err := s.db.Collection("documents").Find(ctx,
+		bson.M{"_id": bson.M{"$in": objectIDs}, "user_id": userObjectID, "zettel.source_document_id": bson.M{"$exists": true}},
+		options.Find().SetProjection(bson.M{"zettel.source_document_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var documents []models.Document
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+	sources := make(map[string]string, len(documents))
+	for _, doc := range documents {
+		if doc.Zettel != nil {
+			sources[doc.ID.Hex()] = doc.Zettel.SourceDocumentID
+		}
+	}
+	return sources, nil
+}
+
+// linkPair identifies two documents regardless of their order
+func linkPair(a, b string) string {
+	if b < a {
+		a, b = b, a
+	}
+	return a + ":" + b
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zettelkasten/internal/query"
+	"zettelkasten/internal/ranking"
+)
+
+// withQueryFilters adds the field operators of a parsed query to filters.
+// Repeated tag: operators must all match, repeated source: operators any.
+func withQueryFilters(filters SearchFilters, parsed query.Parsed) SearchFilters {
+	for _, f := range parsed.Filters {
+		switch f.Field {
+		case query.FieldTag:
+			if f.Negated {
+				filters.TagsNone = append(filters.TagsNone, f.Value)
+			} else {
+				filters.TagsAll = append(filters.TagsAll, f.Value)
+			}
+		case query.FieldSource:
+			if f.Negated {
+				filters.ExcludeSourceTypes = append(filters.ExcludeSourceTypes, strings.ToLower(f.Value))
+			} else {
+				filters.SourceTypes = append(filters.SourceTypes, strings.ToLower(f.Value))
+			}
+		case query.FieldAfter, query.FieldBefore:
+			date, err := query.ParseDate(f.Value)
+			if err != nil {
+				continue
+			}
+			if filters.DateRange == nil {
+				filters.DateRange = &DateRange{}
+			}
+			if f.Field == query.FieldAfter {
+				filters.DateRange.From = date
+			} else {
+				// before: excludes the given date itself
+				filters.DateRange.To = date.Add(-time.Second)
+			}
+		case query.FieldTitle:
+			filters.Title = f.Value
+		case query.FieldFrom, query.FieldTo:
+			filters.Correspondents = append(filters.Correspondents, f.Value)
+		case query.FieldText:
+			filters.ExcludeTerms = append(filters.ExcludeTerms, f.Value)
+		}
+	}
+	return filters
+}
+
+// excludeTerms drops results whose title or content contains any of the
+// words or phrases
+func excludeTerms(results []SearchResult, excluded []string) []SearchResult {
+	if len(excluded) == 0 {
+		return results
+	}
+
+	var phrases []string
+	for _, phrase := range excluded {
+		if terms := ranking.Terms(phrase); len(terms) > 0 {
+			phrases = append(phrases, " "+strings.Join(terms, " ")+" ")
+		}
+	}
+
+	kept := results[:0]
+	for _, result := range results {
+		text := " " + strings.Join(ranking.Terms(result.Source.Title+"\n"+result.Content), " ") + " "
+		matched := false
+		for _, phrase := range phrases {
+			if strings.Contains(text, phrase) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
+
+// documentIDsByTitle returns the IDs of the user's documents whose title
+// contains title, ignoring case
+func (s *DocumentService) documentIDsByTitle(ctx context.Context, userID, title string) ([]string, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := s.db.Collection("documents").Find(ctx, bson.M{
+		"user_id": userObjectID,
+		"title":   primitive.Regex{Pattern: regexp.QuoteMeta(title), Options: "i"},
+	}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var ids []string
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		ids = append(ids, doc.ID.Hex())
+	}
+	return ids, cursor.Err()
+}
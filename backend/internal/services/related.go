@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"zettelkasten/internal/database"
+	"zettelkasten/internal/models"
+	"zettelkasten/internal/ranking"
+)
+
+// Related notes are found among more chunks than the documents requested,
+// as several chunks of a document may match
+const (
+	DefaultRelatedLimit      = 10
+	relatedCandidateFactor   = 5
+	relatedChunksPerDocument = 3
+	// fetchBatchSize bounds the vectors fetched from Pinecone at once
+	fetchBatchSize = 100
+)
+
+// ErrChunkNotFound is returned for chunks that do not exist or belong to
+// another user
+var ErrChunkNotFound = errors.New("chunk not found")
+
+// RelatedDocument is a document with chunks related to a note, best first
+type RelatedDocument struct {
+	DocumentID string `json:"document_id"`
+	Title      string `json:"title"`
+	SourceType string `json:"source_type"`
+	// Score is the similarity of its best chunk
+	Score float32 `json:"score"`
+	// MatchCount is the number of its chunks that matched, of which at most
+	// relatedChunksPerDocument are returned
+	MatchCount int            `json:"match_count"`
+	Chunks     []SearchResult `json:"chunks"`
+}
+
+type RelatedResponse struct {
+	DocumentID string `json:"document_id"`
+	// ChunkID is set when related notes were found for a single chunk
+	ChunkID string            `json:"chunk_id,omitempty"`
+	Related []RelatedDocument `json:"related"`
+}
+
+// RelatedToDocument finds notes related to a document as a whole, by the
+// centroid of its chunks' vectors
+func (s *DocumentService) RelatedToDocument(ctx context.Context, userID, documentID string, limit int, minScore float32) (*RelatedResponse, error) {
+	doc, err := s.GetDocument(ctx, userID, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, doc.ChunkCount)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("%s_%d", documentID, i)
+	}
+	stored, err := fetchVectors(s.pinecone, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, 0, len(stored))
+	for _, id := range ids {
+		if vector, ok := stored[id]; ok {
+			vectors = append(vectors, vector)
+		}
+	}
+	centroid := ranking.Centroid(vectors)
+	if centroid == nil {
+		return &RelatedResponse{DocumentID: documentID, Related: []RelatedDocument{}}, nil
+	}
+
+	related, err := s.related(ctx, userID, documentID, centroid, limit, minScore)
+	if err != nil {
+		return nil, err
+	}
+	return &RelatedResponse{DocumentID: documentID, Related: related}, nil
+}
+
+// RelatedToChunk finds notes related to a single chunk by its vector
+func (s *DocumentService) RelatedToChunk(ctx context.Context, userID, chunkID string, limit int, minScore float32) (*RelatedResponse, error) {
+	stored, err := s.pinecone.Fetch([]string{chunkID})
+	if err != nil {
+		return nil, err
+	}
+	chunk, ok := stored[chunkID]
+	if !ok || getStringFromMetadata(chunk.Metadata, "user_id") != userID || len(chunk.Values) == 0 {
+		return nil, ErrChunkNotFound
+	}
+
+	documentID := getStringFromMetadata(chunk.Metadata, "document_id")
+
+	related, err := s.related(ctx, userID, documentID, chunk.Values, limit, minScore)
+	if err != nil {
+		return nil, err
+	}
+	return &RelatedResponse{DocumentID: documentID, ChunkID: chunkID, Related: related}, nil
+}
+
+// related queries the user's chunks nearest to vector outside the source
+// document and groups them by document. Chunks of documents that no longer
+// exist are skipped, as vectors deleted documents left behind may still be
+// found.
+func (s *DocumentService) related(ctx context.Context, userID, sourceDocumentID string, vector []float32, limit int, minScore float32) ([]RelatedDocument, error) {
+	if limit <= 0 {
+		limit = DefaultRelatedLimit
+	}
+	candidates := min(limit*relatedCandidateFactor, maxCandidates)
+
+	queryResponse, err := s.pinecone.Query(vector, candidates, map[string]interface{}{
+		"user_id":     userID,
+		"document_id": map[string]interface{}{"$ne": sourceDocumentID},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var documentIDs []string
+	for _, match := range queryResponse.Matches {
+		documentIDs = append(documentIDs, getStringFromMetadata(match.Vector.Metadata.AsMap(), "document_id"))
+	}
+	existing, err := s.existingDocuments(ctx, userID, documentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	related := []RelatedDocument{}
+	positions := make(map[string]int)
+	for _, match := range queryResponse.Matches {
+		if match.Score < minScore {
+			continue
+		}
+		result := newSearchResult(match.Vector.Id, match.Vector.Metadata.AsMap())
+		if !existing[result.Source.DocumentID] {
+			continue
+		}
+		result.SimilarityScore = match.Score
+		result.Score = match.Score
+
+		key := result.Source.DocumentID
+		if key == "" {
+			key = result.ID
+		}
+		i, ok := positions[key]
+		if !ok {
+			if len(related) == limit {
+				continue
+			}
+			i = len(related)
+			positions[key] = i
+			related = append(related, RelatedDocument{
+				DocumentID: result.Source.DocumentID,
+				Title:      result.Source.Title,
+				SourceType: result.Source.Type,
+				Score:      result.Score,
+			})
+		}
+
+		related[i].MatchCount++
+		if len(related[i].Chunks) < relatedChunksPerDocument {
+			related[i].Chunks = append(related[i].Chunks, result)
+		}
+	}
+	return related, nil
+}
+
+// existingDocuments reports which of the given documents exist and belong
+// to the user
+func (s *DocumentService) existingDocuments(ctx context.Context, userID string, documentIDs []string) (map[string]bool, error) {
+	existing := make(map[string]bool, len(documentIDs))
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	objectIDs := make([]primitive.ObjectID, 0, len(documentIDs))
+	for _, id := range documentIDs {
+		if objectID, err := primitive.ObjectIDFromHex(id); err == nil {
+			objectIDs = append(objectIDs, objectID)
+		}
+	}
+	if len(objectIDs) == 0 {
+		return existing, nil
+	}
+
+	cursor, err := s.db.Collection("documents").Find(ctx,
+		bson.M{"_id": bson.M{"$in": objectIDs}, "user_id": userObjectID},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var documents []models.Document
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+	for _, doc := range documents {
+		existing[doc.ID.Hex()] = true
+	}
+	return existing, nil
+}
+
+// fetchVectors returns the stored values of the vectors that exist among
+// ids, fetching them in batches
+func fetchVectors(pinecone *database.PineconeClient, ids []string) (map[string][]float32, error) {
+	values := make(map[string][]float32, len(ids))
+	for start := 0; start < len(ids); start += fetchBatchSize {
+		end := min(start+fetchBatchSize, len(ids))
+		stored, err := pinecone.Fetch(ids[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for id, vector := range stored {
+			if len(vector.Values) > 0 {
+				values[id] = vector.Values
+			}
+		}
+	}
+	return values, nil
+}
@@ -2,37 +2,139 @@ package services
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
 	"time"
 
 	"zettelkasten/internal/database"
+	"zettelkasten/internal/parsers"
+	"zettelkasten/internal/query"
+	"zettelkasten/internal/ranking"
+	"zettelkasten/internal/rerank"
 )
 
 type SearchService struct {
-	pinecone         *database.PineconeClient
-	redis            *database.RedisClient
-	embeddingService *EmbeddingService
+	pinecone        *database.PineconeClient
+	cache           *SearchCache
+	queryEmbeddings *EmbeddingCache
+	documentService *DocumentService
+	keywords        *KeywordIndex
+	reranker        rerank.Reranker
+}
+
+// Search modes
+const (
+	SearchModeVector  = "vector"
+	SearchModeKeyword = "keyword"
+	SearchModeHybrid  = "hybrid"
+	// SearchModeFilter is reported for queries made only of field
+	// operators, which list matching chunks without ranking them
+	SearchModeFilter = "filter"
+)
+
+// Search limits: the results returned when no limit is given and the most
+// that can be requested
+const (
+	DefaultSearchLimit = 10
+	MaxSearchLimit     = 100
+)
+
+// DefaultHybridWeight weighs vector and keyword ranks equally
+const DefaultHybridWeight = 0.5
+
+// hybridCandidateFactor is how many more candidates than requested results
+// each list contributes to hybrid fusion
+const hybridCandidateFactor = 3
+
+// Candidates fetched for reranking, diversification and grouping, as a
+// multiple of the requested results and in total
+const (
+	rerankCandidateFactor    = 4
+	diversityCandidateFactor = 4
+	maxCandidates            = 100
+)
+
+// DefaultMMRLambda favors relevance over diversity
+const DefaultMMRLambda = 0.7
+
+// exclusionCandidateFactor over-fetches candidates when excluded words may
+// remove some of them
+const exclusionCandidateFactor = 2
+
+type SearchRequest struct {
+	Query               string        `json:"query"`
+	Limit               int           `json:"limit"`
+	Filters             SearchFilters `json:"filters"`
+	IncludeContext      bool          `json:"include_context"`
+	SimilarityThreshold float32       `json:"similarity_threshold"`
+	// Mode is SearchModeVector, SearchModeKeyword or SearchModeHybrid
+	Mode string `json:"mode"`
+	// HybridWeight is the share of the vector ranking in hybrid mode, from
+	// 0 (keyword only) to 1 (vector only)
+	HybridWeight *float64 `json:"hybrid_weight"`
+	// Rerank reorders an over-fetched candidate list with the reranker
+	// before returning the top Limit results
+	Rerank bool `json:"rerank"`
+	// RerankCandidates overrides the number of candidates reranked
+	RerankCandidates int `json:"rerank_candidates"`
+	// Diversify reorders candidates by maximal marginal relevance so that
+	// near duplicates do not crowd out other matches
+	Diversify bool `json:"diversify"`
+	// MMRLambda trades relevance (1) against diversity (0)
+	MMRLambda *float64 `json:"mmr_lambda"`
+	// GroupByDocument returns each document's best chunk once, with the
+	// number of its chunks that matched
+	GroupByDocument bool `json:"group_by_document"`
 }
 
 type SearchFilters struct {
-	SourceTypes []string   `json:"source_types"`
-	DateRange   *DateRange `json:"date_range"`
-	Tags        []string   `json:"tags"`
+	SourceTypes        []string `json:"source_types"`
+	ExcludeSourceTypes []string `json:"exclude_source_types"`
+	// Title matches documents whose title contains it, ignoring case
+	Title string `json:"title"`
+	// ExcludeTerms drops results containing any of the words or phrases
+	ExcludeTerms []string   `json:"exclude_terms"`
+	DateRange    *DateRange `json:"date_range"`
+	// Tags matches chunks with any of the tags, TagsAll chunks with all of
+	// them and TagsNone chunks with none of them
+	Tags     []string `json:"tags"`
+	TagsAll  []string `json:"tags_all"`
+	TagsNone []string `json:"tags_none"`
+	// DocumentIDs limits results to chunks of these documents
+	DocumentIDs    []string   `json:"document_ids"`
+	Correspondents []string   `json:"correspondents"`
+	SentDateRange  *DateRange `json:"sent_date_range"`
 }
 
+// DateRange bounds are inclusive; a zero bound is open
 type DateRange struct {
 	From time.Time `json:"from"`
 	To   time.Time `json:"to"`
 }
 
 type SearchResult struct {
-	ID              string                 `json:"id"`
-	Content         string                 `json:"content"`
-	SimilarityScore float32                `json:"similarity_score"`
-	Source          SearchSource           `json:"source"`
-	Metadata        map[string]interface{} `json:"metadata"`
-	Context         *SearchContext         `json:"context,omitempty"`
+	ID              string  `json:"id"`
+	Content         string  `json:"content"`
+	SimilarityScore float32 `json:"similarity_score"`
+	// KeywordScore is the BM25 score of keyword and hybrid matches
+	KeywordScore float32 `json:"keyword_score,omitempty"`
+	// Score is the score results are ordered by: the similarity, the
+	// keyword score or the fused rank score depending on the mode
+	Score float32 `json:"score"`
+	// OriginalScore and RerankScore are set on reranked results: the score
+	// before reranking and the reranker's score, which becomes Score
+	OriginalScore *float32 `json:"original_score,omitempty"`
+	RerankScore   *float32 `json:"rerank_score,omitempty"`
+	// MatchCount is the number of matching chunks of the document when
+	// results are grouped by document
+	MatchCount int                    `json:"match_count,omitempty"`
+	Source     SearchSource           `json:"source"`
+	Metadata   map[string]interface{} `json:"metadata"`
+	Location   *ChunkLocation         `json:"location,omitempty"`
+	Context    *SearchContext         `json:"context,omitempty"`
 }
 
 type SearchSource struct {
@@ -50,63 +152,325 @@ type SearchContext struct {
 type SearchResponse struct {
 	Results              []SearchResult `json:"results"`
 	TotalResults         int            `json:"total_results"`
+	Mode                 string         `json:"mode"`
+	Reranked             bool           `json:"reranked"`
+	Diversified          bool           `json:"diversified"`
 	QueryEmbeddingTimeMs int64          `json:"query_embedding_time_ms"`
 	SearchTimeMs         int64          `json:"search_time_ms"`
+	// CacheHit is set when the response was served from the cache, and
+	// SemanticCacheHit when it was cached for a query with a near
+	// identical embedding
+	CacheHit          bool `json:"cache_hit"`
+	SemanticCacheHit  bool `json:"semantic_cache_hit"`
+	EmbeddingCacheHit bool `json:"embedding_cache_hit"`
+	// ParsedQuery shows the free text and field operators found in the
+	// query
+	ParsedQuery *query.Parsed `json:"parsed_query,omitempty"`
 }
 
-func NewSearchService(pinecone *database.PineconeClient, redis *database.RedisClient, embeddingService *EmbeddingService) *SearchService {
+func NewSearchService(pinecone *database.PineconeClient, cache *SearchCache, queryEmbeddings *EmbeddingCache, documentService *DocumentService, keywords *KeywordIndex, reranker rerank.Reranker) *SearchService {
 	return &SearchService{
-		pinecone:         pinecone,
-		redis:            redis,
-		embeddingService: embeddingService,
+		pinecone:        pinecone,
+		cache:           cache,
+		queryEmbeddings: queryEmbeddings,
+		documentService: documentService,
+		keywords:        keywords,
+		reranker:        reranker,
+	}
+}
+
+func IsValidSearchMode(mode string) bool {
+	return mode == "" || mode == SearchModeVector || mode == SearchModeKeyword || mode == SearchModeHybrid
+}
+
+// withSearchDefaults fills in the mode, limit, hybrid weight and MMR lambda
+// of a request, clamping the limit to [1, MaxSearchLimit] and the latter two
+// to [0, 1], so that requests relying on the defaults are the same as those
+// setting them
+func withSearchDefaults(req SearchRequest) SearchRequest {
+	if req.Mode == "" {
+		req.Mode = SearchModeVector
+	}
+	if req.Limit < 1 {
+		req.Limit = DefaultSearchLimit
+	}
+	req.Limit = min(req.Limit, MaxSearchLimit)
+	weight := DefaultHybridWeight
+	if req.HybridWeight != nil {
+		weight = math.Max(0, math.Min(1, *req.HybridWeight))
+	}
+
+	lambda := DefaultMMRLambda
+	if req.MMRLambda != nil {
+		lambda = math.Max(0, math.Min(1, *req.MMRLambda))
 	}
+
+	req.HybridWeight = &weight
+	req.MMRLambda = &lambda
+	return req
 }
 
-func (s *SearchService) Search(ctx context.Context, userID, query string, limit int, filters SearchFilters, similarityThreshold float32) (*SearchResponse, error) {
-	// Check cache first
-	cacheKey := fmt.Sprintf("search:%s:%s:%d", userID, query, limit)
-	if cached, err := s.redis.Get(cacheKey); err == nil {
-		var response SearchResponse
-		if json.Unmarshal([]byte(cached), &response) == nil {
-			return &response, nil
+func (s *SearchService) Search(ctx context.Context, userID string, req SearchRequest) (*SearchResponse, error) {
+	req = withSearchDefaults(req)
+	weight, lambda := *req.HybridWeight, *req.MMRLambda
+
+	// Check cache first, keyed by the request with its defaults applied
+	cacheKey := s.cache.Key(userID, req)
+	if cached, ok := s.cache.Get(userID, cacheKey); ok {
+		cached.CacheHit = true
+		return cached, nil
+	}
+
+	// Field operators become filters and only the free text is searched
+	parsed := query.Parse(req.Query)
+	req.Filters = withQueryFilters(req.Filters, parsed)
+	text := parsed.Text
+
+	response := &SearchResponse{Mode: req.Mode, ParsedQuery: &parsed}
+
+	filter := vectorFilter(userID, req.Filters)
+	if req.Filters.Title != "" && s.documentService != nil {
+		documentIDs, err := s.documentService.documentIDsByTitle(ctx, userID, req.Filters.Title)
+		if err != nil {
+			return nil, err
+		}
+		if len(documentIDs) == 0 {
+			response.Results = []SearchResult{}
+			return response, nil
 		}
+		filter["document_id"] = map[string]interface{}{"$in": documentIDs}
 	}
 
-	// Generate embedding for query
-	embeddingStart := time.Now()
-	queryEmbedding, err := s.embeddingService.GenerateEmbedding(query)
-	if err != nil {
-		return nil, err
+	// Reranking needs a longer list to choose from, and excluded words
+	// remove results after retrieval
+	fetch := req.Limit
+	if len(req.Filters.ExcludeTerms) > 0 {
+		fetch = req.Limit * exclusionCandidateFactor
+	}
+	if req.Rerank && s.reranker != nil {
+		fetch = max(fetch, rerankCandidates(req.Limit, req.RerankCandidates))
+	}
+	if req.Diversify || req.GroupByDocument {
+		fetch = max(fetch, min(req.Limit*diversityCandidateFactor, maxCandidates))
 	}
-	embeddingTime := time.Since(embeddingStart).Milliseconds()
 
-	// Build Pinecone filter
-	pineconeFilter := map[string]interface{}{
+	candidates := fetch
+	if req.Mode == SearchModeHybrid {
+		candidates = fetch * hybridCandidateFactor
+	}
+
+	var results []SearchResult
+	var queryEmbedding []float32
+	if text == "" && len(parsed.Filters) > 0 {
+		// Without free text there is nothing to rank by, so list the
+		// chunks matching the filters, newest first
+		if s.keywords == nil {
+			return nil, fmt.Errorf("filter-only search is not available")
+		}
+		response.Mode = SearchModeFilter
+		searchStart := time.Now()
+		var err error
+		results, err = s.keywords.List(ctx, userID, fetch, filter)
+		if err != nil {
+			return nil, err
+		}
+		response.SearchTimeMs = time.Since(searchStart).Milliseconds()
+	} else {
+		var vectorResults, keywordResults []SearchResult
+		if req.Mode != SearchModeKeyword {
+			// Generate embedding for query
+			embeddingStart := time.Now()
+			var err error
+			queryEmbedding, response.EmbeddingCacheHit, err = s.queryEmbeddings.Embed(userID, text)
+			if err != nil {
+				return nil, err
+			}
+			response.QueryEmbeddingTimeMs = time.Since(embeddingStart).Milliseconds()
+
+			// A query meaning the same as a cached one, with the same
+			// options, reuses its results. Keyword matches in hybrid mode
+			// depend on the exact words, so only vector searches do.
+			if req.Mode == SearchModeVector {
+				if similar, ok := s.cache.Similar(userID, req, queryEmbedding); ok {
+					similar.CacheHit = true
+					similar.SemanticCacheHit = true
+					similar.EmbeddingCacheHit = response.EmbeddingCacheHit
+					similar.ParsedQuery = &parsed
+					return similar, nil
+				}
+			}
+
+			searchStart := time.Now()
+			vectorResults, err = s.vectorSearch(queryEmbedding, candidates, filter, req.SimilarityThreshold)
+			if err != nil {
+				return nil, err
+			}
+			response.SearchTimeMs = time.Since(searchStart).Milliseconds()
+		}
+
+		if req.Mode != SearchModeVector {
+			if s.keywords == nil {
+				return nil, fmt.Errorf("keyword search is not available")
+			}
+			searchStart := time.Now()
+			var err error
+			keywordResults, err = s.keywords.Search(ctx, userID, text, candidates, filter)
+			if err != nil {
+				return nil, err
+			}
+			response.SearchTimeMs += time.Since(searchStart).Milliseconds()
+		}
+
+		switch req.Mode {
+		case SearchModeKeyword:
+			results = keywordResults
+		case SearchModeHybrid:
+			results = fuseResults(vectorResults, keywordResults, weight, fetch)
+		default:
+			results = vectorResults
+		}
+	}
+
+	results = excludeTerms(results, req.Filters.ExcludeTerms)
+
+	if req.Rerank && s.reranker != nil && text != "" {
+		rerankStart := time.Now()
+		reranked, err := s.rerankResults(ctx, text, results)
+		if err != nil {
+			log.Printf("Warning: Reranking failed, returning results in their original order: %v", err)
+		} else {
+			results = reranked
+			response.Reranked = true
+		}
+		response.SearchTimeMs += time.Since(rerankStart).Milliseconds()
+	}
+
+	if req.Diversify && len(results) > 1 {
+		diversified, err := s.diversify(results, lambda)
+		if err != nil {
+			log.Printf("Warning: Diversification failed, returning results in their original order: %v", err)
+		} else {
+			results = diversified
+			response.Diversified = true
+		}
+	}
+
+	if req.GroupByDocument {
+		results = groupByDocument(results)
+	}
+
+	if len(results) > req.Limit {
+		results = results[:req.Limit]
+	}
+
+	if req.IncludeContext && s.documentService != nil {
+		s.documentService.AttachContext(ctx, userID, results)
+	}
+
+	response.Results = results
+	response.TotalResults = len(results)
+
+	s.cache.Set(cacheKey, response)
+	if req.Mode == SearchModeVector && queryEmbedding != nil {
+		s.cache.Remember(userID, req, cacheKey, queryEmbedding)
+	}
+
+	return response, nil
+}
+
+// vectorFilter builds the Pinecone metadata filter for a user's search
+func vectorFilter(userID string, filters SearchFilters) map[string]interface{} {
+	filter := map[string]interface{}{
 		"user_id": userID,
 	}
 
 	if len(filters.SourceTypes) > 0 {
-		pineconeFilter["source_type"] = map[string]interface{}{
+		filter["source_type"] = map[string]interface{}{
 			"$in": filters.SourceTypes,
 		}
 	}
 
 	if filters.DateRange != nil {
-		pineconeFilter["created_at"] = map[string]interface{}{
-			"$gte": filters.DateRange.From.Unix(),
-			"$lte": filters.DateRange.To.Unix(),
+		if condition := dateCondition(filters.DateRange); condition != nil {
+			filter["created_at"] = condition
+		}
+	}
+
+	// Email chunks carry sender and recipient addresses as participants
+	if len(filters.Correspondents) > 0 {
+		correspondents := make([]string, len(filters.Correspondents))
+		for i, address := range filters.Correspondents {
+			correspondents[i] = strings.ToLower(strings.TrimSpace(address))
+		}
+		filter["participants"] = map[string]interface{}{
+			"$in": correspondents,
+		}
+	}
+
+	if filters.SentDateRange != nil {
+		if condition := dateCondition(filters.SentDateRange); condition != nil {
+			filter["sent_at"] = condition
 		}
 	}
 
-	// Perform search
-	searchStart := time.Now()
-	queryResponse, err := s.pinecone.Query(queryEmbedding, limit, pineconeFilter)
+	// Tags are a list field, so each condition is a separate clause, as are
+	// the exclusion of source types, which may also be included above, and
+	// the documents, which a title filter may also restrict
+	var conditions []interface{}
+	if len(filters.DocumentIDs) > 0 {
+		conditions = append(conditions, map[string]interface{}{
+			"document_id": map[string]interface{}{"$in": filters.DocumentIDs},
+		})
+	}
+	if len(filters.ExcludeSourceTypes) > 0 {
+		conditions = append(conditions, map[string]interface{}{
+			"source_type": map[string]interface{}{"$nin": filters.ExcludeSourceTypes},
+		})
+	}
+	if tags := parsers.NormalizeTags(filters.Tags); len(tags) > 0 {
+		conditions = append(conditions, map[string]interface{}{
+			parsers.TagsKey: map[string]interface{}{"$in": tags},
+		})
+	}
+	for _, tag := range parsers.NormalizeTags(filters.TagsAll) {
+		conditions = append(conditions, map[string]interface{}{
+			parsers.TagsKey: map[string]interface{}{"$in": []string{tag}},
+		})
+	}
+	if tags := parsers.NormalizeTags(filters.TagsNone); len(tags) > 0 {
+		conditions = append(conditions, map[string]interface{}{
+			parsers.TagsKey: map[string]interface{}{"$nin": tags},
+		})
+	}
+	if len(conditions) > 0 {
+		filter["$and"] = conditions
+	}
+
+	return filter
+}
+
+// dateCondition converts a date range to a filter on Unix timestamps
+func dateCondition(dateRange *DateRange) map[string]interface{} {
+	condition := map[string]interface{}{}
+	if !dateRange.From.IsZero() {
+		condition["$gte"] = dateRange.From.Unix()
+	}
+	if !dateRange.To.IsZero() {
+		condition["$lte"] = dateRange.To.Unix()
+	}
+	if len(condition) == 0 {
+		return nil
+	}
+	return condition
+}
+
+// vectorSearch returns the chunks most similar to embedding
+func (s *SearchService) vectorSearch(embedding []float32, limit int, filter map[string]interface{}, similarityThreshold float32) ([]SearchResult, error) {
+	queryResponse, err := s.pinecone.Query(embedding, limit, filter)
 	if err != nil {
 		return nil, err
 	}
-	searchTime := time.Since(searchStart).Milliseconds()
 
-	// Process results
 	var results []SearchResult
 	for _, match := range queryResponse.Matches {
 		if match.Score < similarityThreshold {
@@ -114,37 +478,162 @@ func (s *SearchService) Search(ctx context.Context, userID, query string, limit
 		}
 
 		// Convert metadata from structpb.Struct to map
-		metadataMap := match.Vector.Metadata.AsMap()
+		result := newSearchResult(match.Vector.Id, match.Vector.Metadata.AsMap())
+		result.SimilarityScore = match.Score
+		result.Score = match.Score
+		results = append(results, result)
+	}
+	return results, nil
+}
 
-		result := SearchResult{
-			ID:              match.Vector.Id,
-			Content:         getStringFromMetadata(metadataMap, "content"),
-			SimilarityScore: match.Score,
-			Source: SearchSource{
-				DocumentID:   getStringFromMetadata(metadataMap, "document_id"),
-				Title:        getStringFromMetadata(metadataMap, "title"),
-				Type:         getStringFromMetadata(metadataMap, "source_type"),
-				OriginalPath: getStringFromMetadata(metadataMap, "original_path"),
-			},
-			Metadata: metadataMap,
+// fuseResults merges vector and keyword results with reciprocal rank
+// fusion, giving the vector ranking the share weight
+func fuseResults(vectorResults, keywordResults []SearchResult, weight float64, limit int) []SearchResult {
+	byID := make(map[string]SearchResult, len(vectorResults)+len(keywordResults))
+	lists := make([][]string, 2)
+	for _, result := range vectorResults {
+		byID[result.ID] = result
+		lists[0] = append(lists[0], result.ID)
+	}
+	for _, result := range keywordResults {
+		if existing, ok := byID[result.ID]; ok {
+			existing.KeywordScore = result.KeywordScore
+			byID[result.ID] = existing
+		} else {
+			byID[result.ID] = result
 		}
+		lists[1] = append(lists[1], result.ID)
+	}
 
+	var results []SearchResult
+	for _, fused := range ranking.Fuse(lists, []float64{weight, 1 - weight}) {
+		if len(results) == limit {
+			break
+		}
+		result := byID[fused.ID]
+		result.Score = float32(fused.Score)
 		results = append(results, result)
 	}
+	return results
+}
 
-	response := &SearchResponse{
-		Results:              results,
-		TotalResults:         len(results),
-		QueryEmbeddingTimeMs: embeddingTime,
-		SearchTimeMs:         searchTime,
+// diversify orders results by maximal marginal relevance, comparing the
+// chunks' stored vectors
+func (s *SearchService) diversify(results []SearchResult, lambda float64) ([]SearchResult, error) {
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+	stored, err := s.pinecone.Fetch(ids)
+	if err != nil {
+		return nil, err
 	}
 
-	// Cache results for 1 hour
-	if responseData, err := json.Marshal(response); err == nil {
-		s.redis.Set(cacheKey, string(responseData), time.Hour)
+	// Scores are on different scales depending on the mode, so relevance
+	// is relative to the best result
+	maxScore := float32(0)
+	for _, result := range results {
+		if result.Score > maxScore {
+			maxScore = result.Score
+		}
 	}
 
-	return response, nil
+	relevance := make([]float64, len(results))
+	vectors := make([][]float32, len(results))
+	for i, result := range results {
+		if maxScore > 0 {
+			relevance[i] = float64(result.Score / maxScore)
+		}
+		vectors[i] = stored[result.ID].Values
+	}
+
+	order := ranking.MMR(relevance, vectors, lambda, len(results))
+	diversified := make([]SearchResult, len(order))
+	for i, index := range order {
+		diversified[i] = results[index]
+	}
+	return diversified, nil
+}
+
+// groupByDocument keeps the best result of each document, in order, and
+// counts the document's matching chunks
+func groupByDocument(results []SearchResult) []SearchResult {
+	positions := make(map[string]int)
+	var grouped []SearchResult
+	for _, result := range results {
+		key := result.Source.DocumentID
+		if key == "" {
+			key = result.ID
+		}
+		if i, ok := positions[key]; ok {
+			grouped[i].MatchCount++
+			continue
+		}
+		positions[key] = len(grouped)
+		result.MatchCount = 1
+		grouped = append(grouped, result)
+	}
+	return grouped
+}
+
+// rerankCandidates returns how many candidates to rerank for limit results
+func rerankCandidates(limit, requested int) int {
+	candidates := requested
+	if candidates <= 0 {
+		candidates = limit * rerankCandidateFactor
+	}
+	if candidates > maxCandidates {
+		candidates = maxCandidates
+	}
+	if candidates < limit {
+		candidates = limit
+	}
+	return candidates
+}
+
+// rerankResults scores results against the query with the reranker and
+// orders them by that score
+func (s *SearchService) rerankResults(ctx context.Context, query string, results []SearchResult) ([]SearchResult, error) {
+	passages := make([]string, len(results))
+	for i, result := range results {
+		passages[i] = result.Content
+		if result.Source.Title != "" {
+			passages[i] = result.Source.Title + "\n\n" + result.Content
+		}
+	}
+
+	scores, err := s.reranker.Rerank(ctx, query, passages)
+	if err != nil {
+		return nil, err
+	}
+
+	reranked := make([]SearchResult, len(results))
+	for i, result := range results {
+		original := result.Score
+		score := float32(scores[i])
+		result.OriginalScore = &original
+		result.RerankScore = &score
+		result.Score = score
+		reranked[i] = result
+	}
+	sort.SliceStable(reranked, func(i, j int) bool { return reranked[i].Score > reranked[j].Score })
+	return reranked, nil
+}
+
+// newSearchResult builds a result from the metadata stored with a chunk
+func newSearchResult(id string, metadata map[string]interface{}) SearchResult {
+	return SearchResult{
+		ID:      id,
+		Content: getStringFromMetadata(metadata, "content"),
+		Source: SearchSource{
+			DocumentID:   getStringFromMetadata(metadata, "document_id"),
+			Title:        getStringFromMetadata(metadata, "title"),
+			Type:         getStringFromMetadata(metadata, "source_type"),
+			OriginalPath: getStringFromMetadata(metadata, "original_path"),
+		},
+		Metadata: metadata,
+		Location: chunkLocation(metadata),
+	}
 }
 
 func getStringFromMetadata(metadata map[string]interface{}, key string) string {
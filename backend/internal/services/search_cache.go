@@ -0,0 +1,223 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"zettelkasten/internal/database"
+	"zettelkasten/internal/parsers"
+	"zettelkasten/internal/ranking"
+)
+
+// searchCacheTTL is how long a search response is cached
+const searchCacheTTL = time.Hour
+
+// semanticCacheEntries bounds the queries remembered for the semantic
+// cache per user and set of search options
+const semanticCacheEntries = 50
+
+// SearchCache caches search responses in Redis. A key hashes the whole
+// request along with the user's cache generation, which is bumped whenever
+// one of the user's documents changes so that earlier responses are no
+// longer found and expire on their own.
+//
+// With a semantic tolerance, a response is also reused for a request that
+// differs only in its query when the queries' embeddings are within that
+// cosine distance.
+type SearchCache struct {
+	redis             *database.RedisClient
+	semanticTolerance float64
+	metrics           *CacheMetrics
+}
+
+// semanticEntry is a cached response's key and query embedding
+type semanticEntry struct {
+	Key       string `json:"key"`
+	Embedding []byte `json:"embedding"`
+}
+
+func NewSearchCache(redis *database.RedisClient, semanticTolerance float64, metrics *CacheMetrics) *SearchCache {
+	return &SearchCache{
+		redis:             redis,
+		semanticTolerance: semanticTolerance,
+		metrics:           metrics,
+	}
+}
+
+// Key returns the cache key of a request, whose defaults must already be
+// applied so that equivalent requests share a key
+func (c *SearchCache) Key(userID string, req SearchRequest) string {
+	return fmt.Sprintf("search:%s:%d:%s", userID, c.generation(userID), requestHash(req))
+}
+
+func (c *SearchCache) Get(userID, key string) (*SearchResponse, bool) {
+	response, ok := c.load(key)
+	if ok {
+		c.metrics.Add(userID, metricSearchHits, 1)
+	} else {
+		c.metrics.Add(userID, metricSearchMisses, 1)
+	}
+	return response, ok
+}
+
+func (c *SearchCache) load(key string) (*SearchResponse, bool) {
+	cached, err := c.redis.Get(key)
+	if err != nil {
+		return nil, false
+	}
+	var response SearchResponse
+	if err := json.Unmarshal([]byte(cached), &response); err != nil {
+		return nil, false
+	}
+	return &response, true
+}
+
+// Similar returns the cached response of the remembered query closest to
+// embedding, if within the semantic tolerance. req must carry the filters
+// of its query's field operators, as its query text is ignored.
+func (c *SearchCache) Similar(userID string, req SearchRequest, embedding []float32) (*SearchResponse, bool) {
+	if c.semanticTolerance <= 0 {
+		return nil, false
+	}
+
+	entries, err := c.redis.LRange(c.semanticKey(userID, req), 0, -1)
+	if err != nil {
+		return nil, false
+	}
+
+	bestKey := ""
+	best := 1 - c.semanticTolerance
+	for _, data := range entries {
+		var entry semanticEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			continue
+		}
+		cached, ok := decodeEmbedding(string(entry.Embedding))
+		if !ok {
+			continue
+		}
+		if similarity := ranking.Cosine(embedding, cached); similarity >= best {
+			best, bestKey = similarity, entry.Key
+		}
+	}
+	if bestKey == "" {
+		return nil, false
+	}
+
+	response, ok := c.load(bestKey)
+	if ok {
+		// The exact lookup already counted a miss
+		c.metrics.Add(userID, metricSearchMisses, -1)
+		c.metrics.Add(userID, metricSemanticHits, 1)
+	}
+	return response, ok
+}
+
+// Remember records the query embedding of a cached response for semantic
+// lookups
+func (c *SearchCache) Remember(userID string, req SearchRequest, key string, embedding []float32) {
+	if c.semanticTolerance <= 0 {
+		return
+	}
+
+	data, err := json.Marshal(semanticEntry{Key: key, Embedding: encodeEmbedding(embedding)})
+	if err != nil {
+		return
+	}
+	listKey := c.semanticKey(userID, req)
+	if err := c.redis.LPush(listKey, string(data)); err != nil {
+		log.Printf("Warning: Failed to remember query for the semantic cache: %v", err)
+		return
+	}
+	c.redis.LTrim(listKey, 0, semanticCacheEntries-1)
+	c.redis.Expire(listKey, searchCacheTTL)
+}
+
+// semanticKey identifies the requests that differ from req only in their
+// query
+func (c *SearchCache) semanticKey(userID string, req SearchRequest) string {
+	req.Query = ""
+	return fmt.Sprintf("search:semantic:%s:%d:%s", userID, c.generation(userID), requestHash(req))
+}
+
+// requestHash hashes the canonical form of a request for cache keys
+func requestHash(req SearchRequest) string {
+	data, _ := json.Marshal(canonicalSearchRequest(req))
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *SearchCache) Set(key string, response *SearchResponse) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+	if err := c.redis.Set(key, string(data), searchCacheTTL); err != nil {
+		log.Printf("Warning: Failed to cache search response: %v", err)
+	}
+}
+
+// Invalidate bumps the user's cache generation. It is safe to call on a
+// nil cache.
+func (c *SearchCache) Invalidate(userID string) {
+	if c == nil {
+		return
+	}
+	if _, err := c.redis.Incr(searchGenerationKey(userID)); err != nil {
+		log.Printf("Warning: Failed to invalidate search cache of user %s: %v", userID, err)
+	}
+}
+
+func (c *SearchCache) generation(userID string) int64 {
+	value, err := c.redis.Get(searchGenerationKey(userID))
+	if err != nil {
+		return 0
+	}
+	generation, _ := strconv.ParseInt(value, 10, 64)
+	return generation
+}
+
+func searchGenerationKey(userID string) string {
+	return "search:generation:" + userID
+}
+
+// canonicalSearchRequest orders and normalizes the parts of a request that
+// do not change its results, e.g. the order of listed filter values
+func canonicalSearchRequest(req SearchRequest) SearchRequest {
+	req.Query = strings.TrimSpace(req.Query)
+
+	filters := req.Filters
+	filters.SourceTypes = sortedCopy(filters.SourceTypes)
+	filters.ExcludeSourceTypes = sortedCopy(filters.ExcludeSourceTypes)
+	filters.ExcludeTerms = sortedCopy(filters.ExcludeTerms)
+	filters.DocumentIDs = sortedCopy(filters.DocumentIDs)
+	filters.Correspondents = sortedCopy(filters.Correspondents)
+	filters.Tags = parsers.NormalizeTags(filters.Tags)
+	filters.TagsAll = parsers.NormalizeTags(filters.TagsAll)
+	filters.TagsNone = parsers.NormalizeTags(filters.TagsNone)
+	filters.DateRange = utcDateRange(filters.DateRange)
+	filters.SentDateRange = utcDateRange(filters.SentDateRange)
+	req.Filters = filters
+
+	return req
+}
+
+func sortedCopy(values []string) []string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func utcDateRange(dateRange *DateRange) *DateRange {
+	if dateRange == nil {
+		return nil
+	}
+	return &DateRange{From: dateRange.From.UTC(), To: dateRange.To.UTC()}
+}
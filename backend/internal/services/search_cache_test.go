@@ -0,0 +1,94 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRequestHash(t *testing.T) {
+	clamped, full := 1.5, 1.0
+	berlin := time.FixedZone("CEST", 2*60*60)
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		a, b  SearchRequest
+		equal bool
+	}{
+		{
+			name:  "Filter order",
+			a:     SearchRequest{Query: "notes", Filters: SearchFilters{SourceTypes: []string{"obsidian", "notion"}, DocumentIDs: []string{"b", "a"}}},
+			b:     SearchRequest{Query: "notes", Filters: SearchFilters{SourceTypes: []string{"notion", "obsidian"}, DocumentIDs: []string{"a", "b"}}},
+			equal: true,
+		},
+		{
+			name:  "Tag case and order",
+			a:     SearchRequest{Query: "notes", Filters: SearchFilters{Tags: []string{"#Project", "draft"}}},
+			b:     SearchRequest{Query: "notes", Filters: SearchFilters{Tags: []string{"draft", "project"}}},
+			equal: true,
+		},
+		{
+			name:  "Query spacing",
+			a:     SearchRequest{Query: "  notes "},
+			b:     SearchRequest{Query: "notes"},
+			equal: true,
+		},
+		{
+			name:  "Defaults",
+			a:     SearchRequest{Query: "notes"},
+			b:     SearchRequest{Query: "notes", Mode: SearchModeVector, HybridWeight: floatPointer(DefaultHybridWeight), MMRLambda: floatPointer(DefaultMMRLambda)},
+			equal: true,
+		},
+		{
+			name:  "Clamped weight",
+			a:     SearchRequest{Query: "notes", HybridWeight: &clamped},
+			b:     SearchRequest{Query: "notes", HybridWeight: &full},
+			equal: true,
+		},
+		{
+			name:  "Limit capped",
+			a:     SearchRequest{Query: "notes", Limit: 500},
+			b:     SearchRequest{Query: "notes", Limit: MaxSearchLimit},
+			equal: true,
+		},
+		{
+			name:  "Negative limit defaulted",
+			a:     SearchRequest{Query: "notes", Limit: -1},
+			b:     SearchRequest{Query: "notes", Limit: DefaultSearchLimit},
+			equal: true,
+		},
+		{
+			name:  "Date range time zone",
+			a:     SearchRequest{Query: "notes", Filters: SearchFilters{DateRange: &DateRange{From: from.In(berlin), To: to.In(berlin)}}},
+			b:     SearchRequest{Query: "notes", Filters: SearchFilters{DateRange: &DateRange{From: from, To: to}}},
+			equal: true,
+		},
+		{
+			name:  "Different date range",
+			a:     SearchRequest{Query: "notes", Filters: SearchFilters{DateRange: &DateRange{From: from}}},
+			b:     SearchRequest{Query: "notes", Filters: SearchFilters{DateRange: &DateRange{From: to}}},
+			equal: false,
+		},
+		{
+			name:  "Different mode",
+			a:     SearchRequest{Query: "notes", Mode: SearchModeKeyword},
+			b:     SearchRequest{Query: "notes"},
+			equal: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := requestHash(withSearchDefaults(tt.a))
+			b := requestHash(withSearchDefaults(tt.b))
+			if (a == b) != tt.equal {
+				t.Errorf("hashes equal = %v, want %v", a == b, tt.equal)
+			}
+		})
+	}
+}
+
+func floatPointer(value float64) *float64 {
+	return &value
+}
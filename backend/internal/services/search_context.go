@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"zettelkasten/internal/parsers"
+)
+
+// contextBytes is the amount of surrounding text returned on each side of a
+// search result
+const contextBytes = 400
+
+// ChunkLocation is where a chunk appears in the original file
+type ChunkLocation struct {
+	StartOffset int `json:"start_offset"`
+	EndOffset   int `json:"end_offset"`
+	StartLine   int `json:"start_line"`
+	EndLine     int `json:"end_line"`
+}
+
+// chunkLocation reads the location recorded by parsers.LocateChunks from
+// vector metadata
+func chunkLocation(metadata map[string]interface{}) *ChunkLocation {
+	if _, ok := metadata[parsers.EndOffsetKey]; !ok {
+		return nil
+	}
+	return &ChunkLocation{
+		StartOffset: int(getFloatFromMetadata(metadata, parsers.StartOffsetKey)),
+		EndOffset:   int(getFloatFromMetadata(metadata, parsers.EndOffsetKey)),
+		StartLine:   int(getFloatFromMetadata(metadata, parsers.StartLineKey)),
+		EndLine:     int(getFloatFromMetadata(metadata, parsers.EndLineKey)),
+	}
+}
+
+// AttachContext fills in the text before and after each result, taken from
+// the original file when the chunk's location is known and from the
+// neighboring chunks otherwise
+func (s *DocumentService) AttachContext(ctx context.Context, userID string, results []SearchResult) {
+	sources := make(map[string][]byte)
+	var pending []int
+
+	for i := range results {
+		result := &results[i]
+		documentID := result.Source.DocumentID
+
+		if result.Location != nil {
+			source, ok := sources[documentID]
+			if !ok {
+				source = s.textSource(ctx, userID, documentID)
+				sources[documentID] = source
+			}
+			if source != nil && result.Location.EndOffset <= len(source) {
+				result.Context = &SearchContext{
+					Before: textBefore(string(source[:result.Location.StartOffset]), contextBytes),
+					After:  textAfter(string(source[result.Location.EndOffset:]), contextBytes),
+				}
+				continue
+			}
+		}
+		pending = append(pending, i)
+	}
+
+	if len(pending) == 0 {
+		return
+	}
+
+	var ids []string
+	for _, i := range pending {
+		index := int(getFloatFromMetadata(results[i].Metadata, "chunk_index"))
+		documentID := results[i].Source.DocumentID
+		if index > 0 {
+			ids = append(ids, fmt.Sprintf("%s_%d", documentID, index-1))
+		}
+		ids = append(ids, fmt.Sprintf("%s_%d", documentID, index+1))
+	}
+
+	neighbors, err := s.pinecone.Fetch(ids)
+	if err != nil {
+		return
+	}
+
+	neighborContent := func(documentID string, index int) string {
+		vector, ok := neighbors[fmt.Sprintf("%s_%d", documentID, index)]
+		if !ok || getStringFromMetadata(vector.Metadata, "user_id") != userID {
+			return ""
+		}
+		return getStringFromMetadata(vector.Metadata, "content")
+	}
+
+	for _, i := range pending {
+		index := int(getFloatFromMetadata(results[i].Metadata, "chunk_index"))
+		documentID := results[i].Source.DocumentID
+		results[i].Context = &SearchContext{
+			Before: textBefore(neighborContent(documentID, index-1), contextBytes),
+			After:  textAfter(neighborContent(documentID, index+1), contextBytes),
+		}
+	}
+}
+
+// textSource returns the original file of a document if it is text
+func (s *DocumentService) textSource(ctx context.Context, userID, documentID string) []byte {
+	doc, err := s.GetDocument(ctx, userID, documentID)
+	if err != nil || doc.SourceFileID == nil {
+		return nil
+	}
+
+	data, _, err := s.loadSource(*doc.SourceFileID)
+	if err != nil || !utf8.Valid(data) {
+		return nil
+	}
+	return data
+}
+
+// textBefore returns up to n bytes from the end of text, starting at a word
+// boundary
+func textBefore(text string, n int) string {
+	if len(text) <= n {
+		return strings.TrimSpace(text)
+	}
+
+	start := len(text) - n
+	for start < len(text) && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	if i := strings.IndexFunc(text[start:], unicode.IsSpace); i >= 0 {
+		start += i
+	}
+	return strings.TrimSpace(text[start:])
+}
+
+// textAfter returns up to n bytes from the start of text, ending at a word
+// boundary
+func textAfter(text string, n int) string {
+	if len(text) <= n {
+		return strings.TrimSpace(text)
+	}
+
+	end := n
+	for end > 0 && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	if i := strings.LastIndexFunc(text[:end], unicode.IsSpace); i > 0 {
+		end = i
+	}
+	return strings.TrimSpace(text[:end])
+}
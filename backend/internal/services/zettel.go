@@ -0,0 +1,315 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"zettelkasten/internal/llm"
+	"zettelkasten/internal/models"
+	"zettelkasten/internal/parsers"
+)
+
+// Ingestion modes. The default mode only chunks and embeds documents; zettel
+// mode also rewrites them into atomic notes.
+const (
+	ModeChunks = ""
+	ModeZettel = "zettel"
+)
+
+// ZettelSourceType is the source type of notes extracted in zettel mode
+const ZettelSourceType = "zettel"
+
+// zettelBatchTokens bounds the source text sent to the model per request
+const zettelBatchTokens = 3000
+
+// zettelTemperature keeps extracted notes close to the source text
+const zettelTemperature = 0.2
+
+const zettelPrompt = `You turn documents into atomic notes for a Zettelkasten.
+Each note captures exactly one idea in a few sentences of its own words and must make sense on its own.
+You are given numbered passages. Reply with a JSON object of the form
+{"notes":[{"title":"...","summary":"...","content":"...","links":["..."],"source_chunks":[1]}]}
+where title is a short descriptive title, summary is one sentence, links are titles of related notes
+(from this reply or concepts worth a note of their own) and source_chunks are the numbers of the
+passages the note was taken from.`
+
+// ErrNoLLMProvider is returned when zettel mode, answers or reranking by a
+// model are requested without LLM_PROVIDER set
+var ErrNoLLMProvider = errors.New("no LLM provider is configured")
+
+func IsValidMode(mode string) bool {
+	return mode == ModeChunks || mode == ModeZettel
+}
+
+// ZettelsAvailable reports whether a model is configured to extract notes
+func (s *DocumentService) ZettelsAvailable() bool {
+	return s.llm != nil
+}
+
+// zettelNote is a note as returned by the model
+type zettelNote struct {
+	Title        string   `json:"title"`
+	Summary      string   `json:"summary"`
+	Content      string   `json:"content"`
+	Links        []string `json:"links"`
+	SourceChunks []int    `json:"source_chunks"`
+}
+
+// extractZettels rewrites the chunks of source into atomic notes and stores
+// each one as a zettel document
+func (s *DocumentService) extractZettels(ctx context.Context, userID string, source *models.Document, chunks []parsers.Chunk) error {
+	if s.llm == nil {
+		return ErrNoLLMProvider
+	}
+
+	var created []*models.Document
+	for _, batch := range s.zettelBatches(chunks) {
+		notes, err := s.requestZettels(ctx, chunks, batch)
+		if err != nil {
+			return fmt.Errorf("zettel extraction failed: %w", err)
+		}
+
+		for _, note := range notes {
+			doc, err := s.storeZettel(ctx, userID, source, note, batch)
+			if err != nil {
+				return err
+			}
+			if doc != nil {
+				created = append(created, doc)
+			}
+		}
+	}
+
+	log.Printf("Extracted %d zettels from document: %s", len(created), source.Title)
+	s.resolveZettelLinks(ctx, userID, created)
+	return nil
+}
+
+// zettelBatches groups consecutive chunk indexes so that each group fits
+// the model's input budget
+func (s *DocumentService) zettelBatches(chunks []parsers.Chunk) [][]int {
+	var batches [][]int
+	var current []int
+	tokens := 0
+	for i, chunk := range chunks {
+		n := s.tokenizer.Count(chunk.Content)
+		if len(current) > 0 && tokens+n > zettelBatchTokens {
+			batches = append(batches, current)
+			current, tokens = nil, 0
+		}
+		current = append(current, i)
+		tokens += n
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
+
+func (s *DocumentService) requestZettels(ctx context.Context, chunks []parsers.Chunk, batch []int) ([]zettelNote, error) {
+	var passages strings.Builder
+	for i, index := range batch {
+		fmt.Fprintf(&passages, "[%d]\n%s\n\n", i+1, chunks[index].Content)
+	}
+
+	temperature := zettelTemperature
+	resp, err := s.llm.Complete(ctx, llm.Request{
+		Messages: []llm.Message{
+			{Role: "system", Content: zettelPrompt},
+			{Role: "user", Content: passages.String()},
+		},
+		Temperature: &temperature,
+		JSON:        true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Zettel extraction used %d tokens (%d prompt, %d completion)",
+		resp.Usage.TotalTokens, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+
+	return parseZettelNotes(resp.Content)
+}
+
+// parseZettelNotes decodes the model's reply, which may be wrapped in a
+// Markdown code fence
+func parseZettelNotes(content string) ([]zettelNote, error) {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "```") {
+		content = strings.TrimPrefix(content, "```json")
+		content = strings.TrimPrefix(content, "```")
+		content = strings.TrimSuffix(strings.TrimSpace(content), "```")
+	}
+
+	var reply struct {
+		Notes []zettelNote `json:"notes"`
+	}
+	if err := json.Unmarshal([]byte(content), &reply); err != nil {
+		return nil, fmt.Errorf("invalid model response: %w", err)
+	}
+	return reply.Notes, nil
+}
+
+// storeZettel stores one note, returning nil if the note is empty. The
+// note's passage numbers refer to positions in batch.
+func (s *DocumentService) storeZettel(ctx context.Context, userID string, source *models.Document, note zettelNote, batch []int) (*models.Document, error) {
+	note.Title = strings.TrimSpace(note.Title)
+	note.Content = strings.TrimSpace(note.Content)
+	if note.Content == "" {
+		return nil, nil
+	}
+	if note.Title == "" {
+		note.Title = truncateString(note.Content, 60)
+	}
+
+	var chunkIDs []string
+	for _, n := range note.SourceChunks {
+		if n >= 1 && n <= len(batch) {
+			chunkIDs = append(chunkIDs, fmt.Sprintf("%s_%d", source.ID.Hex(), batch[n-1]))
+		}
+	}
+
+	var links []models.ZettelLink
+	var titles []string
+	for _, title := range note.Links {
+		if title = strings.TrimSpace(title); title != "" && !strings.EqualFold(title, note.Title) {
+			links = append(links, models.ZettelLink{Title: title})
+			titles = append(titles, title)
+		}
+	}
+
+	doc := &models.Document{
+		Title:      note.Title,
+		SourceType: ZettelSourceType,
+		Tags:       source.Tags,
+		Metadata: map[string]interface{}{
+			"source_title": source.Title,
+		},
+		Zettel: &models.Zettel{
+			Summary:          strings.TrimSpace(note.Summary),
+			SuggestedLinks:   links,
+			SourceDocumentID: source.ID.Hex(),
+			SourceChunkIDs:   chunkIDs,
+		},
+	}
+
+	// The note is a single chunk, embedded under its title
+	chunk := parsers.Chunk{
+		Content: note.Content,
+		Metadata: map[string]interface{}{
+			parsers.HeadingPathKey: []string{note.Title},
+			"summary":              doc.Zettel.Summary,
+			"source_document_id":   source.ID.Hex(),
+		},
+	}
+	// The model's links count as wikilinks of the note
+	doc.Links = appendLinks(documentLinks([]parsers.Chunk{chunk}), titles...)
+	if err := s.storeDocument(ctx, userID, doc, []parsers.Chunk{chunk}); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// resolveZettelLinks matches the suggested links of new notes to the user's
+// documents by title, preferring notes extracted in the same run
+func (s *DocumentService) resolveZettelLinks(ctx context.Context, userID string, notes []*models.Document) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return
+	}
+
+	byTitle := make(map[string]string, len(notes))
+	for _, note := range notes {
+		byTitle[strings.ToLower(note.Title)] = note.ID.Hex()
+	}
+
+	collection := s.db.Collection("documents")
+	for _, note := range notes {
+		resolved := false
+		for i, link := range note.Zettel.SuggestedLinks {
+			id, ok := byTitle[strings.ToLower(link.Title)]
+			if !ok {
+				var match models.Document
+				err := collection.FindOne(ctx, bson.M{
+					"user_id": userObjectID,
+					"title":   primitive.Regex{Pattern: "^" + regexp.QuoteMeta(link.Title) + "$", Options: "i"},
+				}).Decode(&match)
+				if err != nil {
+					continue
+				}
+				id = match.ID.Hex()
+			}
+			if id == note.ID.Hex() {
+				continue
+			}
+			note.Zettel.SuggestedLinks[i].DocumentID = id
+			resolved = true
+		}
+
+		if resolved {
+			_, err := collection.UpdateOne(ctx, bson.M{"_id": note.ID}, bson.M{"$set": bson.M{"zettel.suggested_links": note.Zettel.SuggestedLinks}})
+			if err != nil {
+				log.Printf("Warning: Failed to store links of zettel %s: %v", note.ID.Hex(), err)
+			}
+		}
+	}
+}
+
+// remapZettelChunks points the source chunks of a document's zettels at
+// the document's new chunks after it was rechunked, dropping chunks whose
+// text is no longer covered
+func (s *DocumentService) remapZettelChunks(ctx context.Context, userID, documentID string, mapping map[string][]string) error {
+	zettels, err := s.ListZettels(ctx, userID, documentID)
+	if err != nil {
+		return err
+	}
+
+	for _, zettel := range zettels {
+		chunkIDs := []string{}
+		seen := make(map[string]bool)
+		for _, id := range zettel.Zettel.SourceChunkIDs {
+			for _, mapped := range mapping[id] {
+				if !seen[mapped] {
+					seen[mapped] = true
+					chunkIDs = append(chunkIDs, mapped)
+				}
+			}
+		}
+
+		_, err := s.db.Collection("documents").UpdateOne(ctx, bson.M{"_id": zettel.ID}, bson.M{"$set": bson.M{"zettel.source_chunk_ids": chunkIDs}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListZettels returns the notes extracted from a document
+func (s *DocumentService) ListZettels(ctx context.Context, userID, documentID string) ([]models.Document, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := s.db.Collection("documents").Find(ctx, bson.M{
+		"user_id":                   userObjectID,
+		"zettel.source_document_id": documentID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	zettels := []models.Document{}
+	if err := cursor.All(ctx, &zettels); err != nil {
+		return nil, err
+	}
+	return zettels, nil
+}
@@ -0,0 +1,129 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// BPE is a byte-pair encoding tokenizer using tiktoken rank files such as
+// cl100k_base.tiktoken, the encoding of text-embedding-3-small
+type BPE struct {
+	ranks map[string]int
+}
+
+// LoadBPE reads a tiktoken rank file from disk
+func LoadBPE(path string) (*BPE, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadBPE(file)
+}
+
+// ReadBPE parses tiktoken ranks, one base64 encoded token and its rank per
+// line
+func ReadBPE(r io.Reader) (*BPE, error) {
+	ranks := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected token and rank", line)
+		}
+
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid token: %w", line, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rank: %w", line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("no ranks found")
+	}
+
+	return &BPE{ranks: ranks}, nil
+}
+
+func (b *BPE) Count(text string) int {
+	count := 0
+	for _, piece := range pretokenize(text) {
+		count += len(b.split(piece)) - 1
+	}
+	return count
+}
+
+func (b *BPE) Offsets(text string) []int {
+	var offsets []int
+	start := 0
+	for _, piece := range pretokenize(text) {
+		bounds := b.split(piece)
+		for _, bound := range bounds[:len(bounds)-1] {
+			offsets = append(offsets, start+bound)
+		}
+		start += len(piece)
+	}
+	return offsets
+}
+
+// Encode returns the token ranks for text
+func (b *BPE) Encode(text string) []int {
+	var tokens []int
+	for _, piece := range pretokenize(text) {
+		bounds := b.split(piece)
+		for i := 0; i < len(bounds)-1; i++ {
+			tokens = append(tokens, b.ranks[piece[bounds[i]:bounds[i+1]]])
+		}
+	}
+	return tokens
+}
+
+// split applies BPE merges to piece and returns the token boundaries,
+// starting at 0 and ending at len(piece)
+func (b *BPE) split(piece string) []int {
+	if _, ok := b.ranks[piece]; ok {
+		return []int{0, len(piece)}
+	}
+
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+
+	// Repeatedly merge the adjacent pair with the lowest rank until no
+	// pair is in the vocabulary
+	for len(bounds) > 2 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i < len(bounds)-2; i++ {
+			if rank, ok := b.ranks[piece[bounds[i]:bounds[i+2]]]; ok && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+	}
+
+	return bounds
+}
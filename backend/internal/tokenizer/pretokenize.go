@@ -0,0 +1,115 @@
+package tokenizer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// pretokenize splits text into the pieces that BPE merges are applied to,
+// following the cl100k_base pattern used by the OpenAI embedding models:
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}|
+//	 ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// Go's regexp package has no lookahead, so the alternatives are matched by
+// hand in the same order. The pieces always concatenate back to text.
+func pretokenize(text string) []string {
+	var pieces []string
+	for i := 0; i < len(text); {
+		n := matchPiece(text[i:])
+		pieces = append(pieces, text[i:i+n])
+		i += n
+	}
+	return pieces
+}
+
+var contractions = []string{"s", "t", "re", "ve", "m", "ll", "d"}
+
+// matchPiece returns the length in bytes of the piece at the start of s
+func matchPiece(s string) int {
+	r, size := utf8.DecodeRuneInString(s)
+
+	// (?i:'s|'t|'re|'ve|'m|'ll|'d)
+	if r == '\'' {
+		for _, c := range contractions {
+			if len(s) > len(c) && strings.EqualFold(s[1:1+len(c)], c) {
+				return 1 + len(c)
+			}
+		}
+	}
+
+	// [^\r\n\p{L}\p{N}]?\p{L}+
+	if unicode.IsLetter(r) {
+		return size + runLength(s[size:], unicode.IsLetter)
+	}
+	if r != '\r' && r != '\n' && !unicode.IsNumber(r) {
+		if n := runLength(s[size:], unicode.IsLetter); n > 0 {
+			return size + n
+		}
+	}
+
+	// \p{N}{1,3}
+	if unicode.IsNumber(r) {
+		n := size
+		for count := 1; count < 3 && n < len(s); count++ {
+			next, nextSize := utf8.DecodeRuneInString(s[n:])
+			if !unicode.IsNumber(next) {
+				break
+			}
+			n += nextSize
+		}
+		return n
+	}
+
+	// ' ?[^\s\p{L}\p{N}]+[\r\n]*'
+	start := 0
+	if r == ' ' {
+		start = 1
+	}
+	if n := runLength(s[start:], isSymbol); n > 0 {
+		n += start
+		return n + runLength(s[n:], isNewline)
+	}
+
+	if unicode.IsSpace(r) {
+		end := runLength(s, unicode.IsSpace)
+
+		// \s*[\r\n]+
+		if last := strings.LastIndexAny(s[:end], "\r\n"); last >= 0 {
+			return last + 1
+		}
+
+		// \s+(?!\S) leaves the final space of a run to prefix the next word
+		if end < len(s) {
+			_, lastSize := utf8.DecodeLastRuneInString(s[:end])
+			if end-lastSize > 0 {
+				return end - lastSize
+			}
+		}
+
+		// \s+
+		return end
+	}
+
+	return size
+}
+
+// runLength returns the length in bytes of the prefix of s whose runes all
+// satisfy f
+func runLength(s string, f func(rune) bool) int {
+	for i, r := range s {
+		if !f(r) {
+			return i
+		}
+	}
+	return len(s)
+}
+
+func isSymbol(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+func isNewline(r rune) bool {
+	return r == '\r' || r == '\n'
+}
@@ -0,0 +1,73 @@
+package tokenizer
+
+import (
+	"log"
+	"unicode/utf8"
+)
+
+// Tokenizer measures text in the tokens seen by the embedding model
+type Tokenizer interface {
+	// Count returns the number of tokens in text
+	Count(text string) int
+	// Offsets returns the byte offset at which each token of text starts
+	Offsets(text string) []int
+}
+
+// Load returns the BPE tokenizer for the rank file at path, or the
+// estimator when no rank file is configured or it cannot be read. Falling
+// back is logged since estimated counts can make chunks larger than their
+// configured maximum.
+func Load(path string) Tokenizer {
+	if path == "" {
+		log.Printf("Warning: TOKENIZER_FILE is not set, token counts are ESTIMATED and chunks may exceed their token limits; point it at cl100k_base.tiktoken (the Docker image includes it)")
+		return NewEstimator()
+	}
+
+	bpe, err := LoadBPE(path)
+	if err != nil {
+		log.Printf("Warning: Failed to load tokenizer ranks from %s, token counts are ESTIMATED and chunks may exceed their token limits: %v", path, err)
+		return NewEstimator()
+	}
+	log.Printf("Loaded tokenizer ranks from %s", path)
+	return bpe
+}
+
+// Estimator approximates token counts without a vocabulary by splitting
+// text the same way the BPE tokenizer does and assuming four bytes per
+// token within each piece
+type Estimator struct{}
+
+func NewEstimator() *Estimator {
+	return &Estimator{}
+}
+
+const bytesPerToken = 4
+
+func (e *Estimator) Count(text string) int {
+	count := 0
+	for _, piece := range pretokenize(text) {
+		count += (len(piece) + bytesPerToken - 1) / bytesPerToken
+	}
+	return count
+}
+
+func (e *Estimator) Offsets(text string) []int {
+	var offsets []int
+	start := 0
+	for _, piece := range pretokenize(text) {
+		for i := 0; i < len(piece); {
+			offsets = append(offsets, start+i)
+			next := i + bytesPerToken
+			if next > len(piece) {
+				next = len(piece)
+			}
+			// Keep multi-byte characters within a single token
+			for next < len(piece) && !utf8.RuneStart(piece[next]) {
+				next++
+			}
+			i = next
+		}
+		start += len(piece)
+	}
+	return offsets
+}
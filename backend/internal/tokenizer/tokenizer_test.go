@@ -0,0 +1,95 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPretokenize(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"Hello world", []string{"Hello", " world"}},
+		{"I'm  fine", []string{"I", "'m", " ", " fine"}},
+		{"1234567", []string{"123", "456", "7"}},
+		{"Wait!!\n\nNext", []string{"Wait", "!!\n\n", "Next"}},
+		{"one\n\n  two", []string{"one", "\n\n", " ", " two"}},
+		{"end   ", []string{"end", "   "}},
+		{"(café)", []string{"(café", ")"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			pieces := pretokenize(tt.text)
+			if !reflect.DeepEqual(pieces, tt.expected) {
+				t.Errorf("pretokenize(%q) = %q, want %q", tt.text, pieces, tt.expected)
+			}
+		})
+	}
+}
+
+func testRanks(tokens ...string) string {
+	var b strings.Builder
+	rank := 0
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), rank)
+		rank++
+	}
+	for _, token := range tokens {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+		rank++
+	}
+	return b.String()
+}
+
+func TestBPE(t *testing.T) {
+	bpe, err := ReadBPE(strings.NewReader(testRanks("ab", "abc", " a", " ab")))
+	if err != nil {
+		t.Fatalf("ReadBPE() error = %v", err)
+	}
+
+	// "abc" is a single token, " abd" merges " a" then " ab" and keeps "d"
+	text := "abc abd"
+	if got := bpe.Count(text); got != 3 {
+		t.Errorf("Count(%q) = %d, want 3", text, got)
+	}
+	if got := bpe.Offsets(text); !reflect.DeepEqual(got, []int{0, 3, 6}) {
+		t.Errorf("Offsets(%q) = %v, want [0 3 6]", text, got)
+	}
+	if got := bpe.Encode(text); !reflect.DeepEqual(got, []int{257, 259, int('d')}) {
+		t.Errorf("Encode(%q) = %v, want [257 259 100]", text, got)
+	}
+}
+
+func TestReadBPEInvalid(t *testing.T) {
+	if _, err := ReadBPE(strings.NewReader("not-base64! 1\n")); err == nil {
+		t.Error("ReadBPE() expected error for invalid token")
+	}
+	if _, err := ReadBPE(strings.NewReader("")); err == nil {
+		t.Error("ReadBPE() expected error for empty file")
+	}
+}
+
+func TestEstimator(t *testing.T) {
+	e := NewEstimator()
+
+	text := "Tokenization works"
+	// "Tokenization" is 12 bytes and " works" is 6 bytes
+	if got := e.Count(text); got != 5 {
+		t.Errorf("Count(%q) = %d, want 5", text, got)
+	}
+	if got := e.Offsets(text); len(got) != e.Count(text) {
+		t.Errorf("Offsets(%q) has %d entries, want %d", text, len(got), e.Count(text))
+	}
+
+	// Multi-byte characters are never split between tokens
+	for _, offset := range e.Offsets("ééééé") {
+		if offset%2 != 0 {
+			t.Errorf("offset %d splits a character", offset)
+		}
+	}
+}
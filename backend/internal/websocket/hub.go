@@ -55,35 +55,39 @@ func (h *Hub) Run() {
 			log.Printf("Client unregistered for user %s", client.UserID)
 
 		case message := <-h.broadcast:
-			h.mu.RLock()
+			// Clients that cannot keep up are dropped, which writes to the
+			// client maps
+			h.mu.Lock()
 			// Send to all clients of the specific user
 			if message.UserID != "" {
 				if clients, ok := h.clients[message.UserID]; ok {
-					for client := range clients {
-						select {
-						case client.Send <- message:
-						default:
-							close(client.Send)
-							delete(clients, client)
-						}
-					}
+					h.send(message.UserID, clients, message)
 				}
 			} else {
 				// Broadcast to all clients
-				for _, clients := range h.clients {
-					for client := range clients {
-						select {
-						case client.Send <- message:
-						default:
-							close(client.Send)
-							delete(clients, client)
-						}
-					}
+				for userID, clients := range h.clients {
+					h.send(userID, clients, message)
 				}
 			}
-			h.mu.RUnlock()
+			h.mu.Unlock()
+		}
+	}
+}
+
+// send delivers a message to clients, dropping those whose buffer is full.
+// The caller holds the write lock.
+func (h *Hub) send(userID string, clients map[*Client]bool, message Message) {
+	for client := range clients {
+		select {
+		case client.Send <- message:
+		default:
+			close(client.Send)
+			delete(clients, client)
 		}
 	}
+	if len(clients) == 0 {
+		delete(h.clients, userID)
+	}
 }
 
 // SendToUser sends a message to all clients of a specific user
@@ -95,3 +99,10 @@ func (h *Hub) SendToUser(userID string, messageType string, payload map[string]i
 	}
 	h.broadcast <- message
 }
+
+// HasClients reports whether the user has an open connection
+func (h *Hub) HasClients(userID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients[userID]) > 0
+}
@@ -144,6 +144,10 @@ export const FolderUpload: React.FC<FolderUploadProps> = ({
           <option value="obsidian">Obsidian</option>
           <option value="roam">Roam Research</option>
           <option value="logseq">Logseq</option>
+          <option value="epub">EPUB E-book</option>
+          <option value="kindle">Kindle Clippings</option>
+          <option value="readwise">Readwise Export</option>
+          <option value="email">Email (mbox / .eml)</option>
         </select>
       </div>
 
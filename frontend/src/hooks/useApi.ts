@@ -1,5 +1,5 @@
 import { useState } from 'react';
-import { Document, SearchResult, SearchRequest, UploadRequest, ApiResponse, Chunk } from '../types';
+import { Document, SearchResult, SearchRequest, UploadRequest, ApiResponse, Chunk, RechunkRequest, TagCount, SearchResponse, AnswerRequest, AnswerResponse, AnswerStreamEvent, ChatSession, ChatScope, ChatTurn, LLMParams, RelatedParams, RelatedResponse, Link, LinkListParams, Pagination, GraphParams, GraphResponse, GraphExportFormat, GraphInsights, BacklinksResponse, UnresolvedLink } from '../types';
 import { API_URL, API_ENDPOINTS, SEARCH_CONFIG } from '../utils/constants';
 
 export const useApi = (token: string | null) => {
@@ -41,9 +41,9 @@ export const useApi = (token: string | null) => {
     }
   };
 
-  const search = async (request: SearchRequest): Promise<ApiResponse<{ results: SearchResult[] }>> => {
+  const search = async (request: SearchRequest): Promise<ApiResponse<SearchResponse>> => {
     const searchData = {
-      query: request.query,
+      ...request,
       limit: request.limit || SEARCH_CONFIG.DEFAULT_LIMIT,
       similarity_threshold: request.similarity_threshold || SEARCH_CONFIG.DEFAULT_SIMILARITY_THRESHOLD,
     };
@@ -54,6 +54,55 @@ export const useApi = (token: string | null) => {
     });
   };
 
+  const searchWithLLM = async (request: AnswerRequest): Promise<ApiResponse<AnswerResponse>> => {
+    return makeRequest(API_ENDPOINTS.SEARCH_WITH_LLM, {
+      method: 'POST',
+      body: JSON.stringify(request),
+    });
+  };
+
+  // Streams an answer as server-sent events; abort the signal to stop it
+  const streamSearchWithLLM = async (
+    request: AnswerRequest,
+    onEvent: (event: AnswerStreamEvent) => void,
+    signal?: AbortSignal
+  ): Promise<void> => {
+    const response = await fetch(`${API_URL}${API_ENDPOINTS.SEARCH_WITH_LLM}`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+        Accept: 'text/event-stream',
+        ...(token && { Authorization: `Bearer ${token}` }),
+      },
+      body: JSON.stringify(request),
+      signal,
+    });
+    if (!response.ok || !response.body) {
+      onEvent({ event: 'error', message: 'Request failed' });
+      return;
+    }
+
+    const reader = response.body.getReader();
+    const decoder = new TextDecoder();
+    let buffer = '';
+    for (;;) {
+      const { done, value } = await reader.read();
+      if (done) break;
+      buffer += decoder.decode(value, { stream: true });
+
+      let end;
+      while ((end = buffer.indexOf('\n\n')) >= 0) {
+        const block = buffer.slice(0, end);
+        buffer = buffer.slice(end + 2);
+        const event = block.match(/^event: (.*)$/m)?.[1];
+        const data = block.match(/^data: (.*)$/m)?.[1];
+        if (event && data) {
+          onEvent({ event, ...JSON.parse(data) } as AnswerStreamEvent);
+        }
+      }
+    }
+  };
+
   const getDocuments = async (): Promise<ApiResponse<{ documents: Document[] }>> => {
     return makeRequest(API_ENDPOINTS.DOCUMENTS.LIST);
   };
@@ -67,6 +116,24 @@ export const useApi = (token: string | null) => {
         formData.append('files[]', file);
       });
       formData.append('source_type', request.source_type);
+      if (request.chunking_strategy) {
+        formData.append('chunking_strategy', request.chunking_strategy);
+      }
+      if (request.chunk_size_preference) {
+        formData.append('chunk_size_preference', request.chunk_size_preference);
+      }
+      if (request.min_tokens !== undefined) {
+        formData.append('min_tokens', String(request.min_tokens));
+      }
+      if (request.max_tokens !== undefined) {
+        formData.append('max_tokens', String(request.max_tokens));
+      }
+      if (request.overlap_tokens !== undefined) {
+        formData.append('overlap_tokens', String(request.overlap_tokens));
+      }
+      if (request.mode) {
+        formData.append('mode', request.mode);
+      }
       
       const response = await fetch(`${API_URL}${API_ENDPOINTS.DOCUMENTS.UPLOAD}`, {
         method: 'POST',
@@ -106,12 +173,176 @@ export const useApi = (token: string | null) => {
     return makeRequest(`${API_ENDPOINTS.DOCUMENTS.CHUNKS}/${documentId}/chunks`);
   };
 
+  const rechunkDocument = async (documentId: string, request: RechunkRequest): Promise<ApiResponse<{ job_id: string }>> => {
+    return makeRequest(`${API_ENDPOINTS.DOCUMENTS.RECHUNK}/${documentId}/rechunk`, {
+      method: 'POST',
+      body: JSON.stringify(request),
+    });
+  };
+
+  const reindexKeywords = async (documentId?: string): Promise<ApiResponse<{ job_id: string }>> => {
+    const query = documentId ? `?document_id=${encodeURIComponent(documentId)}` : '';
+    return makeRequest(`${API_ENDPOINTS.DOCUMENTS.REINDEX}${query}`, { method: 'POST' });
+  };
+
+  const getDocumentZettels = async (documentId: string): Promise<ApiResponse<{ zettels: Document[] }>> => {
+    return makeRequest(`${API_ENDPOINTS.DOCUMENTS.ZETTELS}/${documentId}/zettels`);
+  };
+
+  const relatedQuery = (params?: RelatedParams): string => {
+    const query = new URLSearchParams();
+    if (params?.limit) query.set('limit', String(params.limit));
+    if (params?.min_score) query.set('min_score', String(params.min_score));
+    const encoded = query.toString();
+    return encoded ? `?${encoded}` : '';
+  };
+
+  const getRelatedToDocument = async (documentId: string, params?: RelatedParams): Promise<ApiResponse<RelatedResponse>> => {
+    return makeRequest(`${API_ENDPOINTS.DOCUMENTS.RELATED}/${documentId}/related${relatedQuery(params)}`);
+  };
+
+  const getRelatedToChunk = async (chunkId: string, params?: RelatedParams): Promise<ApiResponse<RelatedResponse>> => {
+    return makeRequest(`${API_ENDPOINTS.CHUNKS}/${chunkId}/related${relatedQuery(params)}`);
+  };
+
+  const getBacklinks = async (documentId: string): Promise<ApiResponse<BacklinksResponse>> => {
+    return makeRequest(`${API_ENDPOINTS.DOCUMENTS.BACKLINKS}/${documentId}/backlinks`);
+  };
+
+  const getUnresolvedLinks = async (): Promise<ApiResponse<{ unresolved: UnresolvedLink[]; total: number }>> => {
+    return makeRequest(API_ENDPOINTS.UNRESOLVED_LINKS);
+  };
+
+  const listLinks = async (params?: LinkListParams): Promise<ApiResponse<{ links: Link[]; pagination: Pagination }>> => {
+    const query = new URLSearchParams();
+    Object.entries(params ?? {}).forEach(([key, value]) => {
+      if (value !== undefined && value !== '') query.set(key, String(value));
+    });
+    const encoded = query.toString();
+    return makeRequest(`${API_ENDPOINTS.LINKS}${encoded ? `?${encoded}` : ''}`);
+  };
+
+  const acceptLink = async (linkId: string): Promise<ApiResponse<Link>> => {
+    return makeRequest(`${API_ENDPOINTS.LINKS}/${linkId}/accept`, { method: 'POST' });
+  };
+
+  const rejectLink = async (linkId: string): Promise<ApiResponse<Link>> => {
+    return makeRequest(`${API_ENDPOINTS.LINKS}/${linkId}/reject`, { method: 'POST' });
+  };
+
+  const suggestLinks = async (documentId?: string): Promise<ApiResponse<{ queued_documents: number }>> => {
+    const query = documentId ? `?document_id=${encodeURIComponent(documentId)}` : '';
+    return makeRequest(`${API_ENDPOINTS.LINKS}/suggest${query}`, { method: 'POST' });
+  };
+
+  const graphQuery = (params?: GraphParams): URLSearchParams => {
+    const query = new URLSearchParams();
+    Object.entries(params ?? {}).forEach(([key, value]) => {
+      if (value === undefined || value === '') return;
+      query.set(key, Array.isArray(value) ? value.join(',') : String(value));
+    });
+    return query;
+  };
+
+  const getGraph = async (params?: GraphParams): Promise<ApiResponse<GraphResponse>> => {
+    const encoded = graphQuery(params).toString();
+    return makeRequest(`${API_ENDPOINTS.GRAPH}${encoded ? `?${encoded}` : ''}`);
+  };
+
+  const graphExportUrl = (format: GraphExportFormat, params?: Omit<GraphParams, 'page' | 'limit'>): string => {
+    const query = graphQuery(params);
+    query.set('format', format);
+    return `${API_URL}${API_ENDPOINTS.GRAPH_EXPORT}?${query.toString()}`;
+  };
+
+  // Resolves to { status: 'pending' } while insights are first computed
+  const getGraphInsights = async (): Promise<ApiResponse<GraphInsights | { status: 'pending' }>> => {
+    return makeRequest(API_ENDPOINTS.GRAPH_INSIGHTS);
+  };
+
+  const refreshGraphInsights = async (): Promise<ApiResponse<{ status: 'pending' }>> => {
+    return makeRequest(`${API_ENDPOINTS.GRAPH_INSIGHTS}/refresh`, { method: 'POST' });
+  };
+
+  const getTags = async (): Promise<ApiResponse<{ tags: TagCount[] }>> => {
+    return makeRequest(API_ENDPOINTS.TAGS);
+  };
+
+  const listChats = async (): Promise<ApiResponse<{ chats: ChatSession[] }>> => {
+    return makeRequest(API_ENDPOINTS.CHATS);
+  };
+
+  const createChat = async (title?: string, scope?: ChatScope): Promise<ApiResponse<ChatSession>> => {
+    return makeRequest(API_ENDPOINTS.CHATS, {
+      method: 'POST',
+      body: JSON.stringify({ title, scope }),
+    });
+  };
+
+  const getChat = async (chatId: string): Promise<ApiResponse<ChatSession>> => {
+    return makeRequest(`${API_ENDPOINTS.CHATS}/${chatId}`);
+  };
+
+  const updateChat = async (chatId: string, update: { title?: string; scope?: ChatScope }): Promise<ApiResponse<ChatSession>> => {
+    return makeRequest(`${API_ENDPOINTS.CHATS}/${chatId}`, {
+      method: 'PATCH',
+      body: JSON.stringify(update),
+    });
+  };
+
+  const deleteChat = async (chatId: string): Promise<ApiResponse> => {
+    return makeRequest(`${API_ENDPOINTS.CHATS}/${chatId}`, {
+      method: 'DELETE',
+    });
+  };
+
+  const sendChatMessage = async (
+    chatId: string,
+    content: string,
+    searchParams?: Omit<SearchRequest, 'query'>,
+    llmParams?: LLMParams
+  ): Promise<ApiResponse<ChatTurn>> => {
+    return makeRequest(`${API_ENDPOINTS.CHATS}/${chatId}/messages`, {
+      method: 'POST',
+      body: JSON.stringify({ content, search_params: searchParams, llm_params: llmParams }),
+    });
+  };
+
+  const exportChatUrl = (chatId: string): string => {
+    return `${API_URL}${API_ENDPOINTS.CHATS}/${chatId}/export`;
+  };
+
   return {
     isLoading,
     search,
+    searchWithLLM,
+    streamSearchWithLLM,
+    listChats,
+    createChat,
+    getChat,
+    updateChat,
+    deleteChat,
+    sendChatMessage,
+    exportChatUrl,
     getDocuments,
     uploadDocuments,
     deleteDocument,
     getDocumentChunks,
+    rechunkDocument,
+    reindexKeywords,
+    getDocumentZettels,
+    getRelatedToDocument,
+    getRelatedToChunk,
+    getBacklinks,
+    getUnresolvedLinks,
+    listLinks,
+    acceptLink,
+    rejectLink,
+    suggestLinks,
+    getGraph,
+    graphExportUrl,
+    getGraphInsights,
+    refreshGraphInsights,
+    getTags,
   };
 }; 
\ No newline at end of file
@@ -5,7 +5,13 @@ export type EventType =
   | 'documents:updated'
   | 'job-progress'
   | 'job-completed'
-  | 'job-failed';
+  | 'job-failed'
+  | 'links:suggested'
+  | 'graph:insights'
+  | 'llm:sources'
+  | 'llm:token'
+  | 'llm:done'
+  | 'llm:error';
 
 export interface WebSocketMessage {
   type: EventType;
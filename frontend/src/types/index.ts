@@ -18,6 +18,32 @@ export interface Document {
   chunk_count: number;
   uploaded_at: string;
   tags?: string[];
+  chunking?: ChunkingSettings;
+  zettel?: Zettel;
+}
+
+export interface ZettelLink {
+  title: string;
+  document_id?: string;
+}
+
+export interface Zettel {
+  summary: string;
+  suggested_links: ZettelLink[];
+  source_document_id: string;
+  source_chunk_ids: string[];
+}
+
+export type IngestionMode = 'zettel';
+
+export type ChunkSizePreference = 'small' | 'medium' | 'large' | 'custom';
+
+export interface ChunkingSettings {
+  preset?: ChunkSizePreference;
+  strategy?: ChunkingStrategy;
+  min_tokens?: number;
+  max_tokens?: number;
+  overlap_tokens?: number;
 }
 
 export interface Chunk {
@@ -36,6 +62,11 @@ export interface SearchResult {
   id: string;
   content: string;
   similarity_score: number;
+  keyword_score?: number;
+  score: number;
+  original_score?: number;
+  rerank_score?: number;
+  match_count?: number;
   source: {
     document_id: string;
     title: string;
@@ -50,6 +81,18 @@ export interface SearchResult {
     source_type: string;
     user_id: string;
   };
+  location?: ChunkLocation;
+  context?: {
+    before: string;
+    after: string;
+  };
+}
+
+export interface ChunkLocation {
+  start_offset: number;
+  end_offset: number;
+  start_line: number;
+  end_line: number;
 }
 
 export interface UploadFile extends File {
@@ -72,7 +115,7 @@ export interface Theme {
 export type ViewType = 'landing' | 'auth' | 'dashboard';
 export type AuthMode = 'login' | 'signup';
 export type DashboardTab = 'search' | 'documents' | 'upload' | 'analytics';
-export type SourceType = 'standard' | 'notion' | 'obsidian' | 'roam' | 'logseq';
+export type SourceType = 'standard' | 'notion' | 'obsidian' | 'roam' | 'logseq' | 'epub' | 'kindle' | 'readwise' | 'email';
 
 export interface ApiResponse<T = any> {
   success: boolean;
@@ -87,11 +130,304 @@ export interface SearchRequest {
   query: string;
   limit?: number;
   similarity_threshold?: number;
+  include_context?: boolean;
+  mode?: SearchMode;
+  hybrid_weight?: number;
+  rerank?: boolean;
+  rerank_candidates?: number;
+  diversify?: boolean;
+  mmr_lambda?: number;
+  group_by_document?: boolean;
+  filters?: SearchFilters;
+}
+
+export interface SearchFilters {
+  source_types?: string[];
+  exclude_source_types?: string[];
+  title?: string;
+  exclude_terms?: string[];
+  tags?: string[];
+  tags_all?: string[];
+  tags_none?: string[];
+  document_ids?: string[];
+}
+
+export interface QueryFilter {
+  field: 'tag' | 'source' | 'after' | 'before' | 'title' | 'from' | 'to' | 'text';
+  value: string;
+  negated: boolean;
+}
+
+export interface ParsedQuery {
+  text: string;
+  filters: QueryFilter[];
+  warnings?: string[];
+}
+
+export interface SearchResponse {
+  results: SearchResult[];
+  total_results: number;
+  mode: SearchMode | 'filter';
+  reranked: boolean;
+  diversified: boolean;
+  cache_hit: boolean;
+  semantic_cache_hit: boolean;
+  embedding_cache_hit: boolean;
+  parsed_query?: ParsedQuery;
+  query_embedding_time_ms: number;
+  search_time_ms: number;
+}
+
+export interface LLMParams {
+  max_tokens?: number;
+  temperature?: number;
+  context_tokens?: number;
+}
+
+export interface AnswerRequest {
+  query: string;
+  search_params?: Omit<SearchRequest, 'query'>;
+  llm_params?: LLMParams;
+}
+
+export interface Citation {
+  number: number;
+  chunk_id: string;
+  document_id: string;
+  title: string;
+}
+
+export interface TokenUsage {
+  prompt_tokens: number;
+  completion_tokens: number;
+  total_tokens: number;
+}
+
+export interface AnswerResponse {
+  llm_response: string;
+  citations: Citation[];
+  sources_used: SearchResult[];
+  tokens_used: number;
+  usage: TokenUsage;
+}
+
+export interface ChatScope {
+  document_ids?: string[];
+  tags?: string[];
+}
+
+export interface ChatMessage {
+  role: 'user' | 'assistant';
+  content: string;
+  standalone_query?: string;
+  citations?: Citation[];
+  tokens_used?: number;
+  created_at: string;
+}
+
+export interface ChatSession {
+  id: string;
+  title: string;
+  scope: ChatScope;
+  messages?: ChatMessage[];
+  created_at: string;
+  updated_at: string;
+}
+
+export interface ChatTurn {
+  user_message: ChatMessage;
+  assistant_message: ChatMessage;
+  sources_used: SearchResult[];
+}
+
+export type AnswerStreamEvent =
+  | { event: 'sources'; sources_used: SearchResult[] }
+  | { event: 'token'; token: string }
+  | { event: 'done'; llm_response: string; citations: Citation[]; tokens_used: number; usage: TokenUsage }
+  | { event: 'error'; message: string };
+
+export interface CacheStats {
+  search_hits: number;
+  search_misses: number;
+  semantic_hits: number;
+  embedding_hits: number;
+  embedding_misses: number;
+  estimated_time_saved_ms: number;
+  search_hit_rate: number;
+}
+
+export interface RelatedDocument {
+  document_id: string;
+  title: string;
+  source_type: string;
+  score: number;
+  match_count: number;
+  chunks: SearchResult[];
+}
+
+export interface RelatedResponse {
+  document_id: string;
+  chunk_id?: string;
+  related: RelatedDocument[];
+}
+
+export interface RelatedParams {
+  limit?: number;
+  min_score?: number;
+}
+
+export type LinkStatus = 'suggested' | 'accepted' | 'rejected';
+
+export interface Link {
+  id: string;
+  source_document_id: string;
+  target_document_id: string;
+  source_chunk_id: string;
+  target_chunk_id: string;
+  score: number;
+  status: LinkStatus;
+  source_title?: string;
+  target_title?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface LinkListParams {
+  status?: LinkStatus;
+  document_id?: string;
+  page?: number;
+  limit?: number;
+}
+
+export interface Pagination {
+  page: number;
+  limit: number;
+  total: number;
+  total_pages: number;
+}
+
+export interface BacklinkReference {
+  chunk_id: string;
+  chunk_index: number;
+  link_text: string;
+  snippet: string;
+}
+
+export interface Backlink {
+  document_id: string;
+  title: string;
+  source_type: string;
+  references: BacklinkReference[];
 }
 
+export interface BacklinksResponse {
+  document_id: string;
+  title: string;
+  backlinks: Backlink[];
+}
+
+export interface UnresolvedLink {
+  target: string;
+  referenced_by: DocumentRef[];
+}
+
+export type GraphEdgeType = 'wikilink' | 'tag' | 'semantic' | 'suggested';
+
+export interface GraphNode {
+  id: string;
+  type: 'document' | 'chunk';
+  label: string;
+  document_id?: string;
+  source_type?: string;
+  tags?: string[];
+  degree: number;
+}
+
+export interface GraphEdge {
+  source: string;
+  target: string;
+  type: GraphEdgeType | 'contains';
+  directed: boolean;
+  weight: number;
+  label?: string;
+}
+
+export interface GraphParams {
+  seed?: string;
+  depth?: number;
+  types?: GraphEdgeType[];
+  include_chunks?: boolean;
+  include_suggested?: boolean;
+  page?: number;
+  limit?: number;
+}
+
+export interface GraphResponse {
+  nodes: GraphNode[];
+  edges: GraphEdge[];
+  pagination: Pagination;
+}
+
+export type GraphExportFormat = 'graphml' | 'gexf' | 'dot';
+
+export interface DocumentRef {
+  document_id: string;
+  title: string;
+}
+
+export interface HubNote extends DocumentRef {
+  pagerank: number;
+  degree: number;
+  degree_centrality: number;
+}
+
+export interface Cluster {
+  id: number;
+  label: string;
+  terms: string[];
+  size: number;
+  documents: DocumentRef[];
+}
+
+export interface BridgeNote extends DocumentRef {
+  cluster: number;
+  linked_clusters: number[];
+  weight: number;
+}
+
+export interface GraphInsights {
+  document_count: number;
+  edge_count: number;
+  hubs: HubNote[];
+  orphans: DocumentRef[];
+  clusters: Cluster[];
+  bridges: BridgeNote[];
+  computed_at: string;
+}
+
+export interface TagCount {
+  tag: string;
+  document_count: number;
+}
+
+export type SearchMode = 'vector' | 'keyword' | 'hybrid';
+
+export type ChunkingStrategy = 'paragraph' | 'sentence' | 'fixed' | 'heading' | 'markdown' | 'semantic';
+
 export interface UploadRequest {
   files: File[];
   source_type: SourceType;
+  chunking_strategy?: ChunkingStrategy;
+  chunk_size_preference?: ChunkSizePreference;
+  min_tokens?: number;
+  max_tokens?: number;
+  overlap_tokens?: number;
+  mode?: IngestionMode;
+}
+
+export interface RechunkRequest {
+  chunk_size_preference?: ChunkSizePreference;
+  chunking?: Omit<ChunkingSettings, 'preset'>;
 }
 
 export interface AuthMessage {
@@ -17,12 +17,26 @@ export const API_ENDPOINTS = {
     UPLOAD: '/documents/upload',
     DELETE: '/documents',
     CHUNKS: '/documents', // Will be used as `/documents/{id}/chunks`
+    RECHUNK: '/documents', // Will be used as `/documents/{id}/rechunk`
+    ZETTELS: '/documents', // Will be used as `/documents/{id}/zettels`
+    RELATED: '/documents', // Will be used as `/documents/{id}/related`
+    BACKLINKS: '/documents', // Will be used as `/documents/{id}/backlinks`
+    REINDEX: '/documents/reindex',
   },
   SEARCH: '/search',
+  SEARCH_WITH_LLM: '/search/with-llm',
+  TAGS: '/tags',
+  CHATS: '/chats',
+  CHUNKS: '/chunks', // Will be used as `/chunks/{id}/related`
+  LINKS: '/links',
+  UNRESOLVED_LINKS: '/links/unresolved',
+  GRAPH: '/graph',
+  GRAPH_EXPORT: '/graph/export',
+  GRAPH_INSIGHTS: '/graph/insights',
 } as const;
 
 export const FILE_UPLOAD = {
-  ACCEPTED_TYPES: '.txt,.md,.json,.zip,.pdf,.docx,.doc,.rtf,.html,.htm,.csv,.xml,.yaml,.yml,.org,.tex,.rst,.adoc,.asciidoc',
+  ACCEPTED_TYPES: '.txt,.md,.json,.zip,.epub,.mbox,.eml,.pdf,.docx,.doc,.rtf,.html,.htm,.csv,.xml,.yaml,.yml,.org,.tex,.rst,.adoc,.asciidoc',
   MAX_SIZE: 10 * 1024 * 1024, // 10MB
 } as const;
 